@@ -0,0 +1,339 @@
+package backend
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/newestuser/github-workflow-dashboard/config"
+	"github.com/newestuser/github-workflow-dashboard/github"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Body of PUT /api/admin/repositories/{owner}/{repo}
+type trackedRepositoryRequest struct {
+	Workflows []string `json:"workflows"`
+	// e.g. 30s, the poll interval of the server is used if empty
+	PollInterval string `json:"pollInterval"`
+}
+
+// Requires the admin token as a bearer token or as the password of basic auth so that browsers can open the admin page,
+// the admin routes aren't found if no admin token is configured
+func (s *Server) withAdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, password, ok := r.BasicAuth(); ok {
+			token = password
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="github-workflow-dashboard admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Requires the admin token like the admin API once one is configured, everyone can call the route otherwise. Browsers send
+// remembered basic auth credentials along with requests of other sites, so basic auth requires the csrf token of the admin
+// form as well
+func (s *Server) withOptionalAdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	authorized := s.withAdminAuth(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok && !hmac.Equal([]byte(r.FormValue("csrf")), []byte(s.csrfToken())) {
+			http.Error(w, "invalid csrf token", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.AdminToken == "" {
+			next(w, r)
+			return
+		}
+		authorized(w, r)
+	}
+}
+
+// Lists the explicitly tracked repositories, discovered repositories aren't managed by the admin API
+func adminRepositoriesApi(server *Server) http.HandlerFunc {
+	return server.withAdminAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeAdminJson(w, http.StatusOK, configOf(server.getFilters(), server.getPollIntervals()))
+	})
+}
+
+// Adds or updates the tracked workflows of a repository with PUT and stops tracking it with DELETE
+func adminRepositoryApi(server *Server) http.HandlerFunc {
+	return server.withAdminAuth(func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		owner, repo := params["owner"], params["repo"]
+
+		switch r.Method {
+		case http.MethodPut:
+			request := &trackedRepositoryRequest{}
+			if r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+					http.Error(w, fmt.Sprintf("invalid body, err: %s", err), http.StatusBadRequest)
+					return
+				}
+			}
+
+			tracked := &config.Repository{Owner: owner, Repo: repo, Workflows: request.Workflows, PollInterval: request.PollInterval}
+			created, err := server.trackRepository(tracked)
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+
+			status := http.StatusOK
+			if created {
+				status = http.StatusCreated
+			}
+			writeAdminJson(w, status, tracked)
+		case http.MethodDelete:
+			if err := server.untrackRepository(owner, repo); err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// Serves the tracked repositories with a form to change them, the form posts back to the page
+func adminDashboard(server *Server) http.HandlerFunc {
+	return server.withAdminAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if err := server.submitAdminForm(r); err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			http.Redirect(w, r, server.path("/admin"), http.StatusSeeOther)
+			return
+		}
+
+		server.renderReportPage(w, server.templates.admin, &adminHTMLViewModel{
+			Repositories: configOf(server.getFilters(), server.getPollIntervals()).Repositories,
+			CSRFToken:    server.csrfToken(),
+		})
+	})
+}
+
+func (s *Server) submitAdminForm(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return &adminError{status: http.StatusBadRequest, err: err}
+	}
+	if !hmac.Equal([]byte(r.PostForm.Get("csrf")), []byte(s.csrfToken())) {
+		return &adminError{status: http.StatusForbidden, err: fmt.Errorf("invalid csrf token")}
+	}
+
+	owner, repo := strings.TrimSpace(r.PostForm.Get("owner")), strings.TrimSpace(r.PostForm.Get("repo"))
+	switch r.PostForm.Get("action") {
+	case "save":
+		workflows := make([]string, 0)
+		for _, workflow := range strings.Split(r.PostForm.Get("workflows"), ",") {
+			if workflow = strings.TrimSpace(workflow); workflow != "" {
+				workflows = append(workflows, workflow)
+			}
+		}
+		_, err := s.trackRepository(&config.Repository{Owner: owner, Repo: repo, Workflows: workflows, PollInterval: strings.TrimSpace(r.PostForm.Get("pollInterval"))})
+		return err
+	case "remove":
+		return s.untrackRepository(owner, repo)
+	default:
+		return &adminError{status: http.StatusBadRequest, err: fmt.Errorf("unknown action=%s", r.PostForm.Get("action"))}
+	}
+}
+
+// Token of the admin form derived from the admin token, so that other sites can't submit the form with the credentials
+// the browser remembered
+func (s *Server) csrfToken() string {
+	mac := hmac.New(sha256.New, []byte(s.opts.AdminToken))
+	mac.Write([]byte("admin form"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// An error of the admin API with the status it is served with
+type adminError struct {
+	status int
+	err    error
+}
+
+func (e *adminError) Error() string {
+	return e.err.Error()
+}
+
+func writeAdminJson(w http.ResponseWriter, status int, result interface{}) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Warn("Failed writing admin response, err: ", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	if adminErr, ok := err.(*adminError); ok {
+		http.Error(w, adminErr.Error(), adminErr.status)
+		return
+	}
+	log.Error(err.Error())
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// The repositories of the filters with their poll intervals sorted by owner and name
+func configOf(filters []*github.WorkflowFilter, pollIntervals map[RepoId]time.Duration) *config.Config {
+	result := &config.Config{Repositories: make([]*config.Repository, 0)}
+	for _, filter := range filters {
+		workflows := filter.WorkflowNames
+		if len(workflows) == 0 {
+			workflows = nil
+		}
+		tracked := &config.Repository{Owner: filter.Owner, Repo: filter.Repo, Workflows: workflows}
+		if interval, ok := pollIntervals[RepoId{owner: filter.Owner, name: filter.Repo}]; ok {
+			tracked.PollInterval = interval.String()
+		}
+		result.Repositories = append(result.Repositories, tracked)
+	}
+	result.Sort()
+	return result
+}
+
+// Tracks the workflows of the repository from the next poll on, true if the repository wasn't tracked before
+func (s *Server) trackRepository(tracked *config.Repository) (bool, error) {
+	if err := tracked.Validate(); err != nil {
+		return false, &adminError{status: http.StatusBadRequest, err: err}
+	}
+
+	s.adminMutex.Lock()
+	defer s.adminMutex.Unlock()
+
+	created := true
+	filters := make([]*github.WorkflowFilter, 0)
+	for _, filter := range s.getFilters() {
+		if filter.Owner == tracked.Owner && filter.Repo == tracked.Repo {
+			created = false
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	filters = append(filters, s.newFilter(tracked))
+
+	// validated above
+	interval, _ := tracked.GetPollInterval()
+	pollIntervals := s.pollIntervalsWith(RepoId{owner: tracked.Owner, name: tracked.Repo}, interval)
+
+	if err := s.saveFilters(filters, pollIntervals); err != nil {
+		return false, err
+	}
+	log.Info("Tracking workflows ", tracked.Workflows, " of repo: ", tracked.Owner, "/", tracked.Repo, " from the next poll on")
+	return created, nil
+}
+
+func (s *Server) untrackRepository(owner, repo string) error {
+	s.adminMutex.Lock()
+	defer s.adminMutex.Unlock()
+
+	found := false
+	filters := make([]*github.WorkflowFilter, 0)
+	for _, filter := range s.getFilters() {
+		if filter.Owner == owner && filter.Repo == repo {
+			found = true
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	if !found {
+		return &adminError{status: http.StatusNotFound, err: fmt.Errorf("repository %s/%s isn't tracked", owner, repo)}
+	}
+
+	if err := s.saveFilters(filters, s.pollIntervalsWith(RepoId{owner: owner, name: repo}, 0)); err != nil {
+		return err
+	}
+	log.Info("Stopped tracking repo: ", owner, "/", repo, " from the next poll on")
+	return nil
+}
+
+// Persists the filters to the config file before they replace the tracked filters, must be called while holding the
+// admin mutex
+func (s *Server) saveFilters(filters []*github.WorkflowFilter, pollIntervals map[RepoId]time.Duration) error {
+	if s.opts.ConfigFile != "" {
+		if err := config.Save(s.opts.ConfigFile, configOf(filters, pollIntervals)); err != nil {
+			return err
+		}
+	}
+	s.updateFilters(filters, pollIntervals)
+	return nil
+}
+
+// A copy of the poll intervals with the interval of the repository, zero removes it
+func (s *Server) pollIntervalsWith(repo RepoId, interval time.Duration) map[RepoId]time.Duration {
+	result := make(map[RepoId]time.Duration)
+	for r, i := range s.getPollIntervals() {
+		result[r] = i
+	}
+	delete(result, repo)
+	if interval > 0 {
+		result[repo] = interval
+	}
+	return result
+}
+
+// A filter of the repository with the limit and latest options of the repositories added at runtime
+func (s *Server) newFilter(tracked *config.Repository) *github.WorkflowFilter {
+	workflows := append([]string{}, tracked.Workflows...)
+	return &github.WorkflowFilter{
+		Owner:         tracked.Owner,
+		Repo:          tracked.Repo,
+		WorkflowNames: workflows,
+		Limit:         s.opts.Limit,
+		Latest:        s.opts.Latest,
+	}
+}
+
+// Replaces the filters with the ones of the config file if it exists
+func (s *Server) loadConfig() error {
+	if s.opts.ConfigFile == "" {
+		return nil
+	}
+
+	loaded, err := config.Load(s.opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if loaded == nil {
+		log.Info("Config file ", s.opts.ConfigFile, " doesn't exist yet, tracking the configured repositories until they are changed")
+		return nil
+	}
+
+	filters := make([]*github.WorkflowFilter, 0, len(loaded.Repositories))
+	pollIntervals := make(map[RepoId]time.Duration)
+	for _, tracked := range loaded.Repositories {
+		filters = append(filters, s.newFilter(tracked))
+		// validated by Load
+		if interval, _ := tracked.GetPollInterval(); interval > 0 {
+			pollIntervals[RepoId{owner: tracked.Owner, name: tracked.Repo}] = interval
+		}
+	}
+	log.Info("Tracking the ", len(filters), " repositories of config file ", s.opts.ConfigFile)
+	s.updateFilters(filters, pollIntervals)
+	return nil
+}
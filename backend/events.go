@@ -0,0 +1,181 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Interval of the comments sent to keep idle event streams from being closed by proxies
+const eventsKeepAliveInterval = 30 * time.Second
+
+// Notifies subscribers of new versions of the state, subscribers that didn't receive the previous version yet only get
+// the latest one
+type stateEvents struct {
+	mu          sync.Mutex
+	subscribers map[chan *stateRepository]bool
+	// closed when the streams have to end
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newStateEvents() *stateEvents {
+	return &stateEvents{subscribers: make(map[chan *stateRepository]bool), closed: make(chan struct{})}
+}
+
+// Ends all event streams, e.g. when the web server shuts down since they would keep it from completing
+func (e *stateEvents) close() {
+	e.closeOnce.Do(func() { close(e.closed) })
+}
+
+// A channel receiving the new versions of the state and a func to stop receiving them
+func (e *stateEvents) subscribe() (<-chan *stateRepository, func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ch := make(chan *stateRepository, 1)
+	e.subscribers[ch] = true
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.subscribers, ch)
+	}
+}
+
+func (e *stateEvents) publish(state *stateRepository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ch := range e.subscribers {
+		select {
+		case ch <- state:
+		default:
+			// replace the version the subscriber didn't receive yet, only publish sends to the channel
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- state
+		}
+	}
+}
+
+// An updated or removed repository section of the dashboard
+type repositoryEvent struct {
+	Version uint64    `json:"version"`
+	ID      string    `json:"id"`
+	Owner   string    `json:"owner"`
+	Repo    string    `json:"repo"`
+	Uts     time.Time `json:"uts"`
+	HTML    string    `json:"html,omitempty"`
+}
+
+// Stream the sections of the repositories whose state changed after each poll as server-sent events, the repositories
+// can be filtered like the dashboard routes (?owner=, ?repo=, ?workflow=)
+func eventsApi(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := &RunQuery{Owner: query.Get("owner"), Repo: query.Get("repo"), Workflow: query.Get("workflow")}
+
+		// subscribe before reading the current version so that no version is missed
+		updates, unsubscribe := server.events.subscribe()
+		defer unsubscribe()
+		last := server.getState()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		fmt.Fprintf(w, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-server.events.closed:
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case state := <-updates:
+				if err := server.writeRepositoryEvents(w, filter, last, state); err != nil {
+					log.Warn("Failed writing dashboard events, err: ", err)
+					return
+				}
+				last = state
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Writes an event per repository matching the filter that was added, updated or removed between the versions or whose
+// latest fetch failed
+func (s *Server) writeRepositoryEvents(w io.Writer, filter *RunQuery, previous, current *stateRepository) error {
+	for _, repo := range previous.candidateRepos(filter) {
+		if _, ok := current.data[repo]; !ok {
+			event := &repositoryEvent{Version: current.version, ID: repositorySectionID(repo), Owner: repo.owner, Repo: repo.name}
+			if err := writeEvent(w, "removed", event); err != nil {
+				return err
+			}
+		}
+	}
+
+	regressions := s.getRegressions().Regressions
+	polls := s.getPollStatus()
+	for _, repo := range current.candidateRepos(filter) {
+		// repositories that failed to be fetched keep their state but their section shows the failure
+		failed := polls.repos[repo] != nil && polls.repos[repo].consecutiveFailures > 0
+		if before, ok := previous.data[repo]; ok && before.state == current.data[repo].state && !failed {
+			continue
+		}
+
+		// the candidate exists, so the query returns exactly its state
+		repoState := current.queryRepos(&RunQuery{Owner: repo.owner, Repo: repo.name, Workflow: filter.Workflow})[0]
+		section, err := s.renderRepoHTMLSection(repoState, regressionsOf(repoState, regressions))
+		if err != nil {
+			return err
+		}
+
+		event := &repositoryEvent{
+			Version: current.version,
+			ID:      repositorySectionID(repo),
+			Owner:   repo.owner,
+			Repo:    repo.name,
+			Uts:     repoState.uts,
+			HTML:    string(section),
+		}
+		if err := writeEvent(w, "repository", event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEvent(w io.Writer, name string, data interface{}) error {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, bytes)
+	return err
+}
+
+// ID of the element of the section of the repository on the dashboard
+func repositorySectionID(repo RepoId) string {
+	return fmt.Sprintf("repository-%s", repo)
+}
@@ -0,0 +1,239 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	log "github.com/sirupsen/logrus"
+)
+
+// Upper bounds in seconds of the buckets of the run duration histograms
+var runDurationBuckets = []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200}
+
+// The runs of a workflow on a branch, the labels of the workflow metrics
+type workflowBranch struct {
+	owner    string
+	repo     string
+	workflow string
+	branch   string
+}
+
+func (w workflowBranch) labels() []string {
+	return []string{"owner", w.owner, "repo", w.repo, "workflow", w.workflow, "branch", w.branch}
+}
+
+type workflowBranchMetrics struct {
+	lastRun     *github.WorkflowRun
+	lastSuccess *github.WorkflowRun
+	inProgress  int
+	queued      int
+	// Number of completed runs per bucket of runDurationBuckets, durations above the last bucket only count in count
+	buckets []int
+	sum     float64
+	count   int
+}
+
+// Serves the health of the tracked workflows in the prometheus text format, the metrics are computed from the state so
+// that scraping doesn't call github
+func metricsHandler(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := server.writeMetrics(w, server.requestState(r)); err != nil {
+			log.Warn("Failed writing metrics, err: ", err)
+		}
+	}
+}
+
+func (s *Server) writeMetrics(w io.Writer, state *stateRepository) error {
+	m := &metricsWriter{w: w}
+
+	groups, keys := workflowBranchMetricsOf(state)
+
+	m.family("github_workflow_last_run_conclusion", "gauge", "Conclusion of the latest completed run of the workflow on the branch, 1 for the reported conclusion.")
+	for _, key := range keys {
+		if run := groups[key].lastRun; run != nil {
+			m.sample("github_workflow_last_run_conclusion", append(key.labels(), "conclusion", run.JobConclusion), 1)
+		}
+	}
+
+	m.family("github_workflow_last_run_timestamp_seconds", "gauge", "Creation time of the latest completed run of the workflow on the branch.")
+	for _, key := range keys {
+		if run := groups[key].lastRun; run != nil {
+			m.sample("github_workflow_last_run_timestamp_seconds", key.labels(), unixSeconds(run.JobRunTime))
+		}
+	}
+
+	m.family("github_workflow_last_success_timestamp_seconds", "gauge", "Creation time of the latest successful run of the workflow on the branch.")
+	for _, key := range keys {
+		if run := groups[key].lastSuccess; run != nil {
+			m.sample("github_workflow_last_success_timestamp_seconds", key.labels(), unixSeconds(run.JobRunTime))
+		}
+	}
+
+	m.family("github_workflow_runs_in_progress", "gauge", "Number of runs of the workflow on the branch in progress.")
+	for _, key := range keys {
+		m.sample("github_workflow_runs_in_progress", key.labels(), float64(groups[key].inProgress))
+	}
+
+	m.family("github_workflow_runs_queued", "gauge", "Number of runs of the workflow on the branch waiting to start.")
+	for _, key := range keys {
+		m.sample("github_workflow_runs_queued", key.labels(), float64(groups[key].queued))
+	}
+
+	m.family("github_workflow_run_duration_seconds", "histogram", "Duration of the completed runs of the workflow on the branch kept in the state.")
+	for _, key := range keys {
+		group := groups[key]
+		cumulative := 0
+		for i, bound := range runDurationBuckets {
+			cumulative += group.buckets[i]
+			m.sample("github_workflow_run_duration_seconds_bucket", append(key.labels(), "le", formatFloat(bound)), float64(cumulative))
+		}
+		m.sample("github_workflow_run_duration_seconds_bucket", append(key.labels(), "le", "+Inf"), float64(group.count))
+		m.sample("github_workflow_run_duration_seconds_sum", key.labels(), group.sum)
+		m.sample("github_workflow_run_duration_seconds_count", key.labels(), float64(group.count))
+	}
+
+	s.writeExporterMetrics(m, state)
+	return m.err
+}
+
+func (s *Server) writeExporterMetrics(m *metricsWriter, state *stateRepository) {
+	m.family("github_workflow_dashboard_state_version", "gauge", "Version of the state the metrics were computed from.")
+	m.sample("github_workflow_dashboard_state_version", nil, float64(state.version))
+
+	polls := s.getPollStatus()
+	m.family("github_workflow_dashboard_poll_duration_seconds", "gauge", "Duration of the latest poll of all tracked repositories.")
+	m.sample("github_workflow_dashboard_poll_duration_seconds", nil, polls.duration.Seconds())
+
+	m.family("github_workflow_dashboard_poll_errors_total", "counter", "Number of failed polls of the repository.")
+	for _, repo := range s.statusRepos(polls) {
+		errors := 0
+		if status, ok := polls.repos[repo]; ok {
+			errors = status.errors
+		}
+		m.sample("github_workflow_dashboard_poll_errors_total", []string{"owner", repo.owner, "repo", repo.name}, float64(errors))
+	}
+
+	if s.opts.RateLimits == nil {
+		return
+	}
+	if rateLimit, ok := s.opts.RateLimits.RateLimit(); ok {
+		m.family("github_workflow_dashboard_rate_limit_remaining", "gauge", "Requests remaining in the github API rate limit window as of the latest response.")
+		m.sample("github_workflow_dashboard_rate_limit_remaining", nil, float64(rateLimit.Remaining))
+		m.family("github_workflow_dashboard_rate_limit", "gauge", "Requests allowed in the github API rate limit window.")
+		m.sample("github_workflow_dashboard_rate_limit", nil, float64(rateLimit.Limit))
+		if !rateLimit.Reset.IsZero() {
+			m.family("github_workflow_dashboard_rate_limit_reset_timestamp_seconds", "gauge", "Time the github API rate limit window resets.")
+			m.sample("github_workflow_dashboard_rate_limit_reset_timestamp_seconds", nil, unixSeconds(rateLimit.Reset))
+		}
+	}
+}
+
+// The metrics of every workflow on every branch of the state and their keys sorted by the labels
+func workflowBranchMetricsOf(state *stateRepository) (map[workflowBranch]*workflowBranchMetrics, []workflowBranch) {
+	groups := map[workflowBranch]*workflowBranchMetrics{}
+	group := func(run *github.WorkflowRun) *workflowBranchMetrics {
+		key := workflowBranch{owner: run.WorkflowOwner, repo: run.WorkflowRepo, workflow: run.WorkflowName, branch: run.JobBranch}
+		if _, ok := groups[key]; !ok {
+			groups[key] = &workflowBranchMetrics{buckets: make([]int, len(runDurationBuckets))}
+		}
+		return groups[key]
+	}
+
+	runs, _ := state.queryRuns(&RunQuery{})
+	for _, run := range runs {
+		metrics := group(run)
+		countActiveRun(metrics, run)
+		if run.ActiveRun != nil {
+			countActiveRun(group(run.ActiveRun), run.ActiveRun)
+		}
+
+		if !run.IsCompleted() {
+			continue
+		}
+		if metrics.lastRun == nil || run.JobRunTime.After(metrics.lastRun.JobRunTime) {
+			metrics.lastRun = run
+		}
+		if run.JobConclusion == "success" && (metrics.lastSuccess == nil || run.JobRunTime.After(metrics.lastSuccess.JobRunTime)) {
+			metrics.lastSuccess = run
+		}
+
+		if duration, ok := stats.Duration(run); ok {
+			seconds := duration.Seconds()
+			for i, bound := range runDurationBuckets {
+				if seconds <= bound {
+					metrics.buckets[i]++
+					break
+				}
+			}
+			metrics.sum += seconds
+			metrics.count++
+		}
+	}
+
+	keys := make([]workflowBranch, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Join(keys[i].labels(), "\x00") < strings.Join(keys[j].labels(), "\x00")
+	})
+	return groups, keys
+}
+
+func countActiveRun(metrics *workflowBranchMetrics, run *github.WorkflowRun) {
+	switch run.JobStatus {
+	case github.StatusInProgress:
+		metrics.inProgress++
+	case github.StatusQueued:
+		metrics.queued++
+	}
+}
+
+// Writes metric families in the prometheus text format, the first error stops all further writes
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (m *metricsWriter) family(name, kind, help string) {
+	m.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labels are pairs of names and values
+func (m *metricsWriter) sample(name string, labels []string, value float64) {
+	if len(labels) == 0 {
+		m.printf("%s %s\n", name, formatFloat(value))
+		return
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelValueReplacer.Replace(labels[i+1])))
+	}
+	m.printf("%s{%s} %s\n", name, strings.Join(pairs, ","), formatFloat(value))
+}
+
+func (m *metricsWriter) printf(format string, args ...interface{}) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, format, args...)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// Json names of the fields of a run that can be selected with ?fields=
+var runFields = jsonFieldNames(reflect.TypeOf(github.WorkflowRun{}))
+
+// Response of the json API of the runs
+type runsResponse struct {
+	// Version of the state the runs were read from and the time it was created
+	Version uint64    `json:"version"`
+	Updated time.Time `json:"updated"`
+	// Number of all runs matching the query
+	Total int `json:"total"`
+	// Passed as ?cursor= to get the next page, empty on the last page
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Runs       interface{} `json:"runs"`
+}
+
+// Parses the filters, sort order and page of the json API of the runs, e.g.
+// ?branch=main&conclusion=failure&since=2022-03-01&sort=-duration&limit=50&cursor=...
+func parseRunQuery(values url.Values) (*RunQuery, error) {
+	q := &RunQuery{
+		Owner:      values.Get("owner"),
+		Repo:       values.Get("repo"),
+		Workflow:   values.Get("workflow"),
+		Branch:     values.Get("branch"),
+		Event:      values.Get("event"),
+		Conclusion: values.Get("conclusion"),
+		Status:     values.Get("status"),
+		Actor:      values.Get("actor"),
+		Sort:       values.Get("sort"),
+	}
+
+	var err error
+	if q.Since, err = parseQueryTime(values, "since"); err != nil {
+		return nil, err
+	}
+	if q.Until, err = parseQueryTime(values, "until"); err != nil {
+		return nil, err
+	}
+	if q.Offset, err = parseQueryInt(values, "offset"); err != nil {
+		return nil, err
+	}
+	if q.Limit, err = parseQueryInt(values, "limit"); err != nil {
+		return nil, err
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		if q.After, err = decodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// The selected fields of ?fields=a,b, nil if all fields are selected
+func parseRunFields(values url.Values) ([]string, error) {
+	value := values.Get("fields")
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(value, ",")
+	for _, field := range fields {
+		if !runFields[field] {
+			return nil, fmt.Errorf("unknown field=%s", field)
+		}
+	}
+	return fields, nil
+}
+
+// Either a RFC3339 time or a date
+func parseQueryTime(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a RFC3339 time or a date, %s=%s", name, name, value)
+	}
+	return t, nil
+}
+
+func parseQueryInt(values url.Values, name string) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, %s=%s", name, name, value)
+	}
+	return result, nil
+}
+
+// The cursor of the page after the run, opaque so that the pagination can change without breaking clients
+func encodeCursor(runId int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(runId)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor=%s", cursor)
+	}
+
+	runId, err := strconv.Atoi(string(value))
+	if err != nil || runId <= 0 {
+		return 0, fmt.Errorf("invalid cursor=%s", cursor)
+	}
+	return runId, nil
+}
+
+// The runs with only the fields, all fields are kept if fields is nil
+func selectRunFields(runs []*github.WorkflowRun, fields []string) (interface{}, error) {
+	if fields == nil {
+		return runs, nil
+	}
+
+	result := make([]map[string]json.RawMessage, 0, len(runs))
+	for _, run := range runs {
+		bytes, err := json.Marshal(run)
+		if err != nil {
+			return nil, err
+		}
+
+		all := map[string]json.RawMessage{}
+		if err := json.Unmarshal(bytes, &all); err != nil {
+			return nil, err
+		}
+
+		selected := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				selected[field] = value
+			}
+		}
+		result = append(result, selected)
+	}
+	return result, nil
+}
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	result := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			result[name] = true
+		}
+	}
+	return result
+}
@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/newestuser/github-workflow-dashboard/github"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Refreshes requested sooner after the previous poll of a repository are delayed, so that refreshing can't use up the
+// rate limit of the github API
+const minRefreshInterval = 10 * time.Second
+
+// The polls of a repository, only accessed by the poll goroutine
+type repoSchedule struct {
+	next     time.Time
+	lastPoll time.Time
+	// interval between the previous and the next poll without the jitter
+	interval time.Duration
+	// number of polls in a row that found no new or updated runs
+	idlePolls int
+	// latest update of the runs of the previous poll and their number, compared to detect idle repositories
+	lastUpdate time.Time
+	runs       int
+}
+
+// The filters of the tracked repositories due for a poll and whether repositories stopped being tracked. Repositories
+// that started to be tracked are due at a random time within the jitter.
+func (s *Server) dueFilters(now time.Time, jitter time.Duration) ([]*github.WorkflowFilter, bool) {
+	due := make([]*github.WorkflowFilter, 0)
+	tracked := map[RepoId]bool{}
+	for _, filter := range s.trackedFilters() {
+		repo := RepoId{owner: filter.Owner, name: filter.Repo}
+		tracked[repo] = true
+
+		schedule, ok := s.schedules[repo]
+		if !ok {
+			schedule = &repoSchedule{next: now}
+			if jitter > 0 {
+				schedule.next = now.Add(time.Duration(s.random.Int63n(int64(jitter))))
+			}
+			s.schedules[repo] = schedule
+		}
+		if !schedule.next.After(now) {
+			due = append(due, filter)
+		}
+	}
+
+	removed := false
+	for repo := range s.schedules {
+		if !tracked[repo] {
+			delete(s.schedules, repo)
+			removed = true
+		}
+	}
+	return due, removed
+}
+
+// Schedules the next poll of the repository after it was polled, state is nil if the poll failed
+func (s *Server) reschedule(repo RepoId, state *repoState, now time.Time) *repoSchedule {
+	schedule, ok := s.schedules[repo]
+	if !ok {
+		schedule = &repoSchedule{}
+		s.schedules[repo] = schedule
+	}
+
+	schedule.lastPoll = now
+	schedule.interval = s.nextPollInterval(schedule, repo, state)
+	schedule.next = now.Add(s.jittered(schedule.interval))
+	return schedule
+}
+
+// The poll interval of the repository, with adaptive polling repositories with queued or in progress runs are polled
+// more often while the interval of repositories without new or updated runs doubles after every poll
+func (s *Server) nextPollInterval(schedule *repoSchedule, repo RepoId, state *repoState) time.Duration {
+	base := s.opts.PollInterval
+	if interval, ok := s.getPollIntervals()[repo]; ok {
+		base = interval
+	}
+	if !s.opts.AdaptivePolling || state == nil {
+		return base
+	}
+
+	lastUpdate, runs := runsFingerprint(state.runs)
+	changed := !lastUpdate.Equal(schedule.lastUpdate) || runs != schedule.runs
+	schedule.lastUpdate, schedule.runs = lastUpdate, runs
+
+	if hasActiveRuns(state.runs) {
+		schedule.idlePolls = 0
+		if s.opts.ActivePollInterval > 0 && s.opts.ActivePollInterval < base {
+			return s.opts.ActivePollInterval
+		}
+		return base
+	}
+
+	if changed {
+		schedule.idlePolls = 0
+		return base
+	}
+	schedule.idlePolls++
+
+	max := s.opts.MaxPollInterval
+	if max < base {
+		max = base
+	}
+	interval := base
+	for i := 0; i < schedule.idlePolls && interval < max; i++ {
+		interval *= 2
+	}
+	if interval > max {
+		interval = max
+	}
+	return interval
+}
+
+// The interval changed by up to 10% so that repositories with the same interval aren't polled at once
+func (s *Server) jittered(interval time.Duration) time.Duration {
+	spread := int64(interval / 5)
+	if spread <= 0 {
+		return interval
+	}
+	return interval - interval/10 + time.Duration(s.random.Int63n(spread))
+}
+
+// Polls the repository as soon as possible but not sooner than minRefreshInterval after its previous poll
+func (s *Server) refresh(repo RepoId, now time.Time) {
+	schedule, ok := s.schedules[repo]
+	if !ok {
+		return
+	}
+
+	next := now
+	if earliest := schedule.lastPoll.Add(minRefreshInterval); earliest.After(now) {
+		next = earliest
+	}
+	if next.Before(schedule.next) {
+		schedule.next = next
+	}
+}
+
+// The time of the next poll of a repository or of the reports, whatever comes first
+func (s *Server) nextWakeUp(nextReports time.Time) time.Time {
+	result := nextReports
+	for _, schedule := range s.schedules {
+		if schedule.next.Before(result) {
+			result = schedule.next
+		}
+	}
+	return result
+}
+
+// Requests an immediate poll of a tracked repository, the poll is delayed if the repository was polled moments ago. The
+// admin token is required once one is configured, since the polls use up the rate limit
+func refreshApi(server *Server) http.HandlerFunc {
+	return server.withOptionalAdminAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		params := mux.Vars(r)
+		repo := RepoId{owner: params["owner"], name: params["repo"]}
+		if !server.isTracked(repo) {
+			http.Error(w, fmt.Sprintf("repository %s isn't tracked", repo), http.StatusNotFound)
+			return
+		}
+
+		select {
+		case server.refreshes <- repo:
+		default:
+			log.Warn("Dropped the refresh of repo: ", repo, ", too many refreshes are pending")
+			http.Error(w, "too many refreshes are pending", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Whether the repository was tracked during the latest poll or is tracked explicitly
+func (s *Server) isTracked(repo RepoId) bool {
+	if _, ok := s.getPollStatus().repos[repo]; ok {
+		return true
+	}
+	for _, filter := range s.getFilters() {
+		if filter.Owner == repo.owner && filter.Repo == repo.name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasActiveRuns(runs []*github.WorkflowRun) bool {
+	for _, run := range runs {
+		if !run.IsCompleted() || run.ActiveRun != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// The latest update of the runs and their number, both change when a run is added or updated
+func runsFingerprint(runs []*github.WorkflowRun) (time.Time, int) {
+	lastUpdate := time.Time{}
+	for _, run := range runs {
+		if run.JobUpdateTime.After(lastUpdate) {
+			lastUpdate = run.JobUpdateTime
+		}
+	}
+	return lastUpdate, len(runs)
+}
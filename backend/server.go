@@ -5,149 +5,363 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"math/rand"
 	"net/http"
-	"sort"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/newestuser/github-workflow-dashboard/config"
 	"github.com/newestuser/github-workflow-dashboard/formatter"
 	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/snapshot"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/newestuser/github-workflow-dashboard/store"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Stored runs that didn't succeed are refetched for this long after they were created, since their re-runs update them
+const rerunWindow = 24 * time.Hour
+
 type Options struct {
 	Port                int
 	Filters             []*github.WorkflowFilter
 	PollInterval        time.Duration
 	LatestOnly          bool
 	ParseWorkflowParams bool
+	// Parse the workflow files of the tracked workflows to show their triggers and missed schedules
+	ParseWorkflowDefinitions bool
+	// Owners whose repositories are discovered and tracked in addition to Filters
+	Discovery         []*github.DiscoveryFilter
+	DiscoveryInterval time.Duration
+	// Track the self-hosted runners of every tracked repository
+	RepoRunners bool
+	// Organizations whose self-hosted runners are tracked
+	OrgRunners []string
+	// Report the state and last run of all workflows of every tracked repository
+	WorkflowReport bool
+	// Workflows that haven't run for longer are reported as stale
+	StaleAfter time.Duration
+	// Report flaky workflows and jobs, the jobs of failed and re-run runs are fetched for that
+	FlakyReport bool
+	// Runs of tracked workflows that are deployments, used to compute delivery metrics
+	Deployments []*stats.DeploymentSelector
+	// Report duration regressions of workflows and jobs, the jobs of successful runs are fetched for that
+	RegressionReport bool
+	Regression       stats.RegressionOptions
+	// Show a row per workflow and branch with sparklines of the last History runs instead of a row per run, 0 means off
+	History int
+	// Location of the hours and weekdays of the heatmap, UTC if nil
+	Timezone *time.Location
+	// Keeps every fetched run so that only new runs are fetched and the state is loaded on start, nil keeps no history
+	Store *store.Store
+	// Stored runs created longer ago are deleted, zero keeps them forever
+	Retention time.Duration
+	// State served on start until the repositories are polled, nil starts with the stored or an empty state
+	Snapshot *snapshot.Snapshot
+	// Rate limit of the github responses reported as a metric, nil doesn't report it
+	RateLimits *github.RateLimitTracker
+	// Repositories changed at runtime are persisted to the file, the repositories of the file replace Filters if it exists
+	ConfigFile string
+	// Token of the admin API and page managing the tracked repositories, both are disabled if empty
+	AdminToken string
+	// Limit and latest options of the repositories tracked at runtime
+	Limit  int
+	Latest github.LatestOptions
+	// Poll intervals of repositories by owner/repo overriding PollInterval
+	RepoPollIntervals map[string]time.Duration
+	// Repositories are first polled at a random time within the jitter after the start instead of all at once
+	StartupJitter time.Duration
+	// Poll repositories with queued or in progress runs every ActivePollInterval if that is shorter than their poll
+	// interval, the interval of repositories without new or updated runs doubles after every poll up to MaxPollInterval
+	AdaptivePolling    bool
+	ActivePollInterval time.Duration
+	MaxPollInterval    time.Duration
+	// Fetching a repository, discovering the repositories of an owner or fetching a report is cancelled after the
+	// timeout, zero doesn't time out
+	PollTimeout time.Duration
+	// Time Run waits for in flight requests after its context was cancelled, zero waits until they finished
+	ShutdownTimeout time.Duration
+	// Path under which the dashboard is served, e.g. /ci, without a trailing slash, empty serves it at the root
+	PathPrefix string
+}
+
+func (o *Options) tracksRunners() bool {
+	return o.RepoRunners || len(o.OrgRunners) > 0
 }
 
-func NewServer(client *github.WorkflowClient, opts *Options) *Server {
+func NewServer(client github.WorkflowClient, opts *Options) *Server {
+	pollIntervals := make(map[RepoId]time.Duration)
+	for repo, interval := range opts.RepoPollIntervals {
+		if ownerAndRepo := strings.SplitN(repo, "/", 2); len(ownerAndRepo) == 2 {
+			pollIntervals[RepoId{owner: ownerAndRepo[0], name: ownerAndRepo[1]}] = interval
+		}
+	}
+
 	return &Server{
-		client:     client,
-		opts:       opts,
-		stateMutex: sync.Mutex{},
-		state:      newStateRepo(),
+		client:        client,
+		opts:          opts,
+		stateMutex:    sync.Mutex{},
+		filters:       opts.Filters,
+		pollIntervals: pollIntervals,
+		schedules:     make(map[RepoId]*repoSchedule),
+		refreshes:     make(chan RepoId, 64),
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		templates:     newPageTemplates(opts.PathPrefix),
+		state:         newStateRepo(),
+		events:        newStateEvents(),
+		discovered:    make(map[string][]*github.WorkflowFilter),
+		jobs:          make(map[int]*cachedJobs),
 	}
 }
 
 type Server struct {
-	client *github.WorkflowClient
-	opts   *Options
+	client    github.WorkflowClient
+	opts      *Options
+	templates *pageTemplates
 
 	stateMutex sync.Mutex
-	state      *stateRepository
+	// explicitly tracked repositories and their poll intervals, replaced when they are changed at runtime
+	filters       []*github.WorkflowFilter
+	pollIntervals map[RepoId]time.Duration
+	state         *stateRepository
+	events        *stateEvents
+	runners       *runnerState
+	workflows     *workflowState
+	flaky         *flakyState
+	regressions   *regressionState
+	polls         *pollStatus
+
+	// discovered workflow filters by discovery owner, only accessed by the poll goroutine
+	discovered    map[string][]*github.WorkflowFilter
+	lastDiscovery time.Time
+	// jobs of runs by run ID, only accessed by the poll goroutine
+	jobs map[int]*cachedJobs
+	// schedules of the polls of the tracked repositories, only accessed by the poll goroutine
+	schedules map[RepoId]*repoSchedule
+	random    *rand.Rand
+	// repositories requested to be polled right away
+	refreshes chan RepoId
+	// serializes the changes of the tracked repositories
+	adminMutex sync.Mutex
 }
 
-type stateRepository struct {
-	data map[RepoId]*repoState
+type cachedJobs struct {
+	attempt int
+	jobs    []*github.RunJob
 }
 
-func newStateRepo() *stateRepository {
-	return &stateRepository{
-		data: make(map[RepoId]*repoState),
-	}
+type runnerState struct {
+	Runners    []*github.Runner    `json:"runners"`
+	QueuedJobs []*github.QueuedJob `json:"queuedJobs"`
+	Uts        time.Time           `json:"uts"`
 }
 
-func (r *stateRepository) setMulti(newState []*repoState) {
-	for _, s := range newState {
-		r.set(s)
-	}
+type workflowState struct {
+	Workflows []*github.Workflow `json:"workflows"`
+	Uts       time.Time          `json:"uts"`
 }
 
-func (r *stateRepository) set(newState *repoState) {
-	if r.data == nil {
-		r.data = make(map[RepoId]*repoState)
-	}
-	r.data[newState.repo] = newState
+type flakyState struct {
+	Reports []*stats.FlakinessReport `json:"reports"`
+	Uts     time.Time                `json:"uts"`
+}
 
+type regressionState struct {
+	Regressions []*stats.DurationRegression `json:"regressions"`
+	Uts         time.Time                   `json:"uts"`
 }
 
-func (r *stateRepository) filter(owner, repo, workflow string) []*repoState {
-	result := make([]*repoState, 0)
-	// find all
-	if owner == "" && repo == "" && workflow == "" {
-		for _, val := range r.data {
-			result = append(result, val)
-		}
-		return result
-	}
+type RepoId struct {
+	owner string
+	name  string
+}
 
-	// find by owner
-	if owner != "" && repo == "" && workflow == "" {
-		for _, val := range r.data {
-			if val.repo.owner == owner {
-				result = append(result, val)
-			}
-		}
-		return result
+func (r RepoId) String() string {
+	return fmt.Sprintf("%s/%s", r.owner, r.name)
+}
+
+type repoState struct {
+	repo        RepoId
+	runs        []*github.WorkflowRun
+	definitions []*github.WorkflowDefinition
+	uts         time.Time
+	// imported from a snapshot, kept even if the repository isn't tracked
+	imported bool
+}
+
+// The templates of the pages, their links are prefixed with the path prefix of the server by the path function
+type pageTemplates struct {
+	dashboard   *template.Template
+	repository  *template.Template
+	runners     *template.Template
+	workflows   *template.Template
+	flaky       *template.Template
+	regressions *template.Template
+	heatmap     *template.Template
+	dora        *template.Template
+	admin       *template.Template
+}
+
+func newPageTemplates(pathPrefix string) *pageTemplates {
+	funcs := template.FuncMap{"path": func(path string) string { return pathPrefix + path }}
+	parse := func(name, text string) *template.Template {
+		return template.Must(template.New(name).Funcs(funcs).Parse(text))
 	}
 
-	// find by owner and repo
-	if owner != "" && repo != "" && workflow == "" {
-		for _, val := range r.data {
-			if val.repo.owner == owner && val.repo.name == repo {
-				result = append(result, val)
-			}
-		}
-		return result
+	return &pageTemplates{
+		dashboard:   parse("dashboard", dashboardHTMLTemplate),
+		repository:  parse("repository", repositoryHTMLTemplate),
+		runners:     parse("runners", runnersHTMLTemplate),
+		workflows:   parse("workflows", workflowsHTMLTemplate),
+		flaky:       parse("flaky", flakyHTMLTemplate),
+		regressions: parse("regressions", regressionsHTMLTemplate),
+		heatmap:     parse("heatmap", heatmapHTMLTemplate),
+		dora:        parse("dora", doraHTMLTemplate),
+		admin:       parse("admin", adminHTMLTemplate),
 	}
+}
 
-	// find by owner, repo and workflow
-	if owner != "" && repo != "" && workflow != "" {
-		for _, val := range r.data {
-			if val.repo.owner == owner && val.repo.name == repo {
+// Serves the dashboard and polls github until the process exits
+func (s *Server) Start() error {
+	return s.Run(context.Background())
+}
 
-				tmpState := &repoState{
-					repo: val.repo,
-					uts:  val.uts,
-					runs: make([]*github.WorkflowRun, 0),
-				}
-				for _, run := range val.runs {
-					if run.WorkflowName == workflow {
-						tmpState.runs = append(tmpState.runs, run)
-					}
-				}
+// Serves the dashboard and polls github until the context is cancelled, then stops polling and waits up to
+// ShutdownTimeout for in flight requests to finish
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.init(); err != nil {
+		return err
+	}
 
-				result = append(result, tmpState)
-			}
-		}
-		return result
+	httpServer := s.newHTTPServer()
+
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		s.pollGithubWorkflows(pollCtx)
+	}()
+	defer func() {
+		stopPolling()
+		<-polled
+		log.Info("Stopped polling github")
+	}()
+
+	served := make(chan error, 1)
+	go func() {
+		log.Info("starting web server on port ", s.opts.Port, " under path ", s.path("/"))
+		served <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
 	}
 
-	// no other filters are supported
-	panic(fmt.Errorf("filtering workflow runs by (owner=%s, repo=%s, workflow=%s) is not supported", owner, repo, workflow))
+	log.Info("Shutting down web server")
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	if s.opts.ShutdownTimeout > 0 {
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.opts.ShutdownTimeout)
+	}
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down web server failed, err: %s", err)
+	}
+	return nil
 }
 
-type RepoId struct {
-	owner string
-	name  string
+// The web server of the dashboard. Its requests aren't cancelled on shutdown so that they can complete within the
+// shutdown timeout, only the event streams that would never complete are ended.
+func (s *Server) newHTTPServer() *http.Server {
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.opts.Port),
+		Handler: s.Handler(),
+	}
+	httpServer.RegisterOnShutdown(s.events.close)
+	return httpServer
 }
 
-func (r RepoId) String() string {
-	return fmt.Sprintf("%s/%s", r.owner, r.name)
+// Polls github until the context is cancelled without serving the dashboard, for services serving Handler themselves
+func (s *Server) Poll(ctx context.Context) error {
+	if err := s.init(); err != nil {
+		return err
+	}
+	s.pollGithubWorkflows(ctx)
+	return nil
 }
 
-type repoState struct {
-	repo RepoId
-	runs []*github.WorkflowRun
-	uts  time.Time
+// Loads the tracked repositories and the state served until the repositories are polled
+func (s *Server) init() error {
+	if err := s.loadConfig(); err != nil {
+		return err
+	}
+	if s.opts.Store != nil {
+		s.updateState(s.loadStoredStatesIgnoringErrors())
+	}
+	if s.opts.Snapshot != nil {
+		s.restoreSnapshot(s.opts.Snapshot)
+	}
+	return nil
 }
 
-var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTMLTemplate))
-var repositoryTemplate = template.Must(template.New("repository").Parse(repositoryHTMLTemplate))
+// The dashboard and its API under the path prefix, it can be mounted into the router of another service without
+// stripping the prefix
+func (s *Server) Handler() http.Handler {
+	return s.newRouter()
+}
 
-func (s *Server) Start() error {
-	log.Info("starting web server on port ", s.opts.Port)
-	go s.pollGithubWorkflows()
+// The path of the dashboard or the API under the path prefix
+func (s *Server) path(path string) string {
+	return s.opts.PathPrefix + path
+}
 
-	r := mux.NewRouter()
+func (s *Server) newRouter() *mux.Router {
+	root := mux.NewRouter()
+	r := root
+	if s.opts.PathPrefix != "" {
+		root.Handle(s.opts.PathPrefix, http.RedirectHandler(s.path("/"), http.StatusMovedPermanently))
+		r = root.PathPrefix(s.opts.PathPrefix).Subrouter()
+	}
+	r.Use(s.withState)
 	r.HandleFunc("/", dashboard(s))
 
+	// pages and APIs that aren't about an owner are registered before the owner routes would match them
+	handleWithReservedAlias(r, "/runners", runnersDashboard(s))
+	handleWithReservedAlias(r, "/workflows", workflowsDashboard(s))
+	handleWithReservedAlias(r, "/flaky", flakyDashboard(s))
+	handleWithReservedAlias(r, "/regressions", regressionsDashboard(s))
+	handleWithReservedAlias(r, "/heatmap", heatmapDashboard(s))
+	handleWithReservedAlias(r, "/dora", doraDashboard(s))
+	handleWithReservedAlias(r, "/metrics", metricsHandler(s))
+	handleWithReservedAlias(r, "/healthz", healthz(s))
+	handleWithReservedAlias(r, "/readyz", readyz(s))
+	handleWithReservedAlias(r, "/admin", adminDashboard(s))
+
+	handleWithReservedAlias(r, "/api/runners", runnersJson(s))
+	handleWithReservedAlias(r, "/api/workflows", workflowsJson(s))
+	handleWithReservedAlias(r, "/api/flaky", flakyJson(s))
+	handleWithReservedAlias(r, "/api/regressions", regressionsJson(s))
+	handleWithReservedAlias(r, "/api/heatmap", heatmapJson(s))
+	handleWithReservedAlias(r, "/api/dora", doraJson(s))
+	handleWithReservedAlias(r, "/api/snapshot", snapshotApi(s))
+	handleWithReservedAlias(r, "/api/events", eventsApi(s))
+	handleWithReservedAlias(r, "/api/runs", runsJson(s))
+	handleWithReservedAlias(r, "/api/status", statusJson(s))
+	handleWithReservedAlias(r, "/api/admin/repositories", adminRepositoriesApi(s))
+	handleWithReservedAlias(r, "/api/admin/repositories/{owner}/{repo}", adminRepositoryApi(s))
+	handleWithReservedAlias(r, "/api/refresh/{owner}/{repo}", refreshApi(s))
+	handleWithReservedAlias(r, "/api/stats/{owner}/{repo}", statsJson(s))
+	r.Handle("/api/_", http.NotFoundHandler())
+	r.PathPrefix("/api/_/").Handler(http.NotFoundHandler())
+	r.Handle("/_", http.NotFoundHandler())
+	r.PathPrefix("/_/").Handler(http.NotFoundHandler())
+
 	r.HandleFunc("/api/{owner}", ownerJson(s))
 	r.HandleFunc("/api/{owner}/{repo}", repoJson(s))
 	r.HandleFunc("/api/{owner}/{repo}/{workflow}", workflowJson(s))
@@ -156,34 +370,81 @@ func (s *Server) Start() error {
 	r.HandleFunc("/{owner}/{repo}", repoDashboard(s))
 	r.HandleFunc("/{owner}/{repo}/{workflow}", workflowDashboard(s))
 
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.opts.Port), r)
+	return root
+}
+
+// Serves a page or API that isn't about an owner at its path and at the same path under the reserved owner, e.g. /runners
+// and /_/runners, which no tracked owner can shadow
+func handleWithReservedAlias(r *mux.Router, path string, handler http.HandlerFunc) {
+	r.HandleFunc(path, handler)
+	if strings.HasPrefix(path, "/api/") {
+		r.HandleFunc("/api/"+config.ReservedOwner+strings.TrimPrefix(path, "/api"), handler)
+	} else {
+		r.HandleFunc("/"+config.ReservedOwner+path, handler)
+	}
 }
 
-func filterAndRenderRepoSections(w http.ResponseWriter, server *Server, owner, repo, workflow string) {
-	state, _ := server.getState()
-	repoState := state.filter(owner, repo, workflow)
+type stateContextKey struct{}
 
-	sort.Slice(repoState, func(i, j int) bool {
-		return repoState[i].repo.String() < repoState[j].repo.String()
+// Pins the current version of the state to the request so that handlers read a consistent state, the version and its
+// creation time are sent as headers
+func (s *Server) withState(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := s.getState()
+		w.Header().Set("X-State-Version", strconv.FormatUint(state.version, 10))
+		if !state.uts.IsZero() {
+			w.Header().Set("X-State-Updated", state.uts.UTC().Format(time.RFC3339))
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), stateContextKey{}, state)))
 	})
+}
+
+// The version of the state pinned to the request, the current version if none was pinned
+func (s *Server) requestState(r *http.Request) *stateRepository {
+	if state, ok := r.Context().Value(stateContextKey{}).(*stateRepository); ok {
+		return state
+	}
+	return s.getState()
+}
 
-	repoHTML, err := renderMultipleRepoHTMLSections(repoState)
+func filterAndRenderRepoSections(w http.ResponseWriter, r *http.Request, server *Server, owner, repo, workflow string) {
+	state := server.requestState(r)
+	repoState := state.queryRepos(&RunQuery{Owner: owner, Repo: repo, Workflow: workflow})
+
+	repoHTML, err := server.renderMultipleRepoHTMLSections(repoState)
 	if err != nil {
 		log.Error(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	renderDashboard(w, &dashboardHTMLViewModel{
-		Repositories: repoHTML,
+	events := url.Values{}
+	for key, value := range map[string]string{"owner": owner, "repo": repo, "workflow": workflow} {
+		if value != "" {
+			events.Set(key, value)
+		}
+	}
+
+	server.renderDashboard(w, &dashboardHTMLViewModel{
+		Repositories:   repoHTML,
+		Events:         server.path("/api/events?" + events.Encode()),
+		RefreshSeconds: server.refreshSeconds(),
 	})
 }
 
+// Interval in which pages without live updates are reloaded
+func (s *Server) refreshSeconds() int {
+	if s.opts.PollInterval < time.Minute {
+		return 60
+	}
+	return int(s.opts.PollInterval.Seconds())
+}
+
 func ownerDashboard(server *Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		params := mux.Vars(r)
 		owner := params["owner"]
-		filterAndRenderRepoSections(w, server, owner, "", "")
+		filterAndRenderRepoSections(w, r, server, owner, "", "")
 	}
 }
 
@@ -193,7 +454,7 @@ func repoDashboard(server *Server) http.HandlerFunc {
 		owner := params["owner"]
 		repo := params["repo"]
 
-		filterAndRenderRepoSections(w, server, owner, repo, "")
+		filterAndRenderRepoSections(w, r, server, owner, repo, "")
 	}
 }
 
@@ -203,14 +464,20 @@ func workflowDashboard(server *Server) http.HandlerFunc {
 		owner := params["owner"]
 		repo := params["repo"]
 		workflow := params["workflow"]
-		filterAndRenderRepoSections(w, server, owner, repo, workflow)
+		filterAndRenderRepoSections(w, r, server, owner, repo, workflow)
 	}
 }
 
 // Serve a dashboard with all available workflow runs
 func dashboard(server *Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		filterAndRenderRepoSections(w, server, "", "", "")
+		filterAndRenderRepoSections(w, r, server, "", "", "")
+	}
+}
+
+func runsJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveWorkflowJson(w, r, server, "", "", "")
 	}
 }
 
@@ -218,7 +485,7 @@ func ownerJson(server *Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		params := mux.Vars(r)
 		owner := params["owner"]
-		serveWorkflowJson(w, server, owner, "", "")
+		serveWorkflowJson(w, r, server, owner, "", "")
 	}
 }
 
@@ -227,7 +494,7 @@ func repoJson(server *Server) http.HandlerFunc {
 		params := mux.Vars(r)
 		owner := params["owner"]
 		repo := params["repo"]
-		serveWorkflowJson(w, server, owner, repo, "")
+		serveWorkflowJson(w, r, server, owner, repo, "")
 	}
 }
 
@@ -237,23 +504,45 @@ func workflowJson(server *Server) http.HandlerFunc {
 		owner := params["owner"]
 		repo := params["repo"]
 		workflow := params["workflow"]
-		serveWorkflowJson(w, server, owner, repo, workflow)
+		serveWorkflowJson(w, r, server, owner, repo, workflow)
 	}
 }
 
-// Serve github workflow data as a json response
-func serveWorkflowJson(w http.ResponseWriter, server *Server, owner, repo, workflow string) {
-	state, err := server.getState()
+// Serve the runs matching the path and the query params as a json response, see parseRunQuery for the params
+func serveWorkflowJson(w http.ResponseWriter, r *http.Request, server *Server, owner, repo, workflow string) {
+	query, err := parseRunQuery(r.URL.Query())
 	if err != nil {
-		log.Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields, err := parseRunFields(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	result := make([]*github.WorkflowRun, 0)
-	multiRepState := state.filter(owner, repo, workflow)
-	for _, value := range multiRepState {
-		result = append(result, value.runs...)
+	// the path takes precedence over the query params
+	for value, pathValue := range map[*string]string{&query.Owner: owner, &query.Repo: repo, &query.Workflow: workflow} {
+		if pathValue != "" {
+			*value = pathValue
+		}
+	}
+
+	state := server.requestState(r)
+	page, err := state.queryPage(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := &runsResponse{Version: state.version, Updated: state.uts, Total: page.total}
+	if page.last != 0 {
+		result.NextCursor = encodeCursor(page.last)
+	}
+	if result.Runs, err = selectRunFields(page.runs, fields); err != nil {
+		log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Add("Content-Type", "application/json")
@@ -264,166 +553,1202 @@ func serveWorkflowJson(w http.ResponseWriter, server *Server, owner, repo, workf
 	}
 }
 
-func renderMultipleRepoHTMLSections(state []*repoState) ([]template.HTML, error) {
-	sections := make([]template.HTML, 0)
-	for _, repoState := range state {
-		repoHtml, err := renderRepoHTMLSection(repoState)
+// Serve a dashboard with all self-hosted runners and the jobs waiting for them
+func runnersDashboard(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runners := server.getRunners()
+
+		body, err := formatter.RunnersToHTML(runners.Runners, runners.QueuedJobs)
 		if err != nil {
-			return nil, err
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		sections = append(sections, repoHtml)
+
+		server.renderReportPage(w, server.templates.runners, &reportHTMLViewModel{
+			LastUpdateTime: fmt.Sprintf("%s ago", time.Since(runners.Uts).Round(time.Second)),
+			Body:           body,
+		})
 	}
-	return sections, nil
 }
 
-func renderRepoHTMLSection(repoState *repoState) (template.HTML, error) {
-	htmlBody, err := formatter.ToHTMLWithCustomLink(repoState.runs, func(run *github.WorkflowRun) string {
-		return fmt.Sprintf("/%s/%s/%s", run.WorkflowOwner, run.WorkflowRepo, run.WorkflowName)
-	})
+// Serve a ranking of the flaky workflows and jobs of the tracked repositories
+func flakyDashboard(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flaky := server.getFlaky()
 
-	if err != nil {
-		return "", err
+		body, err := formatter.FlakinessToHTML(flaky.Reports)
+		if err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		server.renderReportPage(w, server.templates.flaky, &reportHTMLViewModel{
+			LastUpdateTime: fmt.Sprintf("%s ago", time.Since(flaky.Uts).Round(time.Second)),
+			Body:           body,
+		})
 	}
+}
 
-	repoHtmlModel := repsotioryHTMLViewModel{
-		Owner:          repoState.repo.owner,
-		Repository:     repoState.repo.name,
-		LastUpdateTime: fmt.Sprintf("%s ago", time.Since(repoState.uts).Round(time.Second)),
-		Body:           template.HTML(htmlBody),
+// Serve the ranking of flaky workflows and jobs as a json response
+func flakyJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(server.getFlaky()); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
+}
 
-	repoHtml := &strings.Builder{}
-	if err := repositoryTemplate.Execute(repoHtml, repoHtmlModel); err != nil {
-		return "", err
+// Serve the duration regressions and anomalies of the tracked workflows and jobs
+func regressionsDashboard(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		regressions := server.getRegressions()
+
+		body, err := formatter.RegressionsToHTML(regressions.Regressions)
+		if err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		server.renderReportPage(w, server.templates.regressions, &reportHTMLViewModel{
+			LastUpdateTime: fmt.Sprintf("%s ago", time.Since(regressions.Uts).Round(time.Second)),
+			Body:           body,
+		})
 	}
+}
 
-	return template.HTML(repoHtml.String()), nil
+// Serve the duration regressions and anomalies as a json response, meant for alerting.
+// Pass ?regressed=true to leave out workflows and jobs that only have anomalies.
+func regressionsJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		regressions := server.getRegressions()
+
+		if r.URL.Query().Get("regressed") == "true" {
+			regressed := make([]*stats.DurationRegression, 0)
+			for _, regression := range regressions.Regressions {
+				if regression.Regressed {
+					regressed = append(regressed, regression)
+				}
+			}
+			regressions = &regressionState{Regressions: regressed, Uts: regressions.Uts}
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(regressions); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 }
 
-func renderDashboard(w http.ResponseWriter, viewModel *dashboardHTMLViewModel) {
-	if err := dashboardTemplate.Execute(w, viewModel); err != nil {
-		log.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// Serve the run activity of all tracked repositories by hour and weekday, colored by ?metric=runs|failure-rate|queue-time.
+// The runs can be restricted to the last N days (?since-days=N) and the hours shifted to a timezone (?timezone=Europe/Berlin)
+func heatmapDashboard(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metric := r.URL.Query().Get("metric")
+		if metric == "" {
+			metric = formatter.HeatmapRuns
+		}
+		if !formatter.IsHeatmapMetric(metric) {
+			http.Error(w, fmt.Sprintf("metric must be one of %v, metric=%s", formatter.HeatmapMetrics, metric), http.StatusBadRequest)
+			return
+		}
+
+		heatmap, err := server.computeHeatmap(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body, err := formatter.HeatmapToHTML(heatmap, metric)
+		if err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		links := make([]*heatmapLinkViewModel, 0)
+		for _, m := range formatter.HeatmapMetrics {
+			query.Set("metric", m)
+			links = append(links, &heatmapLinkViewModel{Metric: m, URL: server.path("/heatmap?" + query.Encode()), Selected: m == metric})
+		}
+
+		server.renderReportPage(w, server.templates.heatmap, &heatmapHTMLViewModel{
+			Timezone: heatmap.Timezone,
+			Runs:     heatmap.Runs,
+			Metrics:  links,
+			Body:     body,
+		})
 	}
 }
 
-func (s *Server) pollGithubWorkflows() {
-	// trigger poll imiediately after which it should be periodic
-	results := s.fetchAllStatesIgnoringErrors(time.Now())
-	s.updateState(results)
+// Serve the run activity by hour and weekday as a json response, accepts the same params as the heatmap dashboard
+func heatmapJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		heatmap, err := server.computeHeatmap(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	for tick := range time.Tick(s.opts.PollInterval) {
-		results := s.fetchAllStatesIgnoringErrors(tick)
-		s.updateState(results)
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(heatmap); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 }
 
-func (s *Server) fetchAllStatesIgnoringErrors(uts time.Time) []*repoState {
-	fetchExecTs := time.Now()
-	allResults := make([]*repoState, 0)
-	allRepos := strings.Builder{}
+func (s *Server) computeHeatmap(r *http.Request) (*stats.Heatmap, error) {
+	opts, err := parseStatsOptions(r, time.Now())
+	if err != nil {
+		return nil, err
+	}
 
-	for _, f := range s.opts.Filters {
-		allRepos.WriteString(f.GetRepoId().String())
-		allRepos.WriteString(" ")
+	location := s.opts.Timezone
+	if location == nil {
+		location = time.UTC
+	}
+	if value := r.URL.Query().Get("timezone"); value != "" {
+		if location, err = time.LoadLocation(value); err != nil {
+			return nil, fmt.Errorf("unknown timezone=%s, err: %s", value, err)
+		}
 	}
 
-	log.Info("Start fetching state for all repos: ", allRepos.String())
-	for _, filter := range s.opts.Filters {
-		repoExecTs := time.Now()
-		ownerAndRepo := fmt.Sprintf("%s/%s", filter.Owner, filter.Repo)
+	runs, _ := s.requestState(r).queryRuns(&RunQuery{})
 
-		log.Info("Fetching state for repo: ", ownerAndRepo)
-		repoResult, err := s.fetchState(filter, uts)
+	return stats.ComputeHeatmap(runs, location, opts.Since), nil
+}
+
+// Serve delivery metrics of the deployments, the deployments can be restricted to the last N days (?since-days=N)
+// and split into windows of N days (?window-days=N)
+func doraDashboard(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metrics, err := server.computeDeliveryMetrics(r)
 		if err != nil {
-			log.Warn("Failed fetching state for repo: ", ownerAndRepo, " after ", time.Since(repoExecTs).Round(time.Second))
-			continue
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		log.Info("Successfully fetched state for repo: ", ownerAndRepo, " in ", time.Since(repoExecTs).Round(time.Second), " runs: ", filterNames(repoResult.runs))
-		allResults = append(allResults, repoResult)
+		body, err := formatter.DeliveryMetricsToHTML(metrics)
+		if err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		deployments := make([]string, 0)
+		for _, selector := range server.opts.Deployments {
+			deployments = append(deployments, selector.String())
+		}
+
+		server.renderReportPage(w, server.templates.dora, &doraHTMLViewModel{Deployments: deployments, Body: body})
 	}
-	log.Info("Successfully fetched state of all repos in ", time.Since(fetchExecTs).Round(time.Second))
-	return allResults
 }
 
-func (s *Server) fetchState(filter *github.WorkflowFilter, timestamp time.Time) (*repoState, error) {
-	var runs []*github.WorkflowRun
-	var err error
-	ctx := context.Background()
+// Serve delivery metrics of the deployments as a json response, accepts the same params as the dora dashboard
+func doraJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metrics, err := server.computeDeliveryMetrics(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	if s.opts.LatestOnly {
-		runs, err = s.client.FetchLatestWorkflowRuns(ctx, filter)
-	} else {
-		runs, err = s.client.FetchWorkflowRuns(ctx, filter)
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(metrics); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
+}
 
+func (s *Server) computeDeliveryMetrics(r *http.Request) ([]*stats.DeliveryMetrics, error) {
+	opts, err := parseStatsOptions(r, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
-	if s.opts.ParseWorkflowParams {
-		for _, run := range runs {
-			params, err := s.client.FetchWorkflowRunParams(ctx, filter, run.JobRunID)
-			if err != nil {
-				log.Warn("failed fetching workflow params for ", fmt.Sprintf("%s/%s", filter.Owner, filter.Repo), " workflow: ", run.WorkflowName, " runId: ", run.JobRunID, ", it will be omitted, err: ", err)
-				continue
-			}
-			run.WorkflowParams = params
+	runs, _ := s.requestState(r).queryRuns(&RunQuery{})
+
+	return stats.ComputeDeliveryMetrics(runs, s.opts.Deployments, opts), nil
+}
+
+// Serve stats of the runs of a repository as a json response, the runs can be grouped by branch (?branch=true),
+// restricted to the last N days (?since-days=N) and split into windows of N days (?window-days=N)
+func statsJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		opts, err := parseStatsOptions(r, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-	}
 
-	return &repoState{
-		repo: RepoId{owner: filter.Owner, name: filter.Repo},
-		runs: runs,
-		uts:  timestamp,
-	}, nil
+		runs, _ := server.requestState(r).queryRuns(&RunQuery{Owner: params["owner"], Repo: params["repo"]})
+
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats.Compute(runs, opts)); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 }
 
-func filterNames(runs []*github.WorkflowRun) []string {
-	namesMap := map[string]bool{}
-	for _, run := range runs {
-		namesMap[run.WorkflowName] = true
+func parseStatsOptions(r *http.Request, now time.Time) (stats.Options, error) {
+	query := r.URL.Query()
+	opts := stats.Options{}
+
+	if value := query.Get("branch"); value != "" {
+		groupByBranch, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("branch must be a boolean, branch=%s", value)
+		}
+		opts.GroupByBranch = groupByBranch
 	}
 
-	names := make([]string, 0)
-	for k, _ := range namesMap {
-		names = append(names, k)
+	if value := query.Get("since-days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return opts, fmt.Errorf("since-days must be >= 0, since-days=%s", value)
+		}
+		if days > 0 {
+			opts.Since = now.Add(-time.Duration(days) * 24 * time.Hour)
+		}
 	}
 
-	return names
-}
+	if value := query.Get("window-days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return opts, fmt.Errorf("window-days must be >= 0, window-days=%s", value)
+		}
+		opts.Window = time.Duration(days) * 24 * time.Hour
+	}
 
-func (s *Server) getState() (*stateRepository, error) {
-	return s.state, nil
+	return opts, nil
 }
 
-func (s *Server) updateState(newState []*repoState) {
-	s.state.setMulti(newState)
-}
+// Export the state of all repositories as a snapshot file on GET, replace the state of the repositories of an
+// uploaded snapshot on POST
+func snapshotApi(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			exported := server.newSnapshot(server.requestState(r), time.Now())
+			w.Header().Add("Content-Type", "application/gzip")
+			w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"dashboard-%s.json.gz\"", exported.CreatedAt.UTC().Format("20060102T150405Z")))
+			if err := snapshot.Write(w, exported); err != nil {
+				log.Error(err.Error())
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		case http.MethodPost:
+			imported, err := snapshot.Read(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			server.restoreSnapshot(imported)
 
-func (s *Server) lockState() {
-	s.stateMutex.Lock()
+			w.Header().Add("Content-Type", "application/json")
+			result := map[string]int{"repositories": len(imported.Repositories), "runs": len(imported.Runs())}
+			if err := json.NewEncoder(w).Encode(result); err != nil {
+				log.Error(err.Error())
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		default:
+			w.Header().Add("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
 }
 
-func (s *Server) unlockState() {
-	s.stateMutex.Unlock()
+// Serve self-hosted runners and the jobs waiting for them as a json response
+func runnersJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(server.getRunners()); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 }
 
-type dashboardHTMLViewModel struct {
-	Repositories []template.HTML
+// Serve a report of all workflows of the tracked repositories with their state and last run
+func workflowsDashboard(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workflows := server.getWorkflows()
+
+		body, err := formatter.WorkflowsToHTML(workflows.Workflows)
+		if err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		server.renderReportPage(w, server.templates.workflows, &workflowsHTMLViewModel{
+			LastUpdateTime: fmt.Sprintf("%s ago", time.Since(workflows.Uts).Round(time.Second)),
+			StaleDays:      int(server.opts.StaleAfter.Hours() / 24),
+			Body:           body,
+		})
+	}
 }
 
-type repsotioryHTMLViewModel struct {
-	Owner          string
-	Repository     string
-	LastUpdateTime string
-	Body           template.HTML
+// Serve the workflow report as a json response
+func workflowsJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(server.getWorkflows()); err != nil {
+			log.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 }
 
-const dashboardHTMLTemplate = `
-<html>
-	<head>
+func (s *Server) renderMultipleRepoHTMLSections(state []*repoState) ([]template.HTML, error) {
+	regressions := s.getRegressions().Regressions
+	sections := make([]template.HTML, 0)
+	for _, repoState := range state {
+		repoHtml, err := s.renderRepoHTMLSection(repoState, regressionsOf(repoState, regressions))
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, repoHtml)
+	}
+	return sections, nil
+}
+
+// The regressions of the workflows whose runs are in the state
+func regressionsOf(repoState *repoState, regressions []*stats.DurationRegression) []*stats.DurationRegression {
+	workflows := map[string]bool{}
+	for _, run := range repoState.runs {
+		workflows[run.WorkflowName] = true
+	}
+
+	result := make([]*stats.DurationRegression, 0)
+	for _, regression := range regressions {
+		if regression.WorkflowOwner == repoState.repo.owner && regression.WorkflowRepo == repoState.repo.name && workflows[regression.WorkflowName] {
+			result = append(result, regression)
+		}
+	}
+	return result
+}
+
+func (s *Server) renderRepoHTMLSection(repoState *repoState, regressions []*stats.DurationRegression) (template.HTML, error) {
+	workflowLink := func(run *github.WorkflowRun) string {
+		return s.path(fmt.Sprintf("/%s/%s/%s", run.WorkflowOwner, run.WorkflowRepo, run.WorkflowName))
+	}
+
+	var htmlBody template.HTML
+	var err error
+	if s.opts.History > 0 {
+		htmlBody, err = formatter.ToHTMLHistoryWithCustomLink(repoState.runs, s.opts.History, workflowLink)
+	} else {
+		htmlBody, err = formatter.ToHTMLWithCustomLink(repoState.runs, workflowLink)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	streaksHtml, err := formatter.StreaksToHTML(stats.ComputeStreaks(repoState.runs), time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	regressionsHtml, err := formatter.RegressionBannersToHTML(regressions)
+	if err != nil {
+		return "", err
+	}
+
+	definitionsHtml := template.HTML("")
+	if len(repoState.definitions) > 0 {
+		definitionsHtml, err = formatter.DefinitionsToHTML(repoState.definitions, time.Now())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	repoHtmlModel := repsotioryHTMLViewModel{
+		Owner:          repoState.repo.owner,
+		Repository:     repoState.repo.name,
+		SectionID:      repositorySectionID(repoState.repo),
+		LastUpdate:     repoState.uts.UTC().Format(time.RFC3339),
+		LastUpdateTime: fmt.Sprintf("%s ago", time.Since(repoState.uts).Round(time.Second)),
+		Body:           template.HTML(htmlBody),
+		Streaks:        streaksHtml,
+		Regressions:    regressionsHtml,
+		Definitions:    definitionsHtml,
+	}
+	if status, ok := s.getPollStatus().repos[repoState.repo]; ok && s.isStale(status, time.Now()) {
+		repoHtmlModel.PollProblem = s.pollProblem(status, time.Now())
+		repoHtmlModel.PollError = status.lastError
+	}
+
+	repoHtml := &strings.Builder{}
+	if err := s.templates.repository.Execute(repoHtml, repoHtmlModel); err != nil {
+		return "", err
+	}
+
+	return template.HTML(repoHtml.String()), nil
+}
+
+// Renders the section of a report page with the model into the dashboard
+func (s *Server) renderReportPage(w http.ResponseWriter, tmpl *template.Template, model interface{}) {
+	section := &strings.Builder{}
+	if err := tmpl.Execute(section, model); err != nil {
+		log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.renderDashboard(w, &dashboardHTMLViewModel{
+		Repositories: []template.HTML{template.HTML(section.String())},
+	})
+}
+
+func (s *Server) renderDashboard(w http.ResponseWriter, viewModel *dashboardHTMLViewModel) {
+	if err := s.templates.dashboard.Execute(w, viewModel); err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Polls every repository when it is due and the reports once per poll interval, repositories requested to be refreshed
+// are polled right away
+func (s *Server) pollGithubWorkflows(ctx context.Context) {
+	jitter := s.opts.StartupJitter
+	// the reports of the first poll wait for the repositories polled within the startup jitter
+	nextReports := time.Now().Add(jitter)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case repo := <-s.refreshes:
+			s.refresh(repo, time.Now())
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		now := time.Now()
+		s.discoverRepositoriesIfDue(ctx, now)
+		due, removed := s.dueFilters(now, jitter)
+		jitter = 0
+		if len(due) > 0 || removed {
+			states := s.fetchStatesIgnoringErrors(ctx, due, now)
+			// fetches cancelled by the shutdown would be reported as failed
+			if ctx.Err() != nil {
+				return
+			}
+			s.updatePolledState(states)
+		}
+
+		if !now.Before(nextReports) {
+			s.updateRunners(s.fetchRunnersIgnoringErrors(ctx, now))
+			s.updateWorkflows(s.fetchWorkflowsIgnoringErrors(ctx, now))
+			s.updateFlaky(s.detectFlakinessIgnoringErrors(ctx, now))
+			s.updateRegressions(s.detectRegressionsIgnoringErrors(ctx, now))
+			s.pruneJobs()
+			s.pruneStoredRuns(now)
+			nextReports = now.Add(s.opts.PollInterval)
+		}
+
+		timer.Reset(time.Until(s.nextWakeUp(nextReports)))
+	}
+}
+
+func (s *Server) fetchAllStatesIgnoringErrors(ctx context.Context, uts time.Time) []*repoState {
+	return s.fetchStatesIgnoringErrors(ctx, s.trackedFilters(), uts)
+}
+
+// The context of a single fetch of the poll, cancelled after the poll timeout
+func (s *Server) pollContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opts.PollTimeout > 0 {
+		return context.WithTimeout(ctx, s.opts.PollTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Fetches the state of the repositories of the filters and schedules their next polls, repositories that fail are skipped
+func (s *Server) fetchStatesIgnoringErrors(ctx context.Context, filters []*github.WorkflowFilter, uts time.Time) []*repoState {
+	fetchExecTs := time.Now()
+	allResults := make([]*repoState, 0)
+	allRepos := strings.Builder{}
+
+	for _, f := range filters {
+		allRepos.WriteString(f.GetRepoId().String())
+		allRepos.WriteString(" ")
+	}
+
+	results := make([]*repoPollResult, 0, len(filters))
+	log.Info("Start fetching state for repos: ", allRepos.String())
+	for _, filter := range filters {
+		if ctx.Err() != nil {
+			break
+		}
+		repoExecTs := time.Now()
+		repo := RepoId{owner: filter.Owner, name: filter.Repo}
+
+		log.Info("Fetching state for repo: ", repo)
+		fetchCtx, cancel := s.pollContext(ctx)
+		repoResult, err := s.fetchState(fetchCtx, filter, uts)
+		cancel()
+		schedule := s.reschedule(repo, repoResult, time.Now())
+		results = append(results, &repoPollResult{repo: repo, attempted: repoExecTs, err: err, interval: schedule.interval, next: schedule.next})
+		if err != nil {
+			log.Warn("Failed fetching state for repo: ", repo, " after ", time.Since(repoExecTs).Round(time.Second), ", err: ", err)
+			continue
+		}
+
+		log.Info("Successfully fetched state for repo: ", repo, " in ", time.Since(repoExecTs).Round(time.Second), " runs: ", filterNames(repoResult.runs), ", next poll in ", schedule.next.Sub(time.Now()).Round(time.Second))
+		allResults = append(allResults, repoResult)
+	}
+	log.Info("Successfully fetched state of repos in ", time.Since(fetchExecTs).Round(time.Second))
+	s.recordPoll(time.Since(fetchExecTs), results)
+	return allResults
+}
+
+// Fetch the runners of all runner scopes and the jobs of all tracked repositories waiting for a runner,
+// scopes and repositories that fail are skipped.
+func (s *Server) fetchRunnersIgnoringErrors(ctx context.Context, uts time.Time) *runnerState {
+	result := &runnerState{
+		Runners:    make([]*github.Runner, 0),
+		QueuedJobs: make([]*github.QueuedJob, 0),
+		Uts:        uts,
+	}
+
+	if !s.opts.tracksRunners() {
+		return result
+	}
+
+	ctx, cancel := s.pollContext(ctx)
+	defer cancel()
+	filters := s.trackedFilters()
+
+	scopes := make([]*github.RunnerScope, 0)
+	for _, org := range s.opts.OrgRunners {
+		scopes = append(scopes, &github.RunnerScope{Owner: org})
+	}
+	if s.opts.RepoRunners {
+		for _, filter := range filters {
+			scopes = append(scopes, &github.RunnerScope{Owner: filter.Owner, Repo: filter.Repo})
+		}
+	}
+
+	for _, scope := range scopes {
+		runners, err := s.client.FetchRunners(ctx, scope)
+		if err != nil {
+			log.Warn("Failed fetching runners of: ", scope, ", err: ", err)
+			continue
+		}
+		result.Runners = append(result.Runners, runners...)
+	}
+
+	for _, filter := range filters {
+		jobs, err := s.client.FetchQueuedJobs(ctx, filter)
+		if err != nil {
+			log.Warn("Failed fetching queued jobs of repo: ", filter.GetRepoId(), ", err: ", err)
+			continue
+		}
+		result.QueuedJobs = append(result.QueuedJobs, jobs...)
+	}
+
+	github.CorrelateQueuedJobs(result.QueuedJobs, result.Runners)
+	log.Info("Fetched ", len(result.Runners), " runners and ", len(result.QueuedJobs), " queued jobs")
+	return result
+}
+
+// Fetch all workflows of the tracked repositories and mark the ones that haven't run recently as stale,
+// repositories that fail are skipped.
+func (s *Server) fetchWorkflowsIgnoringErrors(ctx context.Context, uts time.Time) *workflowState {
+	result := &workflowState{Workflows: make([]*github.Workflow, 0), Uts: uts}
+
+	if !s.opts.WorkflowReport {
+		return result
+	}
+
+	ctx, cancel := s.pollContext(ctx)
+	defer cancel()
+
+	for _, filter := range s.trackedFilters() {
+		// the report covers every workflow of the repository, not only the tracked ones
+		repoFilter := &github.WorkflowFilter{Owner: filter.Owner, Repo: filter.Repo}
+
+		workflows, err := s.client.FetchWorkflows(ctx, repoFilter)
+		if err != nil {
+			log.Warn("Failed fetching workflows of repo: ", filter.GetRepoId(), ", err: ", err)
+			continue
+		}
+		result.Workflows = append(result.Workflows, workflows...)
+	}
+
+	github.MarkStaleWorkflows(result.Workflows, uts, s.opts.StaleAfter)
+	log.Info("Fetched ", len(result.Workflows), " workflows")
+	return result
+}
+
+// Detect flaky workflows and jobs from the runs in the state, the jobs of failed and re-run runs are fetched
+// once per attempt and runs whose jobs can't be fetched are judged by their run history only.
+func (s *Server) detectFlakinessIgnoringErrors(ctx context.Context, uts time.Time) *flakyState {
+	result := &flakyState{Reports: make([]*stats.FlakinessReport, 0), Uts: uts}
+
+	if !s.opts.FlakyReport {
+		return result
+	}
+
+	allRuns, jobs := s.fetchJobsIgnoringErrors(ctx, stats.NeedsJobs)
+	result.Reports = stats.DetectFlakiness(allRuns, jobs)
+	log.Info("Detected ", len(result.Reports), " flaky workflows and jobs")
+	return result
+}
+
+// Detect duration regressions of workflows and jobs from the runs in the state, the jobs of successful runs are fetched
+// once per attempt and runs whose jobs can't be fetched are left out of the job regressions.
+func (s *Server) detectRegressionsIgnoringErrors(ctx context.Context, uts time.Time) *regressionState {
+	result := &regressionState{Regressions: make([]*stats.DurationRegression, 0), Uts: uts}
+
+	if !s.opts.RegressionReport {
+		return result
+	}
+
+	allRuns, jobs := s.fetchJobsIgnoringErrors(ctx, stats.IsSuccess)
+	result.Regressions = stats.DetectDurationRegressions(allRuns, jobs, s.opts.Regression)
+	log.Info("Detected ", len(result.Regressions), " workflows and jobs with duration regressions or anomalies")
+	return result
+}
+
+// All runs in the state and the jobs of the runs selected by needsJobs by run ID, the jobs are fetched once per attempt
+func (s *Server) fetchJobsIgnoringErrors(ctx context.Context, needsJobs func(run *github.WorkflowRun) bool) ([]*github.WorkflowRun, map[int][]*github.RunJob) {
+	ctx, cancel := s.pollContext(ctx)
+	defer cancel()
+	state := s.getState()
+	allRuns := make([]*github.WorkflowRun, 0)
+	jobs := map[int][]*github.RunJob{}
+
+	for _, repoState := range state.queryRepos(&RunQuery{}) {
+		filter := &github.WorkflowFilter{Owner: repoState.repo.owner, Repo: repoState.repo.name}
+		allRuns = append(allRuns, repoState.runs...)
+
+		for _, run := range repoState.runs {
+			if !needsJobs(run) {
+				continue
+			}
+
+			if cached, ok := s.jobs[run.JobRunID]; ok && cached.attempt == run.JobRunAttempt {
+				jobs[run.JobRunID] = cached.jobs
+				continue
+			}
+
+			runJobs, err := s.client.FetchRunJobs(ctx, filter, run.JobRunID)
+			if err != nil {
+				log.Warn("Failed fetching jobs of run ", run.JobRunID, " of repo: ", filter.GetRepoId(), ", err: ", err)
+				continue
+			}
+			jobs[run.JobRunID] = runJobs
+			s.jobs[run.JobRunID] = &cachedJobs{attempt: run.JobRunAttempt, jobs: runJobs}
+		}
+	}
+
+	return allRuns, jobs
+}
+
+// Drop the cached jobs of runs that are no longer tracked
+func (s *Server) pruneJobs() {
+	state := s.getState()
+	tracked := map[int]bool{}
+	for _, repoState := range state.queryRepos(&RunQuery{}) {
+		for _, run := range repoState.runs {
+			tracked[run.JobRunID] = true
+		}
+	}
+
+	for runId := range s.jobs {
+		if !tracked[runId] {
+			delete(s.jobs, runId)
+		}
+	}
+}
+
+// Rediscover the repositories of all discovery owners once the discovery interval has elapsed,
+// the previously discovered repositories of an owner are kept if discovery fails.
+func (s *Server) discoverRepositoriesIfDue(ctx context.Context, now time.Time) {
+	if len(s.opts.Discovery) == 0 {
+		return
+	}
+
+	if !s.lastDiscovery.IsZero() && now.Sub(s.lastDiscovery) < s.opts.DiscoveryInterval {
+		return
+	}
+	s.lastDiscovery = now
+
+	for _, discoveryFilter := range s.opts.Discovery {
+		execTs := time.Now()
+		log.Info("Discovering repositories of owner: ", discoveryFilter.Owner)
+
+		discoverCtx, cancel := s.pollContext(ctx)
+		filters, err := s.client.DiscoverRepositories(discoverCtx, discoveryFilter)
+		cancel()
+		if err != nil {
+			log.Warn("Failed discovering repositories of owner: ", discoveryFilter.Owner, ", previously discovered repositories will be kept, err: ", err)
+			continue
+		}
+
+		log.Info("Successfully discovered ", len(filters), " repositories of owner: ", discoveryFilter.Owner, " in ", time.Since(execTs).Round(time.Second))
+		s.discovered[discoveryFilter.Owner] = filters
+	}
+}
+
+// All explicitly configured filters followed by the discovered ones, explicit filters take precedence
+func (s *Server) trackedFilters() []*github.WorkflowFilter {
+	result := make([]*github.WorkflowFilter, 0)
+	seen := map[string]bool{}
+
+	for _, filter := range s.getFilters() {
+		seen[filter.GetRepoId().String()] = true
+		result = append(result, filter)
+	}
+
+	for _, discoveryFilter := range s.opts.Discovery {
+		for _, filter := range s.discovered[discoveryFilter.Owner] {
+			if seen[filter.GetRepoId().String()] {
+				continue
+			}
+			seen[filter.GetRepoId().String()] = true
+			result = append(result, filter)
+		}
+	}
+
+	return result
+}
+
+func (s *Server) fetchState(ctx context.Context, filter *github.WorkflowFilter, timestamp time.Time) (*repoState, error) {
+	var runs []*github.WorkflowRun
+	var err error
+
+	if s.opts.Store != nil {
+		runs, err = s.syncRuns(ctx, filter, timestamp)
+	} else if s.opts.LatestOnly {
+		runs, err = s.client.FetchLatestWorkflowRuns(ctx, filter)
+	} else {
+		runs, err = s.client.FetchWorkflowRuns(ctx, filter)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// synced runs got their params before being stored
+	if s.opts.Store == nil {
+		s.enrichWithParams(ctx, filter, runs)
+	}
+
+	var definitions []*github.WorkflowDefinition = nil
+	if s.opts.ParseWorkflowDefinitions {
+		definitions, err = s.client.FetchWorkflowDefinitions(ctx, filter)
+		if err != nil {
+			log.Warn("failed fetching workflow definitions for ", fmt.Sprintf("%s/%s", filter.Owner, filter.Repo), ", they will be omitted, err: ", err)
+		}
+	}
+
+	return &repoState{
+		repo:        RepoId{owner: filter.Owner, name: filter.Repo},
+		runs:        runs,
+		definitions: definitions,
+		uts:         timestamp,
+	}, nil
+}
+
+func (s *Server) enrichWithParams(ctx context.Context, filter *github.WorkflowFilter, runs []*github.WorkflowRun) {
+	if !s.opts.ParseWorkflowParams {
+		return
+	}
+
+	for _, run := range runs {
+		params, err := s.client.FetchWorkflowRunParams(ctx, filter, run.JobRunID)
+		if err != nil {
+			log.Warn("failed fetching workflow params for ", fmt.Sprintf("%s/%s", filter.Owner, filter.Repo), " workflow: ", run.WorkflowName, " runId: ", run.JobRunID, ", it will be omitted, err: ", err)
+			continue
+		}
+		run.WorkflowParams = params
+	}
+}
+
+// Fetch the runs created since the latest stored run of each workflow, store them and return all stored runs of the
+// workflows of the filter. Runs outside of the retention are omitted, they're deleted from the store with the reports.
+func (s *Server) syncRuns(ctx context.Context, filter *github.WorkflowFilter, timestamp time.Time) ([]*github.WorkflowRun, error) {
+	stored, _, err := s.opts.Store.LoadRuns(filter.GetRepoId())
+	if err != nil {
+		return nil, err
+	}
+	stored = filterTrackedRuns(filter, stored)
+
+	incremental := *filter
+	incremental.CreatedSince = store.CreatedSince(stored, timestamp.Add(-rerunWindow))
+	fetched, err := s.client.FetchWorkflowRuns(ctx, &incremental)
+	if err != nil {
+		return nil, err
+	}
+	s.enrichWithParams(ctx, filter, fetched)
+
+	if err := s.opts.Store.SaveRuns(filter.GetRepoId(), fetched, timestamp); err != nil {
+		return nil, err
+	}
+
+	runs := store.MergeRuns(stored, fetched)
+	if s.opts.Retention > 0 {
+		runs = filterRetainedRuns(runs, timestamp.Add(-s.opts.Retention))
+	}
+
+	log.Info("Synced ", len(fetched), " new or updated runs of repo: ", filter.GetRepoId(), ", ", len(runs), " runs are stored")
+	return s.selectRuns(filter, runs), nil
+}
+
+// Deletes the stored runs outside of the retention, nothing is deleted without a store or retention
+func (s *Server) pruneStoredRuns(now time.Time) {
+	if s.opts.Store == nil || s.opts.Retention <= 0 {
+		return
+	}
+
+	deleted, err := s.opts.Store.Prune(now.Add(-s.opts.Retention))
+	if err != nil {
+		log.Warn("Failed pruning stored runs, err: ", err)
+		return
+	}
+	log.Info("Deleted ", deleted, " stored runs older than ", s.opts.Retention)
+}
+
+// Load the stored runs of every stored repository, runs of workflows that aren't tracked by a configured filter are
+// skipped. Repositories that fail are skipped.
+func (s *Server) loadStoredStatesIgnoringErrors() []*repoState {
+	s.pruneStoredRuns(time.Now())
+
+	repos, err := s.opts.Store.Repositories()
+	if err != nil {
+		log.Warn("Failed loading stored repositories, err: ", err)
+		return []*repoState{}
+	}
+
+	result := make([]*repoState, 0)
+	for _, repo := range repos {
+		runs, syncTime, err := s.opts.Store.LoadRuns(repo)
+		if err != nil {
+			log.Warn("Failed loading stored runs of repo: ", repo, ", err: ", err)
+			continue
+		}
+
+		filter := &github.WorkflowFilter{Owner: repo.Owner, Repo: repo.Name}
+		for _, f := range s.getFilters() {
+			if f.Owner == repo.Owner && f.Repo == repo.Name {
+				filter = f
+			}
+		}
+		runs = s.selectRuns(filter, filterTrackedRuns(filter, runs))
+
+		log.Info("Loaded ", len(runs), " stored runs of repo: ", repo)
+		result = append(result, &repoState{repo: RepoId{owner: repo.Owner, name: repo.Name}, runs: runs, uts: syncTime})
+	}
+	return result
+}
+
+// The latest runs if only the latest runs are tracked, otherwise all runs
+func (s *Server) selectRuns(filter *github.WorkflowFilter, runs []*github.WorkflowRun) []*github.WorkflowRun {
+	if s.opts.LatestOnly {
+		return github.LatestWorkflowRuns(runs, filter.Latest)
+	}
+	return runs
+}
+
+func filterTrackedRuns(filter *github.WorkflowFilter, runs []*github.WorkflowRun) []*github.WorkflowRun {
+	result := make([]*github.WorkflowRun, 0)
+	for _, run := range runs {
+		if filter.TracksWorkflow(run.WorkflowName) {
+			result = append(result, run)
+		}
+	}
+	return result
+}
+
+func filterRetainedRuns(runs []*github.WorkflowRun, since time.Time) []*github.WorkflowRun {
+	result := make([]*github.WorkflowRun, 0)
+	for _, run := range runs {
+		if !run.JobRunTime.Before(since) {
+			result = append(result, run)
+		}
+	}
+	return result
+}
+
+func filterNames(runs []*github.WorkflowRun) []string {
+	namesMap := map[string]bool{}
+	for _, run := range runs {
+		namesMap[run.WorkflowName] = true
+	}
+
+	names := make([]string, 0)
+	for k, _ := range namesMap {
+		names = append(names, k)
+	}
+
+	return names
+}
+
+// A snapshot of the state of all repositories sorted by repository
+func (s *Server) newSnapshot(state *stateRepository, now time.Time) *snapshot.Snapshot {
+	repoStates := state.queryRepos(&RunQuery{})
+
+	result := snapshot.New(now)
+	for _, repoState := range repoStates {
+		result.Repositories = append(result.Repositories, &snapshot.Repository{
+			Owner:       repoState.repo.owner,
+			Name:        repoState.repo.name,
+			Runs:        repoState.runs,
+			Definitions: repoState.definitions,
+			Uts:         repoState.uts,
+		})
+	}
+	return result
+}
+
+// Replaces the state of the repositories of the snapshot, tracked repositories are replaced again once they are polled
+// while the others are kept until the server stops
+func (s *Server) restoreSnapshot(restored *snapshot.Snapshot) {
+	repoStates := make([]*repoState, 0, len(restored.Repositories))
+	for _, repo := range restored.Repositories {
+		repoStates = append(repoStates, &repoState{
+			repo:        RepoId{owner: repo.Owner, name: repo.Name},
+			runs:        repo.Runs,
+			definitions: repo.Definitions,
+			uts:         repo.Uts,
+			imported:    true,
+		})
+	}
+
+	log.Info("Restoring ", len(repoStates), " repositories from the snapshot created at ", restored.CreatedAt)
+	s.updateState(repoStates)
+}
+
+func (s *Server) getState() *stateRepository {
+	s.lockState()
+	defer s.unlockState()
+	return s.state
+}
+
+// Swaps in the next version of the state with the new repository states, the other repositories are kept
+func (s *Server) updateState(newState []*repoState) {
+	s.swapState(newState, func(*repoState) bool {
+		return true
+	})
+}
+
+// Swaps in the next version of the state with the repository states of a poll, tracked repositories that failed keep
+// their previous state while repositories that are no longer tracked are dropped
+func (s *Server) updatePolledState(newState []*repoState) {
+	tracked := map[RepoId]bool{}
+	for _, filter := range s.trackedFilters() {
+		tracked[RepoId{owner: filter.Owner, name: filter.Repo}] = true
+	}
+
+	s.swapState(newState, func(previous *repoState) bool {
+		return tracked[previous.repo] || previous.imported
+	})
+}
+
+func (s *Server) swapState(newState []*repoState, retain func(*repoState) bool) {
+	s.lockState()
+	defer s.unlockState()
+	s.state = s.state.next(time.Now(), newState, retain)
+	s.events.publish(s.state)
+}
+
+func (s *Server) getRunners() *runnerState {
+	s.lockState()
+	defer s.unlockState()
+
+	if s.runners == nil {
+		return &runnerState{Runners: []*github.Runner{}, QueuedJobs: []*github.QueuedJob{}}
+	}
+	return s.runners
+}
+
+func (s *Server) updateRunners(runners *runnerState) {
+	s.lockState()
+	defer s.unlockState()
+	s.runners = runners
+}
+
+func (s *Server) getWorkflows() *workflowState {
+	s.lockState()
+	defer s.unlockState()
+
+	if s.workflows == nil {
+		return &workflowState{Workflows: []*github.Workflow{}}
+	}
+	return s.workflows
+}
+
+func (s *Server) updateWorkflows(workflows *workflowState) {
+	s.lockState()
+	defer s.unlockState()
+	s.workflows = workflows
+}
+
+func (s *Server) getFlaky() *flakyState {
+	s.lockState()
+	defer s.unlockState()
+
+	if s.flaky == nil {
+		return &flakyState{Reports: []*stats.FlakinessReport{}}
+	}
+	return s.flaky
+}
+
+func (s *Server) updateFlaky(flaky *flakyState) {
+	s.lockState()
+	defer s.unlockState()
+	s.flaky = flaky
+}
+
+func (s *Server) getRegressions() *regressionState {
+	s.lockState()
+	defer s.unlockState()
+
+	if s.regressions == nil {
+		return &regressionState{Regressions: []*stats.DurationRegression{}}
+	}
+	return s.regressions
+}
+
+func (s *Server) updateRegressions(regressions *regressionState) {
+	s.lockState()
+	defer s.unlockState()
+	s.regressions = regressions
+}
+
+func (s *Server) getFilters() []*github.WorkflowFilter {
+	s.lockState()
+	defer s.unlockState()
+	return s.filters
+}
+
+func (s *Server) getPollIntervals() map[RepoId]time.Duration {
+	s.lockState()
+	defer s.unlockState()
+	return s.pollIntervals
+}
+
+func (s *Server) updateFilters(filters []*github.WorkflowFilter, pollIntervals map[RepoId]time.Duration) {
+	s.lockState()
+	defer s.unlockState()
+	s.filters = filters
+	s.pollIntervals = pollIntervals
+}
+
+func (s *Server) getPollStatus() *pollStatus {
+	s.lockState()
+	defer s.unlockState()
+
+	if s.polls == nil {
+		return &pollStatus{repos: map[RepoId]*repoPollStatus{}}
+	}
+	return s.polls
+}
+
+func (s *Server) recordPoll(duration time.Duration, results []*repoPollResult) {
+	tracked := map[RepoId]bool{}
+	for _, filter := range s.trackedFilters() {
+		tracked[RepoId{owner: filter.Owner, name: filter.Repo}] = true
+	}
+	polls := s.getPollStatus().next(time.Now(), duration, results, tracked)
+
+	s.lockState()
+	defer s.unlockState()
+	s.polls = polls
+}
+
+func (s *Server) lockState() {
+	s.stateMutex.Lock()
+}
+
+func (s *Server) unlockState() {
+	s.stateMutex.Unlock()
+}
+
+type dashboardHTMLViewModel struct {
+	Repositories []template.HTML
+	// URL of the server-sent events updating the repositories in place, empty for pages without live updates
+	Events         string
+	RefreshSeconds int
+}
+
+type heatmapHTMLViewModel struct {
+	Timezone string
+	Runs     int
+	Metrics  []*heatmapLinkViewModel
+	Body     template.HTML
+}
+
+type heatmapLinkViewModel struct {
+	Metric   string
+	URL      string
+	Selected bool
+}
+
+type doraHTMLViewModel struct {
+	Deployments []string
+	Body        template.HTML
+}
+
+// View model of the pages showing a single report, e.g. the runners
+type reportHTMLViewModel struct {
+	LastUpdateTime string
+	Body           template.HTML
+}
+
+type adminHTMLViewModel struct {
+	Repositories []*config.Repository
+	CSRFToken    string
+}
+
+type workflowsHTMLViewModel struct {
+	LastUpdateTime string
+	StaleDays      int
+	Body           template.HTML
+}
+
+type repsotioryHTMLViewModel struct {
+	Owner          string
+	Repository     string
+	SectionID      string
+	LastUpdate     string
+	LastUpdateTime string
+	Body           template.HTML
+	Streaks        template.HTML
+	Regressions    template.HTML
+	Definitions    template.HTML
+	// Why the runs may be outdated and the error of the latest failed fetch, empty if the repository was fetched recently
+	PollProblem string
+	PollError   string
+}
+
+const dashboardHTMLTemplate = `
+<html>
+	<head>
 		<meta charset="utf-8">
 		<meta name="viewport" content="width=device-width, initial-scale=1, minimal-ui">
 		<title>Github Workflow Scraper</title>
@@ -442,28 +1767,191 @@ const dashboardHTMLTemplate = `
 					background-color: #0d1117;
 				}
 			}
+
+			.poll-problem {
+				padding: 2px 8px;
+				border-radius: 12px;
+				font-size: 60%;
+				vertical-align: middle;
+				color: #ffffff;
+				background-color: #cf222e;
+			}
 		</style>
 		<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/github-fork-ribbon-css/0.2.3/gh-fork-ribbon.min.css">
 	</head>
 
 	<body>
 		<article class="markdown-body">
-			<h2><a href="/">Home</a></h2>
+			<h2><a href="{{path "/"}}">Home</a> | <a href="{{path "/runners"}}">Runners</a> | <a href="{{path "/workflows"}}">Workflows</a> | <a href="{{path "/flaky"}}">Flaky</a> | <a href="{{path "/regressions"}}">Regressions</a> | <a href="{{path "/heatmap"}}">Heatmap</a> | <a href="{{path "/dora"}}">Delivery</a></h2>
 			<br/>
+			<div id="repositories">
 			{{ range $repository := .Repositories }}
 				{{ $repository }}
 				<br/>
 			{{ end }}
+			</div>
 		</article>
+		{{if .Events}}
+		<noscript><meta http-equiv="refresh" content="{{.RefreshSeconds}}"></noscript>
+		<script>
+			(function() {
+				// same format as the durations rendered by the server, e.g. 1h2m3s
+				function formatAgo(uts) {
+					var seconds = Math.max(0, Math.round((Date.now() - uts.getTime()) / 1000));
+					var hours = Math.floor(seconds / 3600), minutes = Math.floor(seconds % 3600 / 60);
+					var result = hours > 0 ? hours + "h" + minutes + "m" : minutes > 0 ? minutes + "m" : "";
+					return result + seconds % 60 + "s ago";
+				}
+
+				setInterval(function() {
+					document.querySelectorAll(".last-update[data-uts]").forEach(function(element) {
+						element.textContent = formatAgo(new Date(element.dataset.uts));
+					});
+				}, 1000);
+
+				function reloadPeriodically() {
+					setTimeout(function() { location.reload(); }, {{.RefreshSeconds}} * 1000);
+				}
+
+				if (!window.EventSource) {
+					reloadPeriodically();
+					return;
+				}
+
+				var source = new EventSource({{.Events}});
+				source.addEventListener("repository", function(e) {
+					var update = JSON.parse(e.data);
+					var section = document.getElementById(update.id);
+					if (section) {
+						section.outerHTML = update.html;
+					} else {
+						document.getElementById("repositories").insertAdjacentHTML("beforeend", update.html + "<br/>");
+					}
+				});
+				source.addEventListener("removed", function(e) {
+					var section = document.getElementById(JSON.parse(e.data).id);
+					if (section) {
+						section.remove();
+					}
+				});
+				// the browser reconnects on errors unless the server rejected the stream
+				source.onerror = function() {
+					if (source.readyState === EventSource.CLOSED) {
+						reloadPeriodically();
+					}
+				};
+			})();
+		</script>
+		{{end}}
 	</body>
 	
 </html>
 `
 
 const repositoryHTMLTemplate = `
+<div id="{{.SectionID}}">
 <section>
-	<h2><a href="/{{.Owner}}">{{.Owner}}</a>/<a href="/{{.Owner}}/{{.Repository}}">{{.Repository}}</a></h2>
-	<h4>Last update: {{.LastUpdateTime}}</h4>	
+	<h2><a href="{{path "/"}}{{.Owner}}">{{.Owner}}</a>/<a href="{{path "/"}}{{.Owner}}/{{.Repository}}">{{.Repository}}</a>{{if .PollProblem}} <span class="poll-problem" title="{{.PollError}}">stale</span>{{end}}</h2>
+	{{if .PollProblem}}<blockquote><b>Outdated:</b> {{.PollProblem}}{{if .PollError}}, last error: <code>{{.PollError}}</code>{{end}}</blockquote>{{end}}
+	<h4>Last update: <span class="last-update" data-uts="{{.LastUpdate}}">{{.LastUpdateTime}}</span></h4>	
+	{{.Streaks}}
+	{{.Regressions}}
 	{{.Body}}
+	{{if .Definitions}}
+		<h4>Workflow triggers</h4>
+		{{.Definitions}}
+	{{end}}
+</section>
+</div>
+`
+
+const runnersHTMLTemplate = `
+<section>
+	<h2><a href="{{path "/runners"}}">Self-hosted runners</a></h2>
+	<h4>Last update: {{.LastUpdateTime}}</h4>
+	{{.Body}}
+</section>
+`
+
+const workflowsHTMLTemplate = `
+<section>
+	<h2><a href="{{path "/workflows"}}">Workflows</a></h2>
+	<h4>Last update: {{.LastUpdateTime}}</h4>
+	<p>Workflows without a run in the last {{.StaleDays}} days are marked as stale.</p>
+	{{.Body}}
+</section>
+`
+
+const flakyHTMLTemplate = `
+<section>
+	<h2><a href="{{path "/flaky"}}">Flaky workflows and jobs</a></h2>
+	<h4>Last update: {{.LastUpdateTime}}</h4>
+	{{.Body}}
+</section>
+`
+
+const doraHTMLTemplate = `
+<section>
+	<h2><a href="{{path "/dora"}}">Delivery metrics</a></h2>
+	<h4>Deployments: {{range $i, $d := .Deployments}}{{if $i}}, {{end}}<code>{{$d}}</code>{{end}}</h4>
+	{{.Body}}
+</section>
+`
+
+const regressionsHTMLTemplate = `
+<section>
+	<h2><a href="{{path "/regressions"}}">Duration regressions</a></h2>
+	<h4>Last update: {{.LastUpdateTime}}</h4>
+	{{.Body}}
+</section>
+`
+
+const heatmapHTMLTemplate = `
+<section>
+	<h2><a href="{{path "/heatmap"}}">Run activity</a></h2>
+	<h4>{{.Runs}} runs by weekday and hour ({{.Timezone}}):
+		{{range $i, $m := .Metrics}}{{if $i}} | {{end}}{{if $m.Selected}}<b>{{$m.Metric}}</b>{{else}}<a href="{{$m.URL}}">{{$m.Metric}}</a>{{end}}{{end}}
+	</h4>
+	{{.Body}}
+</section>
+`
+
+const adminHTMLTemplate = `
 <section>
+	<h2><a href="{{path "/admin"}}">Tracked repositories</a></h2>
+	<p>Changes are picked up by the next poll. Repositories discovered by owner aren't listed.</p>
+	<table>
+		<thead>
+			<tr><th>Repository</th><th>Workflows</th><th>Poll interval</th><th></th></tr>
+		</thead>
+		<tbody>
+		{{range .Repositories}}
+			<tr>
+				<td><a href="{{path "/"}}{{.Owner}}/{{.Repo}}">{{.Owner}}/{{.Repo}}</a></td>
+				<td>{{range $i, $w := .Workflows}}{{if $i}}, {{end}}<code>{{$w}}</code>{{else}}all{{end}}</td>
+				<td>{{if .PollInterval}}{{.PollInterval}}{{else}}default{{end}}</td>
+				<td>
+					<form method="post" action="{{path "/admin"}}">
+						<input type="hidden" name="csrf" value="{{$.CSRFToken}}">
+						<input type="hidden" name="action" value="remove">
+						<input type="hidden" name="owner" value="{{.Owner}}">
+						<input type="hidden" name="repo" value="{{.Repo}}">
+						<button type="submit">Remove</button>
+					</form>
+				</td>
+			</tr>
+		{{end}}
+		</tbody>
+	</table>
+	<h4>Add or update a repository</h4>
+	<form method="post" action="{{path "/admin"}}">
+		<input type="hidden" name="csrf" value="{{.CSRFToken}}">
+		<input type="hidden" name="action" value="save">
+		<input type="text" name="owner" placeholder="owner" required>
+		<input type="text" name="repo" placeholder="repository" required>
+		<input type="text" name="workflows" placeholder="workflows, comma separated, all if empty" size="40">
+		<input type="text" name="pollInterval" placeholder="poll interval, e.g. 30s">
+		<button type="submit">Save</button>
+	</form>
+</section>
 `
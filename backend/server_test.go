@@ -0,0 +1,967 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/config"
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+	"github.com/newestuser/github-workflow-dashboard/snapshot"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/newestuser/github-workflow-dashboard/store"
+)
+
+func newTestServer(t *testing.T, opts *Options) (*Server, *githubtest.Server) {
+	fake := githubtest.NewServer()
+	t.Cleanup(fake.Close)
+
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 2, Name: "release"})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 10, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: time.Now().Add(-time.Hour)})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 11, WorkflowID: 1, Status: "completed", Conclusion: "failure", CreatedAt: time.Now()})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 20, WorkflowID: 2, Status: "in_progress", CreatedAt: time.Now()})
+
+	if opts.Filters == nil {
+		opts.Filters = []*github.WorkflowFilter{{Owner: "foo", Repo: "bar"}}
+	}
+
+	return NewServer(fake.Client(), opts), fake
+}
+
+func TestServeWorkflowJson(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	s.updateState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/foo", 3},
+		{"/api/foo/bar", 3},
+		{"/api/foo/bar/build", 2},
+		{"/api/foo/baz", 0},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d", test.path, rec.Code)
+		}
+
+		result := decodeRuns(t, rec)
+		if len(result.Runs) != test.want || result.Total != test.want {
+			t.Errorf("%s: got %d of %d runs, wanted %d", test.path, len(result.Runs), result.Total, test.want)
+		}
+	}
+}
+
+type testRunsResponse struct {
+	Version    uint64                `json:"version"`
+	Total      int                   `json:"total"`
+	NextCursor string                `json:"nextCursor"`
+	Runs       []*github.WorkflowRun `json:"runs"`
+}
+
+func decodeRuns(t *testing.T, rec *httptest.ResponseRecorder) *testRunsResponse {
+	result := &testRunsResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	return result
+}
+
+func TestServeWorkflowJsonQuery(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	s.updateState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	tests := []struct {
+		path string
+		want []int
+	}{
+		{"/api/foo/bar?conclusion=failure", []int{11}},
+		{"/api/foo/bar?status=in_progress", []int{20}},
+		{"/api/runs?workflow=build&sort=created", []int{10, 11}},
+		{"/api/foo/bar?sort=-created&limit=2", []int{20, 11}},
+		{"/api/foo/bar/build?since=" + time.Now().Add(-30*time.Minute).UTC().Format(time.RFC3339), []int{11}},
+	}
+	for _, test := range tests {
+		rec := get(test.path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d", test.path, rec.Code)
+		}
+
+		runs := decodeRuns(t, rec).Runs
+		ids := make([]int, 0)
+		for _, run := range runs {
+			ids = append(ids, run.JobRunID)
+		}
+		if len(ids) != len(test.want) || (len(ids) > 0 && ids[0] != test.want[0]) {
+			t.Errorf("%s: got runs %v, wanted %v", test.path, ids, test.want)
+		}
+	}
+
+	// page through all runs with the cursor
+	ids := make([]int, 0)
+	path := "/api/foo?sort=created&limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatalf("got more than 2 pages")
+		}
+		result := decodeRuns(t, get(path))
+		for _, run := range result.Runs {
+			ids = append(ids, run.JobRunID)
+		}
+
+		path = ""
+		if result.NextCursor != "" {
+			path = "/api/foo?sort=created&limit=2&cursor=" + result.NextCursor
+		}
+	}
+	if len(ids) != 3 || ids[0] != 10 {
+		t.Errorf("got runs %v through the cursor, wanted all 3 runs from the oldest", ids)
+	}
+
+	rec := get("/api/foo/bar/build?fields=jobRunId,jobConclusion")
+	var selected struct {
+		Runs []map[string]interface{} `json:"runs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&selected); err != nil || len(selected.Runs) != 2 || len(selected.Runs[0]) != 2 {
+		t.Errorf("got %v, err: %v, wanted 2 runs with 2 fields", selected.Runs, err)
+	}
+
+	for _, query := range []string{"sort=foo", "limit=-1", "since=yesterday", "fields=foo", "cursor=foo", "cursor=" + encodeCursor(404)} {
+		if rec := get("/api/foo/bar?" + query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, wanted %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestFetchLatestOnlyIgnoresFailingRepos(t *testing.T) {
+	s, _ := newTestServer(t, &Options{
+		LatestOnly: true,
+		Filters: []*github.WorkflowFilter{
+			{Owner: "foo", Repo: "bar"},
+			{Owner: "foo", Repo: "missing"},
+		},
+	})
+
+	states := s.fetchAllStatesIgnoringErrors(context.Background(), time.Now())
+	if len(states) != 1 {
+		t.Fatalf("got %d repo states, wanted 1", len(states))
+	}
+
+	if len(states[0].runs) != 2 {
+		t.Errorf("got %d latest runs, wanted 2", len(states[0].runs))
+	}
+}
+
+func TestServeStatsJson(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	s.updateState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	rec := httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats/foo/bar", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+
+	var result []*stats.WorkflowStats
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("got stats of %d workflows, wanted 2", len(result))
+	}
+
+	if build := result[0]; build.WorkflowName != "build" || build.Runs != 2 || build.SuccessRate != 0.5 {
+		t.Errorf("got %s with %d runs and success rate %f, wanted build with 2 runs and success rate 0.5", build.WorkflowName, build.Runs, build.SuccessRate)
+	}
+
+	rec = httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats/foo/bar?window-days=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid window, wanted %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServeHeatmapJson(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	s.updateState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	rec := httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/heatmap?timezone=Europe/Berlin", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+
+	var result stats.Heatmap
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if result.Timezone != "Europe/Berlin" || len(result.Cells) != 7*24 || result.Runs == 0 {
+		t.Errorf("got heatmap of %d runs with %d cells in %s, wanted runs in 168 cells in Europe/Berlin", result.Runs, len(result.Cells), result.Timezone)
+	}
+
+	for _, query := range []string{"timezone=Mars/Olympus", "since-days=-1"} {
+		rec = httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/heatmap?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("got status %d for %s, wanted %d", rec.Code, query, http.StatusBadRequest)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/heatmap?metric=foo", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for an unknown metric, wanted %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSyncRunsWithStore(t *testing.T) {
+	runStore, err := store.Open(filepath.Join(t.TempDir(), "runs.db"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	defer runStore.Close()
+
+	s, fake := newTestServer(t, &Options{Store: runStore})
+	if states := s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()); len(states) != 1 || len(states[0].runs) != 3 {
+		t.Fatalf("got %d repo states, wanted 1 with 3 runs", len(states))
+	}
+
+	// a run created before the latest stored one isn't fetched again, a newer one is
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 9, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: time.Now().Add(-2 * time.Hour)})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 12, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: time.Now().Add(time.Minute)})
+
+	states := s.fetchAllStatesIgnoringErrors(context.Background(), time.Now())
+	if len(states) != 1 || len(states[0].runs) != 4 {
+		t.Fatalf("got %d repo states, wanted 1 with 4 runs", len(states))
+	}
+
+	restarted, _ := newTestServer(t, &Options{Store: runStore, LatestOnly: true})
+	loaded := restarted.loadStoredStatesIgnoringErrors()
+	if len(loaded) != 1 || len(loaded[0].runs) != 2 {
+		t.Fatalf("got %d loaded repo states, wanted 1 with 2 runs", len(loaded))
+	}
+	for _, run := range loaded[0].runs {
+		if run.JobRunID != 12 && run.JobRunID != 20 {
+			t.Errorf("got loaded run %d, wanted the latest runs 12 and 20", run.JobRunID)
+		}
+	}
+}
+
+func TestSyncRerunsWithStore(t *testing.T) {
+	runStore, err := store.Open(filepath.Join(t.TempDir(), "runs.db"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	defer runStore.Close()
+
+	s, fake := newTestServer(t, &Options{Store: runStore})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 12, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: time.Now().Add(time.Minute)})
+	s.fetchAllStatesIgnoringErrors(context.Background(), time.Now())
+
+	// the re-run of the failed run 11 keeps its creation time although a newer run is stored
+	created := time.Now()
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 11, WorkflowID: 1, RunAttempt: 2, Status: "completed", Conclusion: "success", CreatedAt: created, UpdatedAt: created.Add(time.Hour)})
+
+	states := s.fetchAllStatesIgnoringErrors(context.Background(), time.Now())
+	if len(states) != 1 {
+		t.Fatalf("got %d repo states, wanted 1", len(states))
+	}
+	for _, run := range states[0].runs {
+		if run.JobRunID == 11 && (run.JobRunAttempt != 2 || run.JobConclusion != stats.ConclusionSuccess) {
+			t.Errorf("got attempt %d with conclusion %s, wanted the passed re-run", run.JobRunAttempt, run.JobConclusion)
+		}
+	}
+
+	reports := stats.DetectFlakiness(states[0].runs, nil)
+	if len(reports) != 1 || reports[0].Evidence[0].Reason != stats.ReasonRerunPassed {
+		t.Errorf("got %d flakiness reports, wanted build passing on re-run", len(reports))
+	}
+}
+
+func TestPruneStoreWhilePolling(t *testing.T) {
+	runStore, err := store.Open(filepath.Join(t.TempDir(), "runs.db"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	defer runStore.Close()
+
+	s, _ := newTestServer(t, &Options{Store: runStore, Retention: 24 * time.Hour, PollInterval: 10 * time.Millisecond})
+	repo := &github.RepoId{Owner: "foo", Name: "bar"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		_ = s.Poll(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-polled
+	}()
+
+	waitFor := func(condition func([]*github.WorkflowRun, time.Time) bool) {
+		t.Helper()
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			runs, syncTime, err := runStore.LoadRuns(repo)
+			if err != nil {
+				t.Fatalf("got error: %s", err)
+			}
+			if condition(runs, syncTime) {
+				return
+			}
+		}
+		t.Fatalf("timed out waiting for the store")
+	}
+
+	// a run that got older than the retention after the start
+	waitFor(func(_ []*github.WorkflowRun, syncTime time.Time) bool { return !syncTime.IsZero() })
+	expired := &github.WorkflowRun{WorkflowName: "build", JobRunID: 1, JobStatus: github.StatusCompleted, JobRunTime: time.Now().Add(-48 * time.Hour)}
+	if err := runStore.SaveRuns(repo, []*github.WorkflowRun{expired}, time.Now()); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	waitFor(func(runs []*github.WorkflowRun, _ time.Time) bool {
+		for _, run := range runs {
+			if run.JobRunID == expired.JobRunID {
+				return false
+			}
+		}
+		return len(runs) == 3
+	})
+}
+
+func TestExportAndImportSnapshot(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	s.updateState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	rec := httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/snapshot", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+
+	exported, err := snapshot.Read(rec.Body)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(exported.Repositories) != 1 || len(exported.Runs()) != 3 {
+		t.Fatalf("got %d repositories with %d runs, wanted 1 with 3 runs", len(exported.Repositories), len(exported.Runs()))
+	}
+
+	body := &bytes.Buffer{}
+	if err := snapshot.Write(body, exported); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	imported, _ := newTestServer(t, &Options{})
+	rec = httptest.NewRecorder()
+	imported.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/snapshot", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d for the import", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	imported.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/foo/bar/build", nil))
+	if runs := decodeRuns(t, rec).Runs; len(runs) != 2 {
+		t.Errorf("got %d imported build runs, wanted 2", len(runs))
+	}
+
+	rec = httptest.NewRecorder()
+	imported.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/snapshot", bytes.NewBufferString("foo")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid snapshot, wanted %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPolledStateDropsUntrackedRepos(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	s.updateState([]*repoState{
+		{repo: RepoId{owner: "foo", name: "removed"}, runs: []*github.WorkflowRun{}},
+		{repo: RepoId{owner: "foo", name: "imported"}, runs: []*github.WorkflowRun{}, imported: true},
+	})
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	repos := s.getState().queryRepos(&RunQuery{})
+	if len(repos) != 2 || repos[0].repo.name != "bar" || repos[1].repo.name != "imported" {
+		t.Fatalf("got %d repos, wanted the tracked foo/bar and the imported foo/imported", len(repos))
+	}
+
+	rec := httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/foo", nil))
+	if version := rec.Header().Get("X-State-Version"); version != "2" || rec.Header().Get("X-State-Updated") == "" {
+		t.Errorf("got state version %s updated at %s, wanted version 2", version, rec.Header().Get("X-State-Updated"))
+	}
+}
+
+func TestServeWhilePolling(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	router := s.newRouter()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+		}
+	}()
+
+	for _, path := range []string{"/", "/api/foo/bar", "/api/foo/bar/build", "/api/heatmap", "/api/snapshot"} {
+		for i := 0; i < 10; i++ {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: got status %d", path, rec.Code)
+			}
+		}
+	}
+	<-done
+}
+
+func TestStreamRepositoryEvents(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	srv := httptest.NewServer(s.newRouter())
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/events?owner=foo", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewReader(resp.Body)
+	// wait for the stream to be subscribed
+	if line, err := events.ReadString('\n'); err != nil || !strings.HasPrefix(line, "retry:") {
+		t.Fatalf("got %q, err: %v, wanted the retry interval", line, err)
+	}
+
+	nextEvent := func() (string, *repositoryEvent) {
+		name := ""
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("got error: %s", err)
+			}
+			if strings.HasPrefix(line, "event: ") {
+				name = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+			}
+			if strings.HasPrefix(line, "data: ") {
+				event := &repositoryEvent{}
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), event); err != nil {
+					t.Fatalf("got error: %s", err)
+				}
+				return name, event
+			}
+		}
+	}
+
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+	name, event := nextEvent()
+	if name != "repository" || event.ID != "repository-foo/bar" || !strings.Contains(event.HTML, `id="repository-foo/bar"`) {
+		t.Errorf("got %s event %+v, wanted the section of foo/bar", name, event)
+	}
+
+	s.updateFilters([]*github.WorkflowFilter{}, nil)
+	s.updatePolledState([]*repoState{})
+	if name, event := nextEvent(); name != "removed" || event.ID != "repository-foo/bar" {
+		t.Errorf("got %s event %+v, wanted foo/bar to be removed", name, event)
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	s, fake := newTestServer(t, &Options{Filters: []*github.WorkflowFilter{
+		{Owner: "foo", Repo: "bar"},
+		{Owner: "foo", Repo: "missing"},
+	}})
+	started := time.Now().Add(-2 * time.Hour)
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 12, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: started, RunStartedAt: started, UpdatedAt: started.Add(90 * time.Second)})
+	fake.SetRateLimit(&githubtest.RateLimit{Limit: 5000, Remaining: 4321, Reset: time.Now().Add(time.Hour)})
+
+	s.opts.RateLimits = github.NewRateLimitTracker(nil)
+	s.client, _ = github.NewWorkflowClientWithBaseURL(&http.Client{Transport: s.opts.RateLimits}, fake.URL())
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	rec := httptest.NewRecorder()
+	s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE github_workflow_last_run_conclusion gauge\n",
+		`github_workflow_last_run_conclusion{owner="foo",repo="bar",workflow="build",branch="",conclusion="failure"} 1` + "\n",
+		`github_workflow_runs_in_progress{owner="foo",repo="bar",workflow="release",branch=""} 1` + "\n",
+		`github_workflow_run_duration_seconds_bucket{owner="foo",repo="bar",workflow="build",branch="",le="60"} 0` + "\n",
+		`github_workflow_run_duration_seconds_bucket{owner="foo",repo="bar",workflow="build",branch="",le="120"} 1` + "\n",
+		`github_workflow_run_duration_seconds_sum{owner="foo",repo="bar",workflow="build",branch=""} 90` + "\n",
+		`github_workflow_dashboard_poll_errors_total{owner="foo",repo="bar"} 0` + "\n",
+		`github_workflow_dashboard_poll_errors_total{owner="foo",repo="missing"} 1` + "\n",
+		"github_workflow_dashboard_rate_limit_remaining 4321\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("got metrics without %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestPollStatus(t *testing.T) {
+	s, fake := newTestServer(t, &Options{PollInterval: time.Minute})
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := serve("/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d before the first poll, wanted 503", rec.Code)
+	}
+	if rec := serve("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("got status %d, wanted 200", rec.Code)
+	}
+
+	fake.FailRequests("/repos/foo/bar/", http.StatusInternalServerError, 0)
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+	if rec := serve("/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d after a failed poll, wanted 503", rec.Code)
+	}
+
+	fake.ClearErrors()
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+	if rec := serve("/readyz"); rec.Code != http.StatusOK {
+		t.Errorf("got status %d after a successful poll, wanted 200", rec.Code)
+	}
+	if body := serve("/foo/bar").Body.String(); strings.Contains(body, `class="poll-problem"`) {
+		t.Errorf("got a poll problem after a successful poll")
+	}
+
+	fake.FailRequests("/repos/foo/bar/", http.StatusInternalServerError, 0)
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	status := &statusResponse{}
+	if err := json.NewDecoder(serve("/api/status").Body).Decode(status); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if !status.Ready || len(status.Repositories) != 1 {
+		t.Fatalf("got status %+v, wanted a ready server with one repository", status)
+	}
+	repo := status.Repositories[0]
+	if repo.ConsecutiveFailures != 2 || repo.Errors != 3 || repo.LastError == "" || repo.LastSuccess == nil || !repo.Stale {
+		t.Errorf("got repository status %+v, wanted a stale repository that failed twice in a row", repo)
+	}
+
+	body := serve("/foo/bar").Body.String()
+	if !strings.Contains(body, `class="poll-problem"`) || !strings.Contains(body, "fetching failed 2 times in a row") {
+		t.Errorf("got the dashboard without the poll problem:\n%s", body)
+	}
+	if !strings.Contains(serve("/metrics").Body.String(), `github_workflow_dashboard_poll_errors_total{owner="foo",repo="bar"} 3`) {
+		t.Errorf("got metrics without the failed polls")
+	}
+}
+
+func TestManageTrackedRepositories(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "repositories.json")
+	s, _ := newTestServer(t, &Options{AdminToken: "secret", ConfigFile: configFile})
+	serve := func(method, path, body string, auth func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth != nil {
+			auth(r)
+		}
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, r)
+		return rec
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }
+
+	if rec := serve(http.MethodGet, "/api/admin/repositories", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d without a token, wanted 401", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/api/admin/repositories/foo/baz", `{"workflows":["build"]}`, bearer); rec.Code != http.StatusCreated {
+		t.Errorf("got status %d adding a repository, wanted 201", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/api/admin/repositories/foo/baz", `{"workflows":["build","release"]}`, bearer); rec.Code != http.StatusOK {
+		t.Errorf("got status %d updating a repository, wanted 200", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/admin/repositories/foo/bar", "", bearer); rec.Code != http.StatusNoContent {
+		t.Errorf("got status %d removing a repository, wanted 204", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/admin/repositories/foo/bar", "", bearer); rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d removing an untracked repository, wanted 404", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/_/admin/repositories", "", bearer); rec.Code != http.StatusOK {
+		t.Errorf("got status %d listing the repositories under the reserved owner, wanted 200", rec.Code)
+	}
+
+	filters := s.trackedFilters()
+	if len(filters) != 1 || filters[0].Repo != "baz" || len(filters[0].WorkflowNames) != 2 {
+		t.Fatalf("got %d tracked filters, wanted foo/baz with two workflows", len(filters))
+	}
+
+	saved, err := config.Load(configFile)
+	if err != nil || saved == nil || len(saved.Repositories) != 1 || saved.Repositories[0].Repo != "baz" {
+		t.Fatalf("got config %+v and error %v, wanted foo/baz to be persisted", saved, err)
+	}
+
+	basic := func(r *http.Request) {
+		r.SetBasicAuth("admin", "secret")
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if rec := serve(http.MethodPost, "/admin", "action=save&owner=foo&repo=bar", basic); rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d submitting the form without a csrf token, wanted 403", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/admin", "action=save&owner=foo&repo=bar&workflows=build,+release&csrf="+s.csrfToken(), basic); rec.Code != http.StatusSeeOther {
+		t.Errorf("got status %d submitting the form, wanted 303", rec.Code)
+	}
+	if body := serve(http.MethodGet, "/admin", "", basic).Body.String(); !strings.Contains(body, "foo/bar") || !strings.Contains(body, "<code>release</code>") {
+		t.Errorf("got the admin page without foo/bar:\n%s", body)
+	}
+
+	restarted := NewServer(s.client, &Options{ConfigFile: configFile, Filters: []*github.WorkflowFilter{{Owner: "qux", Repo: "bar"}}})
+	if err := restarted.loadConfig(); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if filters := restarted.trackedFilters(); len(filters) != 2 || filters[0].GetRepoId().String() != "foo/bar" || filters[1].GetRepoId().String() != "foo/baz" {
+		t.Errorf("got %d filters after a restart, wanted the repositories of the config file", len(filters))
+	}
+
+	disabled, _ := newTestServer(t, &Options{})
+	rec := httptest.NewRecorder()
+	disabled.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d without an admin token, wanted 404", rec.Code)
+	}
+}
+
+func TestPollSchedule(t *testing.T) {
+	s, _ := newTestServer(t, &Options{
+		PollInterval:       time.Minute,
+		RepoPollIntervals:  map[string]time.Duration{"foo/baz": 5 * time.Minute},
+		AdaptivePolling:    true,
+		ActivePollInterval: 20 * time.Second,
+		MaxPollInterval:    4 * time.Minute,
+	})
+	repo := RepoId{owner: "foo", name: "bar"}
+	schedule := &repoSchedule{}
+	idle := &repoState{repo: repo, runs: []*github.WorkflowRun{{JobStatus: github.StatusCompleted, JobUpdateTime: time.Now()}}}
+	active := &repoState{repo: repo, runs: append(idle.runs, &github.WorkflowRun{JobStatus: "in_progress"})}
+
+	for i, test := range []struct {
+		state *repoState
+		want  time.Duration
+	}{
+		{idle, time.Minute},
+		{idle, 2 * time.Minute},
+		{idle, 4 * time.Minute},
+		{idle, 4 * time.Minute},
+		{active, 20 * time.Second},
+		{idle, time.Minute},
+		{nil, time.Minute},
+	} {
+		if got := s.nextPollInterval(schedule, repo, test.state); got != test.want {
+			t.Errorf("poll %d: got interval %s, wanted %s", i, got, test.want)
+		}
+	}
+	if got := s.nextPollInterval(&repoSchedule{}, RepoId{owner: "foo", name: "baz"}, nil); got != 5*time.Minute {
+		t.Errorf("got interval %s, wanted the interval of the repository", got)
+	}
+
+	now := time.Now()
+	if due, _ := s.dueFilters(now, time.Minute); len(due) != 0 {
+		t.Errorf("got %d due repositories, wanted none within the startup jitter", len(due))
+	}
+	if due, _ := s.dueFilters(now.Add(time.Minute), time.Minute); len(due) != 1 {
+		t.Errorf("got %d due repositories, wanted foo/bar after the startup jitter", len(due))
+	}
+	s.reschedule(repo, idle, now)
+	if due, _ := s.dueFilters(now.Add(time.Second), 0); len(due) != 0 {
+		t.Errorf("got %d due repositories, wanted none right after a poll", len(due))
+	}
+
+	s.refresh(repo, now.Add(time.Second))
+	if next := s.schedules[repo].next; !next.Equal(now.Add(minRefreshInterval)) {
+		t.Errorf("got the refresh at %s, wanted it %s after the poll", next, minRefreshInterval)
+	}
+
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec.Code
+	}
+	if code := serve("/api/refresh/foo/bar"); code != http.StatusAccepted {
+		t.Errorf("got status %d refreshing a tracked repository, wanted 202", code)
+	}
+	if code := serve("/api/refresh/foo/missing"); code != http.StatusNotFound {
+		t.Errorf("got status %d refreshing an untracked repository, wanted 404", code)
+	}
+	if refreshed := <-s.refreshes; refreshed != repo {
+		t.Errorf("got refresh of %s, wanted %s", refreshed, repo)
+	}
+
+	s.updateFilters([]*github.WorkflowFilter{}, nil)
+	if _, removed := s.dueFilters(now, 0); !removed {
+		t.Errorf("got no removed repositories after foo/bar stopped being tracked")
+	}
+}
+
+func TestRefreshRequiresAdminToken(t *testing.T) {
+	s, _ := newTestServer(t, &Options{AdminToken: "secret"})
+	serve := func(body string, auth func(r *http.Request)) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/refresh/foo/bar", strings.NewReader(body))
+		if auth != nil {
+			auth(r)
+		}
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, r)
+		return rec.Code
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }
+	basic := func(r *http.Request) {
+		r.SetBasicAuth("admin", "secret")
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if code := serve("", nil); code != http.StatusUnauthorized {
+		t.Errorf("got status %d without a token, wanted 401", code)
+	}
+	if code := serve("", bearer); code != http.StatusAccepted {
+		t.Errorf("got status %d with the bearer token, wanted 202", code)
+	}
+	if code := serve("", basic); code != http.StatusForbidden {
+		t.Errorf("got status %d with basic auth but without a csrf token, wanted 403", code)
+	}
+	if code := serve("csrf="+s.csrfToken(), basic); code != http.StatusAccepted {
+		t.Errorf("got status %d with basic auth and the csrf token, wanted 202", code)
+	}
+}
+
+func TestServeUnderPathPrefix(t *testing.T) {
+	s, _ := newTestServer(t, &Options{PathPrefix: "/ci", AdminToken: "secret"})
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	mux := http.NewServeMux()
+	mux.Handle("/ci/", s.Handler())
+	serve := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := serve("/ci/api/foo/bar"); rec.Code != http.StatusOK || len(decodeRuns(t, rec).Runs) != 3 {
+		t.Errorf("got status %d, wanted the runs of foo/bar under the prefix", rec.Code)
+	}
+	body := serve("/ci/foo/bar").Body.String()
+	for _, want := range []string{`href="/ci/runners"`, `href="/ci/foo/bar"`, `/ci/api/events?`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("got the dashboard without %q:\n%s", want, body)
+		}
+	}
+	if body := serve("/ci/admin").Body.String(); !strings.Contains(body, `action="/ci/admin"`) {
+		t.Errorf("got the admin page without the prefixed form action:\n%s", body)
+	}
+	if rec := serve("/ci"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/ci/" {
+		t.Errorf("got status %d and location %s, wanted a redirect to /ci/", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestPollTimeoutAndCancellation(t *testing.T) {
+	s, fake := newTestServer(t, &Options{PollTimeout: 50 * time.Millisecond})
+	fake.DelayRequests("/repos/foo/bar/", time.Minute)
+
+	started := time.Now()
+	if states := s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()); len(states) != 0 {
+		t.Errorf("got %d states, wanted the poll of foo/bar to time out", len(states))
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("poll took %s, wanted it to be cancelled after the poll timeout", elapsed)
+	}
+	if status := s.getPollStatus().repos[RepoId{owner: "foo", name: "bar"}]; status == nil || status.consecutiveFailures != 1 {
+		t.Errorf("got poll status %+v, wanted a failed poll", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		if err := s.Poll(ctx); err != nil {
+			t.Errorf("got error: %s", err)
+		}
+	}()
+	cancel()
+
+	select {
+	case <-polled:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("polling didn't stop after the context was cancelled")
+	}
+}
+
+func TestRunShutsDownOnCancel(t *testing.T) {
+	s, _ := newTestServer(t, &Options{PollInterval: time.Minute, ShutdownTimeout: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Run(ctx) }()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("got error: %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("server didn't shut down after the context was cancelled")
+	}
+}
+
+func TestShutdownDrainsRequestsAndEndsEventStreams(t *testing.T) {
+	s, _ := newTestServer(t, &Options{})
+	httpServer := s.newHTTPServer()
+
+	// a request that is still in flight when the shutdown starts
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := httpServer.Handler
+	httpServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/slow" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		close(started)
+		<-release
+		if r.Context().Err() != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	go func() { _ = httpServer.Serve(listener) }()
+	baseURL := "http://" + listener.Addr().String()
+
+	events, err := http.Get(baseURL + "/api/events")
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	defer events.Body.Close()
+	if line, err := bufio.NewReader(events.Body).ReadString('\n'); err != nil || !strings.HasPrefix(line, "retry:") {
+		t.Fatalf("got %q, err: %v, wanted the start of the event stream", line, err)
+	}
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- httpServer.Shutdown(ctx) }()
+
+	streamEnded := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, events.Body)
+		streamEnded <- err
+	}()
+	select {
+	case err := <-streamEnded:
+		if err != nil {
+			t.Errorf("got error: %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("event stream didn't end on shutdown")
+	}
+
+	close(release)
+	if code := <-status; code != http.StatusOK {
+		t.Errorf("got status %d, wanted the in flight request to complete", code)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("got error: %s", err)
+	}
+}
+
+func TestServeRoutesThatArentAboutAnOwner(t *testing.T) {
+	s, fake := newTestServer(t, &Options{Filters: []*github.WorkflowFilter{{Owner: "runners", Repo: "bar"}}})
+	fake.AddWorkflow("runners", "bar", githubtest.Workflow{ID: 3, Name: "build"})
+	fake.AddRun("runners", "bar", githubtest.Run{ID: 30, WorkflowID: 3, Status: "completed", Conclusion: "success", CreatedAt: time.Now()})
+	s.updatePolledState(s.fetchAllStatesIgnoringErrors(context.Background(), time.Now()))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for _, path := range []string{
+		"/runners", "/_/runners", "/api/runners", "/api/_/runners",
+		"/workflows", "/_/workflows", "/api/workflows", "/api/_/workflows",
+		"/api/stats/runners/bar", "/api/_/stats/runners/bar",
+		"/flaky", "/_/flaky", "/api/flaky", "/api/_/flaky",
+		"/dora", "/_/dora", "/api/dora", "/api/_/dora",
+		"/regressions", "/_/regressions", "/api/regressions", "/api/_/regressions",
+		"/heatmap", "/_/heatmap", "/api/heatmap", "/api/_/heatmap",
+		"/api/snapshot", "/api/_/snapshot", "/api/runs", "/api/_/runs",
+		"/metrics", "/_/metrics", "/healthz", "/_/healthz", "/api/status", "/api/_/status",
+	} {
+		if rec := serve(path); rec.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, wanted 200", path, rec.Code)
+		}
+	}
+	if body := serve("/api/runners").Body.String(); body != serve("/api/_/runners").Body.String() {
+		t.Errorf("got %s, wanted the runners under the reserved owner as well", body)
+	}
+
+	// the repositories of an owner named like a route are still served
+	if rec := serve("/api/runners/bar"); rec.Code != http.StatusOK || len(decodeRuns(t, rec).Runs) != 1 {
+		t.Errorf("got status %d, wanted the runs of runners/bar", rec.Code)
+	}
+	if body := serve("/runners/bar").Body.String(); !strings.Contains(body, `href="/runners/bar"`) {
+		t.Errorf("got the dashboard without repository runners/bar:\n%s", body)
+	}
+	for _, path := range []string{"/_", "/_/foo", "/_/foo/bar", "/api/_", "/api/_/foo", "/api/_/foo/bar/baz"} {
+		if rec := serve(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: got status %d, wanted 404 for an unknown reserved path", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+// Orders of RunQuery.Sort, prefixed with "-" for the descending order
+const (
+	SortByCreated  = "created"
+	SortByUpdated  = "updated"
+	SortByDuration = "duration"
+	SortByWorkflow = "workflow"
+)
+
+// Selects runs of the state, empty fields match all runs
+type RunQuery struct {
+	Owner      string
+	Repo       string
+	Workflow   string
+	Branch     string
+	Event      string
+	Conclusion string
+	Status     string
+	// Author of the head commit, the github client doesn't provide the actor who triggered a run
+	Actor string
+	// Runs created at or after Since and before Until, zero times don't bound the range
+	Since time.Time
+	Until time.Time
+	// One of the SortBy orders, runs are ordered by repository, workflow and from the newest to the oldest run if empty
+	Sort string
+	// Number of runs skipped from the start of the sorted runs and the max number of returned runs, 0 means all runs
+	Offset int
+	Limit  int
+	// ID of the run after which a page starts, the offset is applied after it. 0 starts at the first run.
+	After int
+}
+
+// A page of the runs matching a query
+type runPage struct {
+	runs []*github.WorkflowRun
+	// Number of all runs matching the query
+	total int
+	// ID of the last run of the page if more runs follow, 0 otherwise
+	last int
+}
+
+var runSortKeys = []string{SortByCreated, SortByUpdated, SortByDuration, SortByWorkflow}
+
+func (q *RunQuery) validate() error {
+	if q.Sort != "" && !isRunSortKey(strings.TrimPrefix(q.Sort, "-")) {
+		return fmt.Errorf("unknown sort=%s, supported: %s (prefixed with - for descending)", q.Sort, strings.Join(runSortKeys, ", "))
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return fmt.Errorf("offset and limit must be >= 0, offset=%d, limit=%d", q.Offset, q.Limit)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		return fmt.Errorf("until must be after since, since=%s, until=%s", q.Since.Format(time.RFC3339), q.Until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func isRunSortKey(key string) bool {
+	for _, sortKey := range runSortKeys {
+		if key == sortKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *RunQuery) matches(run *github.WorkflowRun) bool {
+	return (q.Branch == "" || run.JobBranch == q.Branch) &&
+		(q.Event == "" || run.JobEvent == q.Event) &&
+		(q.Conclusion == "" || run.JobConclusion == q.Conclusion) &&
+		(q.Status == "" || run.JobStatus == q.Status) &&
+		(q.Actor == "" || run.JobCommitAuthor == q.Actor) &&
+		(q.Since.IsZero() || !run.JobRunTime.Before(q.Since)) &&
+		(q.Until.IsZero() || run.JobRunTime.Before(q.Until))
+}
+
+// An immutable version of the state of all repositories indexed by owner, repository and workflow. Updates create the
+// next version so that readers holding a version see a consistent state.
+type stateRepository struct {
+	version uint64
+	// Time the version was created
+	uts  time.Time
+	data map[RepoId]*indexedRepoState
+	// repositories of each owner sorted by name
+	byOwner map[string][]RepoId
+}
+
+type indexedRepoState struct {
+	state *repoState
+	// runs of each workflow in the order of the state
+	byWorkflow map[string][]*github.WorkflowRun
+}
+
+func newStateRepo() *stateRepository {
+	return &stateRepository{
+		data:    make(map[RepoId]*indexedRepoState),
+		byOwner: make(map[string][]RepoId),
+	}
+}
+
+// The next version of the state with the new repository states, the other repositories of this version are kept if
+// retain returns true for them
+func (r *stateRepository) next(uts time.Time, newState []*repoState, retain func(*repoState) bool) *stateRepository {
+	result := newStateRepo()
+	result.version = r.version + 1
+	result.uts = uts
+
+	replaced := map[RepoId]bool{}
+	for _, s := range newState {
+		replaced[s.repo] = true
+	}
+	for repo, indexed := range r.data {
+		if !replaced[repo] && retain(indexed.state) {
+			result.add(indexed)
+		}
+	}
+
+	for _, s := range newState {
+		indexed := &indexedRepoState{state: s, byWorkflow: make(map[string][]*github.WorkflowRun)}
+		for _, run := range s.runs {
+			indexed.byWorkflow[run.WorkflowName] = append(indexed.byWorkflow[run.WorkflowName], run)
+		}
+		result.add(indexed)
+	}
+	return result
+}
+
+// Only called while creating a version
+func (r *stateRepository) add(indexed *indexedRepoState) {
+	newState := indexed.state
+	if _, ok := r.data[newState.repo]; !ok {
+		repos := append(r.byOwner[newState.repo.owner], newState.repo)
+		sort.Slice(repos, func(i, j int) bool {
+			return repos[i].name < repos[j].name
+		})
+		r.byOwner[newState.repo.owner] = repos
+	}
+	r.data[newState.repo] = indexed
+}
+
+// The states of the repositories matching the query sorted by repository, each with only the runs matching the query.
+// Sorting and pagination of the query aren't applied.
+func (r *stateRepository) queryRepos(q *RunQuery) []*repoState {
+	result := make([]*repoState, 0)
+	for _, repo := range r.candidateRepos(q) {
+		indexed := r.data[repo]
+
+		runs := indexed.state.runs
+		if q.Workflow != "" {
+			runs = indexed.byWorkflow[q.Workflow]
+		}
+
+		filtered := &repoState{
+			repo:        indexed.state.repo,
+			uts:         indexed.state.uts,
+			runs:        make([]*github.WorkflowRun, 0),
+			definitions: indexed.state.definitions,
+		}
+		for _, run := range runs {
+			if q.matches(run) {
+				filtered.runs = append(filtered.runs, run)
+			}
+		}
+
+		if q.Workflow != "" {
+			filtered.definitions = make([]*github.WorkflowDefinition, 0)
+			for _, definition := range indexed.state.definitions {
+				if definition.WorkflowName == q.Workflow {
+					filtered.definitions = append(filtered.definitions, definition)
+				}
+			}
+		}
+
+		result = append(result, filtered)
+	}
+	return result
+}
+
+// The sorted page of the runs matching the query and the total number of matching runs
+func (r *stateRepository) queryRuns(q *RunQuery) ([]*github.WorkflowRun, int) {
+	runs := make([]*github.WorkflowRun, 0)
+	for _, repoState := range r.queryRepos(q) {
+		runs = append(runs, repoState.runs...)
+	}
+
+	sortRuns(runs, q.Sort)
+
+	total := len(runs)
+	from := q.Offset
+	if from > total {
+		from = total
+	}
+	to := total
+	if q.Limit > 0 && from+q.Limit < total {
+		to = from + q.Limit
+	}
+	return runs[from:to], total
+}
+
+// The page of the runs matching the query that starts after the run of q.After, fails if that run doesn't match the
+// query anymore
+func (r *stateRepository) queryPage(q *RunQuery) (*runPage, error) {
+	all := *q
+	all.Offset, all.Limit = 0, 0
+	runs, total := r.queryRuns(&all)
+
+	from := 0
+	if q.After != 0 {
+		from = -1
+		for i, run := range runs {
+			if run.JobRunID == q.After {
+				from = i + 1
+				break
+			}
+		}
+		if from < 0 {
+			return nil, fmt.Errorf("run %d of the cursor no longer matches the query", q.After)
+		}
+	}
+
+	from += q.Offset
+	if from > total {
+		from = total
+	}
+	to := total
+	if q.Limit > 0 && from+q.Limit < total {
+		to = from + q.Limit
+	}
+
+	page := &runPage{runs: runs[from:to], total: total}
+	if to < total && to > from {
+		page.last = runs[to-1].JobRunID
+	}
+	return page, nil
+}
+
+// The repositories the query can match sorted by owner and name
+func (r *stateRepository) candidateRepos(q *RunQuery) []RepoId {
+	if q.Owner != "" && q.Repo != "" {
+		repo := RepoId{owner: q.Owner, name: q.Repo}
+		if _, ok := r.data[repo]; ok {
+			return []RepoId{repo}
+		}
+		return []RepoId{}
+	}
+
+	owners := []string{q.Owner}
+	if q.Owner == "" {
+		owners = make([]string, 0, len(r.byOwner))
+		for owner := range r.byOwner {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+	}
+
+	result := make([]RepoId, 0)
+	for _, owner := range owners {
+		for _, repo := range r.byOwner[owner] {
+			if q.Repo == "" || repo.name == q.Repo {
+				result = append(result, repo)
+			}
+		}
+	}
+	return result
+}
+
+// Sorts the runs by one of the SortBy orders, runs with equal values keep their order
+func sortRuns(runs []*github.WorkflowRun, order string) {
+	if order == "" {
+		return
+	}
+
+	descending := strings.HasPrefix(order, "-")
+	var less func(a, b *github.WorkflowRun) bool
+	switch strings.TrimPrefix(order, "-") {
+	case SortByCreated:
+		less = func(a, b *github.WorkflowRun) bool { return a.JobRunTime.Before(b.JobRunTime) }
+	case SortByUpdated:
+		less = func(a, b *github.WorkflowRun) bool { return a.JobUpdateTime.Before(b.JobUpdateTime) }
+	case SortByDuration:
+		less = func(a, b *github.WorkflowRun) bool {
+			// runs that didn't complete yet have no duration and are sorted as the shortest ones
+			aDuration, _ := stats.Duration(a)
+			bDuration, _ := stats.Duration(b)
+			return aDuration < bDuration
+		}
+	case SortByWorkflow:
+		less = func(a, b *github.WorkflowRun) bool { return a.WorkflowName < b.WorkflowName }
+	default:
+		return
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		if descending {
+			return less(runs[j], runs[i])
+		}
+		return less(runs[i], runs[j])
+	})
+}
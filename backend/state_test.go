@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func newTestStateRepo(now time.Time) *stateRepository {
+	run := func(owner, repo, workflow string, id int, branch, conclusion string, created time.Time) *github.WorkflowRun {
+		return &github.WorkflowRun{
+			WorkflowOwner: owner,
+			WorkflowRepo:  repo,
+			WorkflowName:  workflow,
+			JobRunID:      id,
+			JobBranch:     branch,
+			JobStatus:     github.StatusCompleted,
+			JobConclusion: conclusion,
+			JobRunTime:    created,
+		}
+	}
+
+	return newStateRepo().next(now, []*repoState{
+		{repo: RepoId{owner: "foo", name: "bar"}, runs: []*github.WorkflowRun{
+			run("foo", "bar", "build", 1, "main", "success", now.Add(-time.Hour)),
+			run("foo", "bar", "build", 2, "dev", "failure", now.Add(-2*time.Hour)),
+			run("foo", "bar", "release", 3, "main", "success", now.Add(-3*time.Hour)),
+		}},
+		{repo: RepoId{owner: "foo", name: "baz"}, runs: []*github.WorkflowRun{
+			run("foo", "baz", "build", 4, "main", "failure", now),
+		}},
+		{repo: RepoId{owner: "qux", name: "bar"}, runs: []*github.WorkflowRun{
+			run("qux", "bar", "build", 5, "main", "success", now.Add(-4*time.Hour)),
+		}},
+	}, retainAll)
+}
+
+func retainAll(*repoState) bool {
+	return true
+}
+
+func TestQueryRuns(t *testing.T) {
+	now := time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)
+	state := newTestStateRepo(now)
+
+	tests := []struct {
+		name  string
+		query *RunQuery
+		want  []int
+	}{
+		{"all", &RunQuery{}, []int{1, 2, 3, 4, 5}},
+		{"owner", &RunQuery{Owner: "foo"}, []int{1, 2, 3, 4}},
+		{"owner and repo", &RunQuery{Owner: "foo", Repo: "bar"}, []int{1, 2, 3}},
+		{"owner and workflow", &RunQuery{Owner: "foo", Workflow: "build"}, []int{1, 2, 4}},
+		{"repo across owners", &RunQuery{Repo: "bar"}, []int{1, 2, 3, 5}},
+		{"workflow and branch", &RunQuery{Workflow: "build", Branch: "main"}, []int{1, 4, 5}},
+		{"conclusion", &RunQuery{Conclusion: "failure"}, []int{2, 4}},
+		{"time range", &RunQuery{Since: now.Add(-3 * time.Hour), Until: now}, []int{1, 2, 3}},
+		{"unknown repo", &RunQuery{Owner: "foo", Repo: "missing"}, []int{}},
+		{"sorted", &RunQuery{Sort: "-" + SortByCreated}, []int{4, 1, 2, 3, 5}},
+		{"sorted ascending", &RunQuery{Sort: SortByCreated}, []int{5, 3, 2, 1, 4}},
+		{"page", &RunQuery{Sort: SortByCreated, Offset: 1, Limit: 2}, []int{3, 2}},
+		{"page after the end", &RunQuery{Offset: 10}, []int{}},
+	}
+
+	for _, test := range tests {
+		runs, _ := state.queryRuns(test.query)
+		ids := make([]int, 0)
+		for _, run := range runs {
+			ids = append(ids, run.JobRunID)
+		}
+
+		if len(ids) != len(test.want) {
+			t.Errorf("%s: got runs %v, wanted %v", test.name, ids, test.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != test.want[i] {
+				t.Errorf("%s: got runs %v, wanted %v", test.name, ids, test.want)
+				break
+			}
+		}
+	}
+
+	if _, total := state.queryRuns(&RunQuery{Limit: 2}); total != 5 {
+		t.Errorf("got total %d, wanted 5", total)
+	}
+}
+
+func TestNextStateVersion(t *testing.T) {
+	now := time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)
+	previous := newTestStateRepo(now)
+
+	next := previous.next(now.Add(time.Minute), []*repoState{{repo: RepoId{owner: "foo", name: "bar"}, runs: []*github.WorkflowRun{}}}, func(s *repoState) bool {
+		return s.repo.owner == "foo"
+	})
+	if next.version != previous.version+1 || !next.uts.Equal(now.Add(time.Minute)) {
+		t.Errorf("got version %d at %s, wanted version %d", next.version, next.uts, previous.version+1)
+	}
+
+	repos := next.queryRepos(&RunQuery{Workflow: "build"})
+	if len(repos) != 2 || repos[0].repo.name != "bar" || len(repos[0].runs) != 0 || repos[1].repo.name != "baz" || len(repos[1].runs) != 1 {
+		t.Errorf("got %d repos, wanted the replaced foo/bar without runs and the retained foo/baz", len(repos))
+	}
+
+	if runs, _ := previous.queryRuns(&RunQuery{}); len(runs) != 5 {
+		t.Errorf("got %d runs in the previous version, wanted it unchanged with 5 runs", len(runs))
+	}
+}
+
+func TestRunQueryValidate(t *testing.T) {
+	now := time.Now()
+	invalid := []*RunQuery{
+		{Sort: "foo"},
+		{Sort: "-"},
+		{Offset: -1},
+		{Since: now, Until: now.Add(-time.Hour)},
+	}
+	for _, query := range invalid {
+		if err := query.validate(); err == nil {
+			t.Errorf("got no error for %+v", query)
+		}
+	}
+
+	if err := (&RunQuery{Sort: "-" + SortByDuration, Limit: 10}).validate(); err != nil {
+		t.Errorf("got error: %s", err)
+	}
+}
@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Outcome of the polls of the tracked repositories, replaced after every poll
+type pollStatus struct {
+	// Time the latest poll of all repositories finished and how long it took, zero before the first poll
+	finished time.Time
+	duration time.Duration
+	// Time of the first poll that fetched a repository or had none to fetch, the server isn't ready before
+	ready time.Time
+	// Status of the repositories tracked during the latest poll
+	repos map[RepoId]*repoPollStatus
+}
+
+type repoPollStatus struct {
+	lastAttempt time.Time
+	// zero if the repository was never fetched
+	lastSuccess         time.Time
+	consecutiveFailures int
+	// Error of the latest failed attempt, empty once an attempt succeeds
+	lastError string
+	// Number of failed attempts since the start
+	errors int
+	// Interval until the next poll without the jitter and the time of the next poll
+	interval time.Duration
+	nextPoll time.Time
+}
+
+// Outcome of fetching the state of a repository during a poll
+type repoPollResult struct {
+	repo      RepoId
+	attempted time.Time
+	err       error
+	interval  time.Duration
+	next      time.Time
+}
+
+// Status of the server and of the polls of every repository
+type statusResponse struct {
+	Ready   bool      `json:"ready"`
+	Version uint64    `json:"version"`
+	Updated time.Time `json:"updated"`
+	// Time the latest poll finished and its duration in seconds, omitted before the first poll
+	LastPoll            *time.Time            `json:"lastPoll,omitempty"`
+	PollDurationSeconds float64               `json:"pollDurationSeconds"`
+	Repositories        []*repoStatusResponse `json:"repositories"`
+}
+
+type repoStatusResponse struct {
+	Owner               string     `json:"owner"`
+	Repo                string     `json:"repo"`
+	LastAttempt         *time.Time `json:"lastAttempt,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError,omitempty"`
+	Errors              int        `json:"errors"`
+	PollIntervalSeconds float64    `json:"pollIntervalSeconds,omitempty"`
+	NextPoll            *time.Time `json:"nextPoll,omitempty"`
+	// The latest attempt failed or the repository wasn't fetched for longer than two poll intervals
+	Stale bool `json:"stale"`
+}
+
+// Liveness probe, the server is healthy as long as it serves requests
+func healthz(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// Readiness probe, the server is ready once a poll fetched the state of a repository
+func readyz(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if server.getPollStatus().ready.IsZero() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, "not ready: no repository was fetched yet")
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	}
+}
+
+func statusJson(server *Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := server.requestState(r)
+		polls := server.getPollStatus()
+		now := time.Now()
+
+		result := &statusResponse{
+			Ready:               !polls.ready.IsZero(),
+			Version:             state.version,
+			Updated:             state.uts,
+			PollDurationSeconds: polls.duration.Seconds(),
+			Repositories:        make([]*repoStatusResponse, 0),
+		}
+		if !polls.finished.IsZero() {
+			result.LastPoll = &polls.finished
+		}
+
+		for _, repo := range server.statusRepos(polls) {
+			status := polls.repos[repo]
+			response := &repoStatusResponse{Owner: repo.owner, Repo: repo.name}
+			if status != nil {
+				response.LastAttempt = timeOrNil(status.lastAttempt)
+				response.LastSuccess = timeOrNil(status.lastSuccess)
+				response.ConsecutiveFailures = status.consecutiveFailures
+				response.LastError = status.lastError
+				response.Errors = status.errors
+				response.PollIntervalSeconds = status.interval.Seconds()
+				response.NextPoll = timeOrNil(status.nextPoll)
+				response.Stale = server.isStale(status, now)
+			}
+			result.Repositories = append(result.Repositories, response)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Warn("Failed writing status, err: ", err)
+		}
+	}
+}
+
+// The polled repositories and the configured ones that weren't polled yet sorted by owner and name
+func (s *Server) statusRepos(polls *pollStatus) []RepoId {
+	repos := make([]RepoId, 0, len(polls.repos))
+	for repo := range polls.repos {
+		repos = append(repos, repo)
+	}
+	if polls.finished.IsZero() {
+		for _, filter := range s.getFilters() {
+			repo := RepoId{owner: filter.Owner, name: filter.Repo}
+			if _, ok := polls.repos[repo]; !ok {
+				repos = append(repos, repo)
+			}
+		}
+	}
+
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].owner != repos[j].owner {
+			return repos[i].owner < repos[j].owner
+		}
+		return repos[i].name < repos[j].name
+	})
+	return repos
+}
+
+// Whether the latest attempt to fetch the repository failed or it wasn't fetched for longer than two poll intervals
+func (s *Server) isStale(status *repoPollStatus, now time.Time) bool {
+	if status.consecutiveFailures > 0 {
+		return true
+	}
+	interval := s.opts.PollInterval
+	if status.interval > interval {
+		interval = status.interval
+	}
+	return interval > 0 && now.Sub(status.lastSuccess) > 2*interval
+}
+
+// Why the runs of a stale repository shown on the dashboard may be outdated
+func (s *Server) pollProblem(status *repoPollStatus, now time.Time) string {
+	if status.consecutiveFailures == 0 {
+		return fmt.Sprintf("not fetched for %s", now.Sub(status.lastSuccess).Round(time.Second))
+	}
+
+	problem := fmt.Sprintf("fetching failed %d times in a row", status.consecutiveFailures)
+	if !status.lastSuccess.IsZero() {
+		problem += fmt.Sprintf(", showing the state of %s ago", now.Sub(status.lastSuccess).Round(time.Second))
+	}
+	return problem
+}
+
+// The status after a poll of some of the tracked repositories, repositories that are no longer tracked are dropped
+func (p *pollStatus) next(finished time.Time, duration time.Duration, results []*repoPollResult, tracked map[RepoId]bool) *pollStatus {
+	result := &pollStatus{
+		finished: finished,
+		duration: duration,
+		ready:    p.ready,
+		repos:    make(map[RepoId]*repoPollStatus, len(tracked)),
+	}
+	for repo, status := range p.repos {
+		if tracked[repo] {
+			result.repos[repo] = status
+		}
+	}
+
+	fetched := len(results) == 0
+	for _, r := range results {
+		status := &repoPollStatus{}
+		if previous, ok := p.repos[r.repo]; ok {
+			*status = *previous
+		}
+		status.lastAttempt = r.attempted
+		status.interval = r.interval
+		status.nextPoll = r.next
+
+		if r.err != nil {
+			status.consecutiveFailures++
+			status.errors++
+			status.lastError = r.err.Error()
+		} else {
+			status.lastSuccess = r.attempted
+			status.consecutiveFailures = 0
+			status.lastError = ""
+			fetched = true
+		}
+		result.repos[r.repo] = status
+	}
+
+	if result.ready.IsZero() && fetched {
+		result.ready = finished
+	}
+	return result
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,640 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/formatter"
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/snapshot"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+// A sub command passed as the first CLI argument, e.g. `github-workflow-dashboard workflows -owner foo -repo bar`
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(cmd *command, args []string) error
+}
+
+var commands = []*command{
+	{
+		name:        "workflows",
+		usage:       "[global flags] [-stale-only] ['<workflow>']",
+		description: "List the workflows of each repository with their state and last run, all workflows are listed if none are passed",
+		run:         runWorkflowsCmd,
+	},
+	{
+		name:        "stats",
+		usage:       "[global flags] [-group-by-branch] [-since-days N] [-window-days N] ['<workflow>']",
+		description: "Print success rates, durations and queue times of the workflow runs, all workflows are included if none are passed",
+		run:         runStatsCmd,
+	},
+	{
+		name:        "flaky",
+		usage:       "[global flags] ['<workflow>']",
+		description: "Rank workflows and jobs by flaky failures, i.e. failures that passed on re-run or without a code change",
+		run:         runFlakyCmd,
+	},
+	{
+		name:        "regressions",
+		usage:       "[global flags] [-jobs] ['<workflow>']",
+		description: "Detect workflows and jobs whose recent runs got slower than the baseline and the commits where durations shifted",
+		run:         runRegressionsCmd,
+	},
+	{
+		name:        "heatmap",
+		usage:       "[global flags] [-metric runs|failure-rate|queue-time] [-since-days N] ['<workflow>']",
+		description: "Print run counts, failure rates or queue times by weekday and hour of the day, all workflows are included if none are passed",
+		run:         runHeatmapCmd,
+	},
+	{
+		name:        "dora",
+		usage:       "-deployment <owner>/<repo>/<workflow>[@<branch>][#<param>=<value>] [global flags] [-since-days N] [-window-days N]",
+		description: "Print deployment frequency, lead time, change failure rate and time to restore of the deployment workflows",
+		run:         runDoraCmd,
+	},
+	{
+		name:        "export",
+		usage:       "-output <file> [-server <url>] [global flags] ['<workflow>']",
+		description: "Write the runs of the tracked workflows, or the state of a running server, to a snapshot file",
+		run:         runExportCmd,
+	},
+	{
+		name:        "import",
+		usage:       "-server <url> <file>",
+		description: "Replace the state of the repositories of a snapshot file in a running server",
+		run:         runImportCmd,
+	},
+	{
+		name:        "render",
+		usage:       "[-format ascii|json] [-history N] <file>",
+		description: "Print the runs of a snapshot file without accessing github",
+		run:         runRenderCmd,
+	},
+	{
+		name:        "enable-workflow",
+		usage:       "-owner <owner> -repo <repo> '<workflow>'",
+		description: "Enable a disabled workflow",
+		run: func(cmd *command, args []string) error {
+			return runToggleWorkflowCmd(cmd, args, github.WorkflowClient.EnableWorkflow)
+		},
+	},
+	{
+		name:        "disable-workflow",
+		usage:       "-owner <owner> -repo <repo> '<workflow>'",
+		description: "Disable a workflow so that it is no longer triggered",
+		run: func(cmd *command, args []string) error {
+			return runToggleWorkflowCmd(cmd, args, github.WorkflowClient.DisableWorkflow)
+		},
+	},
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printCommands() {
+	for _, cmd := range commands {
+		fmt.Printf("  %s %s\n", cmd.name, cmd.usage)
+		fmt.Printf("    \t%s\n", cmd.description)
+	}
+}
+
+func newCommandFlagSet(cmd *command) (*flag.FlagSet, *options) {
+	fs, opts := newFlagSet(fmt.Sprintf("%s %s", ClientName, cmd.name))
+	fs.Usage = func() {
+		fmt.Printf("Usage: %s %s %s\n", ClientName, cmd.name, cmd.usage)
+		fmt.Printf("\nflags:\n")
+		fs.PrintDefaults()
+	}
+	return fs, opts
+}
+
+func exitWithUsage(fs *flag.FlagSet, msg string) {
+	fmt.Printf("error: %s\n\n", msg)
+	fs.Usage()
+	os.Exit(1)
+}
+
+func runWorkflowsCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	staleOnly := fs.Bool("stale-only", false, "List only stale or disabled workflows")
+	opts.allWorkflowsByDefault = true
+
+	workflows := make([]*github.Workflow, 0)
+	return runReportCmd(fs, opts, args, &report{
+		fetch: func(ctx context.Context, client github.WorkflowClient, filter *github.WorkflowFilter) error {
+			repoWorkflows, err := client.FetchWorkflows(ctx, filter)
+			if err != nil {
+				return err
+			}
+			workflows = append(workflows, repoWorkflows...)
+			return nil
+		},
+		format: func(json bool) (string, error) {
+			github.MarkStaleWorkflows(workflows, time.Now(), opts.getStaleAfter())
+			if *staleOnly {
+				workflows = filterInactiveWorkflows(workflows)
+			}
+
+			if json {
+				return formatter.WorkflowsToJson(workflows)
+			}
+			return formatter.WorkflowsToAscii(workflows)
+		},
+	})
+}
+
+// A command printing a report of the tracked repositories, it only supplies what is fetched from each repository and how
+// the report is computed and formatted
+type report struct {
+	// validates the parsed options, the options of the tracked repositories are validated if nil
+	validate func() (bool, string)
+	// the filters of the repositories the report is fetched from, the tracked repositories if nil
+	filters func(ctx context.Context, client github.WorkflowClient) ([]*github.WorkflowFilter, error)
+	// fetches the data the report is computed from out of the repository of the filter
+	fetch func(ctx context.Context, client github.WorkflowClient, filter *github.WorkflowFilter) error
+	// computes the report from everything fetched and formats it as json or ascii
+	format func(json bool) (string, error)
+}
+
+// Parses and validates the flags, fetches the report from each repository and prints it in the output format
+func runReportCmd(fs *flag.FlagSet, opts *options, args []string, r *report) error {
+	parseOptions(fs, opts, args)
+
+	validate := r.validate
+	if validate == nil {
+		validate = opts.isValid
+	}
+	if isValid, msg := validate(); !isValid {
+		exitWithUsage(fs, msg)
+	}
+
+	ctx := context.Background()
+	client, err := newGithubClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	var filters []*github.WorkflowFilter
+	if r.filters != nil {
+		filters, err = r.filters(ctx, client)
+	} else {
+		filters, err = newCommandFilters(ctx, client, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, filter := range filters {
+		if err := r.fetch(ctx, client, filter); err != nil {
+			return err
+		}
+	}
+
+	result, err := r.format(opts.formatMod == "json")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(result)
+	return nil
+}
+
+// Validates with each of the checks until one fails
+func validateAll(checks ...func() (bool, string)) func() (bool, string) {
+	return func() (bool, string) {
+		for _, check := range checks {
+			if isValid, msg := check(); !isValid {
+				return false, msg
+			}
+		}
+		return true, ""
+	}
+}
+
+// Collects the runs of the repositories and the jobs of the runs that need them by run ID, the jobs of no run are
+// fetched if needsJobs is nil
+type runsCollector struct {
+	runs      []*github.WorkflowRun
+	jobs      map[int][]*github.RunJob
+	needsJobs func(run *github.WorkflowRun) bool
+}
+
+func newRunsCollector(needsJobs func(run *github.WorkflowRun) bool) *runsCollector {
+	return &runsCollector{runs: make([]*github.WorkflowRun, 0), jobs: map[int][]*github.RunJob{}, needsJobs: needsJobs}
+}
+
+func (c *runsCollector) fetch(ctx context.Context, client github.WorkflowClient, filter *github.WorkflowFilter) error {
+	runs, err := client.FetchWorkflowRuns(ctx, filter)
+	if err != nil {
+		return err
+	}
+	c.runs = append(c.runs, runs...)
+
+	if c.needsJobs == nil {
+		return nil
+	}
+	for _, run := range runs {
+		if !c.needsJobs(run) {
+			continue
+		}
+		if c.jobs[run.JobRunID], err = client.FetchRunJobs(ctx, filter, run.JobRunID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The filters of the explicitly passed repositories followed by the discovered ones
+func newCommandFilters(ctx context.Context, client github.WorkflowClient, opts *options) ([]*github.WorkflowFilter, error) {
+	discoveredFilters, err := discoverMultiple(ctx, client, opts.newDiscoveryFiltersOrNil())
+	if err != nil {
+		return nil, err
+	}
+	return appendDiscoveredFilters(newWorkflowFilters(opts), discoveredFilters), nil
+}
+
+func filterInactiveWorkflows(workflows []*github.Workflow) []*github.Workflow {
+	result := make([]*github.Workflow, 0)
+	for _, workflow := range workflows {
+		if workflow.Stale || !workflow.IsActive() {
+			result = append(result, workflow)
+		}
+	}
+	return result
+}
+
+func runStatsCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	groupByBranch := fs.Bool("group-by-branch", false, "Aggregate the runs of each branch separately")
+	window := addWindowFlags(fs)
+	opts.allWorkflowsByDefault = true
+
+	collector := newRunsCollector(nil)
+	return runReportCmd(fs, opts, args, &report{
+		validate: validateAll(opts.isValid, window.isValid),
+		fetch:    collector.fetch,
+		format: func(json bool) (string, error) {
+			statsOpts := window.toStatsOptions(time.Now())
+			statsOpts.GroupByBranch = *groupByBranch
+			workflowStats := stats.Compute(collector.runs, statsOpts)
+
+			if json {
+				return formatter.StatsToJson(workflowStats)
+			}
+			return formatter.StatsToAscii(workflowStats)
+		},
+	})
+}
+
+// The time range and windows of runs aggregated by the stats and dora commands
+type windowFlags struct {
+	sinceDays  *int
+	windowDays *int
+}
+
+func addWindowFlags(fs *flag.FlagSet) *windowFlags {
+	return &windowFlags{
+		sinceDays:  fs.Int("since-days", 0, "Include only runs created in the last N days (0 means all fetched runs)"),
+		windowDays: fs.Int("window-days", 0, "Split the runs into windows of N days (0 means a single window)"),
+	}
+}
+
+func (w *windowFlags) isValid() (bool, string) {
+	if *w.sinceDays < 0 || *w.windowDays < 0 {
+		return false, fmt.Sprintf("since-days and window-days must be >= 0, since-days=%d, window-days=%d", *w.sinceDays, *w.windowDays)
+	}
+	return true, ""
+}
+
+func (w *windowFlags) toStatsOptions(now time.Time) stats.Options {
+	statsOpts := stats.Options{Window: time.Duration(*w.windowDays) * 24 * time.Hour}
+	if *w.sinceDays > 0 {
+		statsOpts.Since = now.Add(-time.Duration(*w.sinceDays) * 24 * time.Hour)
+	}
+	return statsOpts
+}
+
+func runDoraCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	window := addWindowFlags(fs)
+
+	var selectors []*stats.DeploymentSelector
+	// whether the runs of the deployment of a filter need their params
+	needsParams := map[*github.WorkflowFilter]bool{}
+	runs := make([]*github.WorkflowRun, 0)
+	return runReportCmd(fs, opts, args, &report{
+		validate: validateAll(func() (bool, string) {
+			if len(opts.deployments) == 0 {
+				return false, "provide at least one deployment"
+			}
+			return true, ""
+		}, opts.isCommonValid, window.isValid),
+		filters: func(ctx context.Context, client github.WorkflowClient) ([]*github.WorkflowFilter, error) {
+			// invalid deployments are reported by isCommonValid
+			selectors, _ = opts.getDeploymentSelectors()
+
+			filters := make([]*github.WorkflowFilter, 0, len(selectors))
+			for _, selector := range selectors {
+				filter := &github.WorkflowFilter{
+					Owner:         selector.Owner,
+					Repo:          selector.Repo,
+					WorkflowNames: []string{selector.Workflow},
+					Limit:         opts.limit,
+				}
+				needsParams[filter] = selector.NeedsParams()
+				filters = append(filters, filter)
+			}
+			return filters, nil
+		},
+		fetch: func(ctx context.Context, client github.WorkflowClient, filter *github.WorkflowFilter) error {
+			deploymentRuns, err := client.FetchWorkflowRuns(ctx, filter)
+			if err != nil {
+				return err
+			}
+
+			if needsParams[filter] {
+				if err := client.EnrichWorkflowRunsWithParams(ctx, filter, deploymentRuns); err != nil {
+					return err
+				}
+			}
+			runs = append(runs, deploymentRuns...)
+			return nil
+		},
+		format: func(json bool) (string, error) {
+			metrics := stats.ComputeDeliveryMetrics(runs, selectors, window.toStatsOptions(time.Now()))
+
+			if json {
+				return formatter.DeliveryMetricsToJson(metrics)
+			}
+			return formatter.DeliveryMetricsToAscii(metrics)
+		},
+	})
+}
+
+func runFlakyCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	opts.allWorkflowsByDefault = true
+
+	collector := newRunsCollector(stats.NeedsJobs)
+	return runReportCmd(fs, opts, args, &report{
+		fetch: collector.fetch,
+		format: func(json bool) (string, error) {
+			reports := stats.DetectFlakiness(collector.runs, collector.jobs)
+
+			if json {
+				return formatter.FlakinessToJson(reports)
+			}
+			return formatter.FlakinessToAscii(reports)
+		},
+	})
+}
+
+func runHeatmapCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	metric := fs.String("metric", formatter.HeatmapRuns, "The value of the heatmap cells (runs, failure-rate, queue-time)")
+	sinceDays := fs.Int("since-days", 0, "Include only runs created in the last N days (0 means all fetched runs)")
+	opts.allWorkflowsByDefault = true
+
+	collector := newRunsCollector(nil)
+	return runReportCmd(fs, opts, args, &report{
+		validate: validateAll(opts.isValid, func() (bool, string) {
+			if *sinceDays < 0 {
+				return false, fmt.Sprintf("since-days must be >= 0, since-days=%d", *sinceDays)
+			}
+			if !formatter.IsHeatmapMetric(*metric) {
+				return false, fmt.Sprintf("metric must be one of %v, metric=%s", formatter.HeatmapMetrics, *metric)
+			}
+			return true, ""
+		}),
+		fetch: collector.fetch,
+		format: func(json bool) (string, error) {
+			since := time.Time{}
+			if *sinceDays > 0 {
+				since = time.Now().Add(-time.Duration(*sinceDays) * 24 * time.Hour)
+			}
+			heatmap := stats.ComputeHeatmap(collector.runs, opts.getTimezone(), since)
+
+			if json {
+				return formatter.HeatmapToJson(heatmap)
+			}
+			return formatter.HeatmapToAscii(heatmap, *metric)
+		},
+	})
+}
+
+func runRegressionsCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	withJobs := fs.Bool("jobs", false, "Detect regressions of jobs as well, the jobs of every successful run are fetched for that")
+	opts.allWorkflowsByDefault = true
+
+	collector := newRunsCollector(func(run *github.WorkflowRun) bool { return *withJobs && stats.IsSuccess(run) })
+	return runReportCmd(fs, opts, args, &report{
+		fetch: collector.fetch,
+		format: func(json bool) (string, error) {
+			regressions := stats.DetectDurationRegressions(collector.runs, collector.jobs, opts.getRegressionOptions())
+
+			if json {
+				return formatter.RegressionsToJson(regressions)
+			}
+			return formatter.RegressionsToAscii(regressions)
+		},
+	})
+}
+
+func runExportCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	server := fs.String("server", "", "URL of a running server whose state is exported instead of fetching the runs from github")
+	output := fs.String("output", "", "File the snapshot is written to")
+
+	opts.allWorkflowsByDefault = true
+	parseOptions(fs, opts, args)
+
+	if *output == "" {
+		exitWithUsage(fs, "provide an output file")
+	}
+
+	if *server != "" {
+		exported, err := downloadSnapshot(*server)
+		if err != nil {
+			return err
+		}
+		return writeSnapshot(*output, exported)
+	}
+
+	if isValid, msg := opts.isValid(); !isValid {
+		exitWithUsage(fs, msg)
+	}
+
+	ctx := context.Background()
+	client, err := newGithubClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	filters, err := newCommandFilters(ctx, client, opts)
+	if err != nil {
+		return err
+	}
+
+	exported := snapshot.New(time.Now())
+	for _, filter := range filters {
+		var runs []*github.WorkflowRun
+		if opts.latestOnly {
+			runs, err = client.FetchLatestWorkflowRuns(ctx, filter)
+		} else {
+			runs, err = client.FetchWorkflowRuns(ctx, filter)
+		}
+		if err != nil {
+			return err
+		}
+
+		if opts.parseParams {
+			if err := client.EnrichWorkflowRunsWithParams(ctx, filter, runs); err != nil {
+				return err
+			}
+		}
+
+		repo := &snapshot.Repository{Owner: filter.Owner, Name: filter.Repo, Runs: runs, Uts: time.Now()}
+		if opts.parseDefinitions {
+			if repo.Definitions, err = client.FetchWorkflowDefinitions(ctx, filter); err != nil {
+				return err
+			}
+		}
+		exported.Repositories = append(exported.Repositories, repo)
+	}
+
+	return writeSnapshot(*output, exported)
+}
+
+func downloadSnapshot(serverUrl string) (*snapshot.Snapshot, error) {
+	url := strings.TrimSuffix(serverUrl, "/") + "/api/snapshot"
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("downloading snapshot from %s failed, err: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading snapshot from %s failed, status: %s", url, resp.Status)
+	}
+	return snapshot.Read(resp.Body)
+}
+
+func writeSnapshot(path string, written *snapshot.Snapshot) error {
+	if err := snapshot.WriteFile(path, written); err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d runs of %d repositories to %s\n", len(written.Runs()), len(written.Repositories), path)
+	return nil
+}
+
+func runImportCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	server := fs.String("server", "", "URL of the running server the snapshot is imported into")
+	parseOptions(fs, opts, args)
+
+	if *server == "" {
+		exitWithUsage(fs, "provide a server")
+	}
+	if fs.NArg() != 1 {
+		exitWithUsage(fs, "provide exactly one snapshot file")
+	}
+
+	// the snapshot is read first so that invalid files are rejected before being uploaded
+	imported, err := snapshot.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	if err := snapshot.Write(body, imported); err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(*server, "/") + "/api/snapshot"
+	resp, err := http.Post(url, "application/gzip", body)
+	if err != nil {
+		return fmt.Errorf("importing snapshot into %s failed, err: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("importing snapshot into %s failed, status: %s, err: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	fmt.Printf("imported %d runs of %d repositories into %s\n", len(imported.Runs()), len(imported.Repositories), *server)
+	return nil
+}
+
+func runRenderCmd(cmd *command, args []string) error {
+	fs, opts := newCommandFlagSet(cmd)
+	parseOptions(fs, opts, args)
+
+	if fs.NArg() != 1 {
+		exitWithUsage(fs, "provide exactly one snapshot file")
+	}
+	if opts.history < 0 {
+		exitWithUsage(fs, fmt.Sprintf("history must be >= 0, history=%d", opts.history))
+	}
+
+	rendered, err := snapshot.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	result, err := formatCmdOutput(rendered.Runs(), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(result)
+	return nil
+}
+
+type toggleWorkflowFunc func(github.WorkflowClient, context.Context, *github.WorkflowFilter, string) error
+
+func runToggleWorkflowCmd(cmd *command, args []string, toggle toggleWorkflowFunc) error {
+	fs, opts := newCommandFlagSet(cmd)
+	parseOptions(fs, opts, args)
+
+	if len(opts.owners) != 1 || len(opts.repos) != 1 {
+		exitWithUsage(fs, "provide exactly one owner and repo")
+	}
+	if len(opts.workflows) != 1 || len(opts.workflows[0]) != 1 {
+		exitWithUsage(fs, "provide exactly one workflow")
+	}
+
+	ctx := context.Background()
+	client, err := newGithubClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	filter := &github.WorkflowFilter{Owner: opts.owners[0], Repo: opts.repos[0]}
+	workflowName := opts.workflows[0][0]
+
+	if err := toggle(client, ctx, filter, workflowName); err != nil {
+		return fmt.Errorf("%s '%s' of %s failed, err: %s", cmd.name, workflowName, filter.GetRepoId(), err)
+	}
+
+	fmt.Printf("%s: %s of %s\n", cmd.name, workflowName, filter.GetRepoId())
+	return nil
+}
@@ -7,13 +7,20 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/newestuser/github-workflow-dashboard/backend"
+	"github.com/newestuser/github-workflow-dashboard/config"
 	"github.com/newestuser/github-workflow-dashboard/formatter"
 	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/snapshot"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/newestuser/github-workflow-dashboard/store"
 	"golang.org/x/oauth2"
 )
 
@@ -28,16 +35,75 @@ type options struct {
 	owners             stringArray
 	repos              stringArray
 	latestOnly         bool
+	latestGroupBy      string
+	latestCompleted    bool
 	limit              int
 	parseParams        bool
+	parseDefinitions   bool
 	formatMod          string
 	serverMod          bool
 	serverPort         int
 	serverPollInterval int
 	workflows          [][]string
+	history            int
+	timezone           string
+
+	discoverOwners          stringArray
+	discoverTopics          stringArray
+	discoverName            string
+	discoverVisibility      string
+	discoverArchived        bool
+	discoverWorkflow        string
+	serverDiscoveryInterval int
+
+	recordDir string
+	replayDir string
+
+	storePath     string
+	retentionDays int
+	snapshotPath  string
+	configPath    string
+	adminToken    string
+
+	repoPollIntervals        stringArray
+	serverAdaptivePoll       bool
+	serverActivePollInterval int
+	serverMaxPollInterval    int
+	serverStartupJitter      int
+	serverPollTimeout        int
+	serverShutdownTimeout    int
+	serverPathPrefix         string
+
+	repoRunners bool
+	orgRunners  stringArray
+
+	workflowReport bool
+	staleDays      int
+	flakyReport    bool
+	deployments    stringArray
+
+	regressionReport        bool
+	regressionBaselineRuns  int
+	regressionRecentRuns    int
+	regressionThreshold     float64
+	regressionOutlierFactor float64
+
+	// set by commands that track all workflows of a repository if no workflows are passed
+	allWorkflowsByDefault bool
 }
 
 func (opts *options) isValid() (bool, string) {
+	if len(opts.discoverOwners) > 0 {
+		if isValid, msg := opts.isDiscoveryValid(); !isValid {
+			return false, msg
+		}
+	}
+
+	// repositories can be tracked solely through discovery or the config file of the server
+	if (len(opts.discoverOwners) > 0 || (opts.serverMod && opts.configPath != "")) && len(opts.owners) == 0 && len(opts.repos) == 0 && len(opts.workflows) == 0 {
+		return opts.isCommonValid()
+	}
+
 	if len(opts.owners) == 0 {
 		return false, "provide at least one owner"
 	}
@@ -46,6 +112,9 @@ func (opts *options) isValid() (bool, string) {
 		if owner == "" {
 			return false, "owner can't be empty"
 		}
+		if owner == config.ReservedOwner {
+			return false, fmt.Sprintf("owner %s is reserved by the dashboard", config.ReservedOwner)
+		}
 	}
 
 	if len(opts.repos) == 0 {
@@ -58,23 +127,97 @@ func (opts *options) isValid() (bool, string) {
 		}
 	}
 
-	if len(opts.workflows) == 0 {
+	if len(opts.workflows) == 0 && !opts.allWorkflowsByDefault {
 		return false, "provide at least one workflow"
 	}
 
-	if len(opts.owners) != len(opts.repos) || len(opts.owners) != len(opts.workflows) {
+	if len(opts.owners) != len(opts.repos) || (len(opts.workflows) > 0 && len(opts.owners) != len(opts.workflows)) {
 		return false, fmt.Sprintf("number of owners, repos and set of workflows do not match, owners=%d, repos=%d, workflow sets=%d",
 			len(opts.owners), len(opts.repos), len(opts.owners))
 	}
 
+	return opts.isCommonValid()
+}
+
+func (opts *options) isCommonValid() (bool, string) {
 	if opts.limit < 0 {
 		return false, fmt.Sprintf("limit must be >= 0, limit=%d", opts.limit)
 	}
 
-	if opts.limit > 1 && opts.latestOnly {
+	latestOpts, err := opts.GetLatestOptions()
+	if err != nil {
+		return false, err.Error()
+	}
+
+	if !opts.latestOnly && (opts.latestCompleted || latestOpts.GroupBy != github.GroupByWorkflow) {
+		return false, "latest-group-by and latest-completed can only be used together with latest-only"
+	}
+
+	// the latest run of each group can be older than the latest run of the workflow, so more runs have to be fetched
+	if opts.limit > 1 && opts.latestOnly && latestOpts.NeedsSingleRun() {
 		return false, fmt.Sprintf("can't have both limit > 1 and fetch latest-only, limit=%d", opts.limit)
 	}
 
+	if opts.history < 0 {
+		return false, fmt.Sprintf("history must be >= 0, history=%d", opts.history)
+	}
+	if opts.history > 0 && opts.latestOnly {
+		return false, "history can't be used together with latest-only"
+	}
+
+	if _, err := time.LoadLocation(opts.timezone); err != nil {
+		return false, fmt.Sprintf("unknown timezone=%s, err: %s", opts.timezone, err)
+	}
+
+	if opts.retentionDays < 0 {
+		return false, fmt.Sprintf("retention-days must be >= 0, retention-days=%d", opts.retentionDays)
+	}
+
+	if opts.adminToken != "" && opts.configPath == "" {
+		return false, "admin-token requires a config file in which the tracked repositories are persisted"
+	}
+
+	if _, err := opts.getRepoPollIntervals(); err != nil {
+		return false, err.Error()
+	}
+	if time.Duration(opts.serverActivePollInterval)*time.Second < config.MinPollInterval {
+		return false, fmt.Sprintf("server-active-poll-interval must be >= %d, server-active-poll-interval=%d",
+			int(config.MinPollInterval.Seconds()), opts.serverActivePollInterval)
+	}
+	if opts.serverMaxPollInterval <= 0 || opts.serverStartupJitter < 0 {
+		return false, fmt.Sprintf("server-max-poll-interval must be > 0 and server-startup-jitter >= 0, server-max-poll-interval=%d, server-startup-jitter=%d",
+			opts.serverMaxPollInterval, opts.serverStartupJitter)
+	}
+
+	if opts.serverPollTimeout < 0 || opts.serverShutdownTimeout < 0 {
+		return false, fmt.Sprintf("server-poll-timeout and server-shutdown-timeout must be >= 0, server-poll-timeout=%d, server-shutdown-timeout=%d",
+			opts.serverPollTimeout, opts.serverShutdownTimeout)
+	}
+	if opts.serverPathPrefix != "" && (!strings.HasPrefix(opts.serverPathPrefix, "/") || strings.HasSuffix(opts.serverPathPrefix, "/")) {
+		return false, fmt.Sprintf("server-path-prefix must start and can't end with a slash, server-path-prefix=%s", opts.serverPathPrefix)
+	}
+
+	if opts.staleDays <= 0 {
+		return false, fmt.Sprintf("stale-days must be > 0, stale-days=%d", opts.staleDays)
+	}
+
+	if _, err := opts.getDeploymentSelectors(); err != nil {
+		return false, err.Error()
+	}
+
+	if opts.regressionBaselineRuns < 1 || opts.regressionRecentRuns < 1 {
+		return false, fmt.Sprintf("regression-baseline-runs and regression-recent-runs must be > 0, regression-baseline-runs=%d, regression-recent-runs=%d",
+			opts.regressionBaselineRuns, opts.regressionRecentRuns)
+	}
+	if opts.regressionThreshold <= 1 || opts.regressionOutlierFactor <= 1 {
+		return false, fmt.Sprintf("regression-threshold and regression-outlier-factor must be > 1, regression-threshold=%.2f, regression-outlier-factor=%.2f",
+			opts.regressionThreshold, opts.regressionOutlierFactor)
+	}
+
+	if opts.recordDir != "" && opts.replayDir != "" {
+		return false, "can't both record and replay github API traffic"
+	}
+
 	if opts.formatMod != "ascii" && opts.formatMod != "json" {
 		return false, fmt.Sprintf(`format "%s" not supported`, opts.formatMod)
 	}
@@ -82,19 +225,151 @@ func (opts *options) isValid() (bool, string) {
 	return true, ""
 }
 
+func (opts *options) isDiscoveryValid() (bool, string) {
+	for _, filter := range opts.newDiscoveryFiltersOrNil() {
+		if err := filter.Validate(); err != nil {
+			return false, err.Error()
+		}
+	}
+
+	if _, err := regexp.Compile(opts.discoverName); err != nil {
+		return false, fmt.Sprintf("discover-name is not a valid regular expression, err: %s", err)
+	}
+
+	if _, err := regexp.Compile(opts.discoverWorkflow); err != nil {
+		return false, fmt.Sprintf("discover-workflow is not a valid regular expression, err: %s", err)
+	}
+
+	if opts.serverDiscoveryInterval <= 0 {
+		return false, fmt.Sprintf("server-discovery-interval must be > 0, server-discovery-interval=%d", opts.serverDiscoveryInterval)
+	}
+
+	return true, ""
+}
+
 func (opts *options) GetLimit() int {
 	if opts.latestOnly {
-		return 1
+		if latestOpts, _ := opts.GetLatestOptions(); latestOpts.NeedsSingleRun() {
+			return 1
+		}
 	}
 
 	return opts.limit
 }
 
+func (opts *options) GetLatestOptions() (github.LatestOptions, error) {
+	groupBy, err := github.ParseGroupBy(opts.latestGroupBy)
+	if err != nil {
+		return github.LatestOptions{}, err
+	}
+
+	return github.LatestOptions{GroupBy: groupBy, CompletedOnly: opts.latestCompleted}, nil
+}
+
+func (opts *options) getDeploymentSelectors() ([]*stats.DeploymentSelector, error) {
+	selectors := make([]*stats.DeploymentSelector, 0)
+	for _, deployment := range opts.deployments {
+		selector, err := stats.ParseDeploymentSelector(deployment)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// The poll intervals of the repositories by owner/repo, passed as <owner>/<repo>=<duration>
+func (opts *options) getRepoPollIntervals() (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration)
+	for _, repoInterval := range opts.repoPollIntervals {
+		repoAndInterval := strings.SplitN(repoInterval, "=", 2)
+		if len(repoAndInterval) != 2 || strings.Count(repoAndInterval[0], "/") != 1 {
+			return nil, fmt.Errorf("invalid repo poll interval '%s', expected <owner>/<repo>=<duration>", repoInterval)
+		}
+		interval, err := config.ParsePollInterval(repoAndInterval[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid repo poll interval '%s', err: %s", repoInterval, err)
+		}
+		intervals[repoAndInterval[0]] = interval
+	}
+	return intervals, nil
+}
+
+func (opts *options) getRegressionOptions() stats.RegressionOptions {
+	return stats.RegressionOptions{
+		BaselineRuns:  opts.regressionBaselineRuns,
+		RecentRuns:    opts.regressionRecentRuns,
+		Threshold:     opts.regressionThreshold,
+		OutlierFactor: opts.regressionOutlierFactor,
+	}
+}
+
+// The location of the timezone, UTC if unknown since the timezone is validated by isCommonValid
+func (opts *options) getTimezone() *time.Location {
+	location, err := time.LoadLocation(opts.timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func (opts *options) getStaleAfter() time.Duration {
+	return time.Duration(opts.staleDays) * 24 * time.Hour
+}
+
 func main() {
-	fs := flag.NewFlagSet(ClientName, flag.ExitOnError)
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			if err := cmd.run(cmd, os.Args[2:]); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+	}
+
+	fs, opts := newFlagSet(ClientName)
 
 	version := fs.Bool("version", false, "Print version and exit")
 
+	fs.Usage = func() {
+		fmt.Printf("Usage: %s [global flags] '<workflow>'\n", ClientName)
+		fmt.Printf("       %s <command> [global flags] '<workflow>'\n", ClientName)
+		fmt.Printf("\ncommands:\n")
+		printCommands()
+		fmt.Printf("\nglobal flags:\n")
+		fs.PrintDefaults()
+		fmt.Print(example)
+	}
+
+	parseOptions(fs, opts, os.Args[1:])
+
+	if *version {
+		fmt.Printf("Version: %s\n", Version)
+		return
+	}
+
+	if isValid, msg := opts.isValid(); !isValid {
+		fmt.Printf("error: %s\n\n", msg)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var err error = nil
+	if opts.serverMod {
+		err = executeAsServer(opts)
+	} else {
+		err = executeAsCmd(opts)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// Creates a flag set with all global flags registered, the values of the flags are set in the returned options once parsed
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
 	opts := &options{
 		workflows: [][]string{},
 		owners:    stringArray{},
@@ -105,22 +380,57 @@ func main() {
 	fs.Var(&opts.owners, "owner", "Github repository owner")
 	fs.Var(&opts.repos, "repo", "Github repository")
 	fs.BoolVar(&opts.latestOnly, "latest-only", getBoolEnvOr("WORKFLOW_LATEST_ONLY", false), "Fetch only the latest run of the github workflow")
+	fs.StringVar(&opts.latestGroupBy, "latest-group-by", getStrEnvOr("WORKFLOW_LATEST_GROUP_BY", string(github.GroupByWorkflow)), "Keep the latest run per workflow, per workflow and branch or per workflow and event (workflow, branch, event)")
+	fs.BoolVar(&opts.latestCompleted, "latest-completed", getBoolEnvOr("WORKFLOW_LATEST_COMPLETED", false), "Keep the latest completed run and show queued or in progress runs as currently running")
 	fs.IntVar(&opts.limit, "limit", getIntEnvOr("WORKFLOW_LIMIT", 0), "Max number of runs to be fetched for each workflow (0 means fetch all)")
 	fs.BoolVar(&opts.parseParams, "parse-params", getBoolEnvOr("WORKFLOW_PARSE_PARAMS", false), "Parse workflow run params from log files")
+	fs.BoolVar(&opts.parseDefinitions, "parse-definitions", getBoolEnvOr("WORKFLOW_PARSE_DEFINITIONS", false), "Parse workflow files to show triggers and missed scheduled runs in server-mod")
+	fs.IntVar(&opts.history, "history", getIntEnvOr("WORKFLOW_HISTORY", 0), "Print a row per workflow and branch with sparklines of the conclusions and durations of the last N runs (0 means a row per run)")
+	fs.StringVar(&opts.timezone, "timezone", getStrEnvOr("WORKFLOW_TIMEZONE", "UTC"), "Timezone of the hours and weekdays of the run activity heatmap, e.g. Europe/Berlin or Local")
 	fs.StringVar(&opts.formatMod, "format", getStrEnvOr("WORKFLOW_FORMAT", "ascii"), "The format in which to print the workflow stats (ascii, json)")
 	fs.BoolVar(&opts.serverMod, "server-mod", getBoolEnvOr("WORKFLOW_SERVER_MOD", false), "Start a web server that periodically pulls github workflow stats")
 	fs.IntVar(&opts.serverPort, "server-port", getIntEnvOr("WORKFLOW_SERVER_PORT", 8080), "The port on which to start the web server if running in server-mod")
 	fs.IntVar(&opts.serverPollInterval, "server-poll-interval", getIntEnvOr("WORKFLOW_SERVER_POLL_INTERVAL", 5), "Interval in minutes used to poll github workflows")
+	fs.Var(&opts.repoPollIntervals, "repo-poll-interval", "Interval in which a repository is polled in server-mod instead of the server poll interval, <owner>/<repo>=<duration>, e.g. foo/bar=30s")
+	fs.BoolVar(&opts.serverAdaptivePoll, "server-adaptive-poll", getBoolEnvOr("WORKFLOW_SERVER_ADAPTIVE_POLL", false), "Poll repositories with queued or in progress runs more often and back off on repositories without new runs")
+	fs.IntVar(&opts.serverActivePollInterval, "server-active-poll-interval", getIntEnvOr("WORKFLOW_SERVER_ACTIVE_POLL_INTERVAL", 30), "Interval in seconds used to poll repositories with queued or in progress runs with adaptive polling")
+	fs.IntVar(&opts.serverMaxPollInterval, "server-max-poll-interval", getIntEnvOr("WORKFLOW_SERVER_MAX_POLL_INTERVAL", 30), "Interval in minutes up to which adaptive polling backs off on repositories without new runs")
+	fs.IntVar(&opts.serverStartupJitter, "server-startup-jitter", getIntEnvOr("WORKFLOW_SERVER_STARTUP_JITTER", 10), "Max delay in seconds of the first poll of a repository, spreads the polls of many repositories")
+	fs.IntVar(&opts.serverPollTimeout, "server-poll-timeout", getIntEnvOr("WORKFLOW_SERVER_POLL_TIMEOUT", 600), "Timeout in seconds of fetching a repository or a report in server-mod (0 means no timeout)")
+	fs.IntVar(&opts.serverShutdownTimeout, "server-shutdown-timeout", getIntEnvOr("WORKFLOW_SERVER_SHUTDOWN_TIMEOUT", 10), "Time in seconds the web server waits for in flight requests when stopped by SIGINT or SIGTERM (0 means no timeout)")
+	fs.StringVar(&opts.serverPathPrefix, "server-path-prefix", getStrEnv("WORKFLOW_SERVER_PATH_PREFIX"), "Path under which the web server serves the dashboard, e.g. /ci behind a reverse proxy")
+	fs.BoolVar(&opts.repoRunners, "runners", getBoolEnvOr("WORKFLOW_RUNNERS", false), "Track the self-hosted runners of every tracked repository in server-mod")
+	fs.Var(&opts.orgRunners, "runners-org", "Organization whose self-hosted runners are tracked in server-mod")
+	fs.BoolVar(&opts.workflowReport, "workflow-report", getBoolEnvOr("WORKFLOW_REPORT", false), "Report the state and last run of all workflows of every tracked repository in server-mod")
+	fs.IntVar(&opts.staleDays, "stale-days", getIntEnvOr("WORKFLOW_STALE_DAYS", 30), "Number of days without a run after which a workflow is reported as stale")
+	fs.BoolVar(&opts.flakyReport, "flaky-report", getBoolEnvOr("WORKFLOW_FLAKY_REPORT", false), "Report flaky workflows and jobs of the tracked repositories in server-mod")
+	fs.Var(&opts.deployments, "deployment", "Workflow whose runs are deployments used for delivery metrics, <owner>/<repo>/<workflow>[@<branch>][#<param>=<value>]")
+	fs.BoolVar(&opts.regressionReport, "regression-report", getBoolEnvOr("WORKFLOW_REGRESSION_REPORT", false), "Report duration regressions of the tracked workflows and jobs in server-mod")
+	fs.IntVar(&opts.regressionBaselineRuns, "regression-baseline-runs", getIntEnvOr("WORKFLOW_REGRESSION_BASELINE_RUNS", stats.DefaultBaselineRuns), "Max number of successful runs before the recent ones whose median duration is the baseline")
+	fs.IntVar(&opts.regressionRecentRuns, "regression-recent-runs", getIntEnvOr("WORKFLOW_REGRESSION_RECENT_RUNS", stats.DefaultRecentRuns), "Number of the latest successful runs whose median duration is compared with the baseline")
+	fs.Float64Var(&opts.regressionThreshold, "regression-threshold", getFloatEnvOr("WORKFLOW_REGRESSION_THRESHOLD", stats.DefaultRegressionThreshold), "Ratio of the recent and the baseline median duration from which on a workflow or job regressed")
+	fs.Float64Var(&opts.regressionOutlierFactor, "regression-outlier-factor", getFloatEnvOr("WORKFLOW_REGRESSION_OUTLIER_FACTOR", stats.DefaultOutlierFactor), "Recent runs taking this many times the baseline median duration are reported as anomalies")
+	fs.StringVar(&opts.recordDir, "record", getStrEnv("WORKFLOW_RECORD"), "Record every github API request/response to the given directory")
+	fs.StringVar(&opts.replayDir, "replay", getStrEnv("WORKFLOW_REPLAY"), "Serve github API responses recorded with -record from the given directory instead of the network")
+	fs.StringVar(&opts.storePath, "store", getStrEnv("WORKFLOW_STORE"), "File in which the fetched runs are persisted in server-mod, only new runs are fetched and the history survives restarts")
+	fs.StringVar(&opts.snapshotPath, "snapshot", getStrEnv("WORKFLOW_SNAPSHOT"), "Snapshot file written by the export command whose state is served in server-mod until the repositories are polled")
+	fs.StringVar(&opts.configPath, "config", getStrEnv("WORKFLOW_CONFIG"), "File in which the repositories tracked in server-mod are persisted when changed at runtime, replaces the passed repositories once it exists")
+	fs.StringVar(&opts.adminToken, "admin-token", getStrEnv("WORKFLOW_ADMIN_TOKEN"), "Token of the admin API and page managing the tracked repositories in server-mod, both are disabled if empty. Also required by the refresh API once set")
+	fs.IntVar(&opts.retentionDays, "retention-days", getIntEnvOr("WORKFLOW_RETENTION_DAYS", 90), "Number of days the runs are kept in the store (0 means forever)")
+	fs.Var(&opts.discoverOwners, "discover-owner", "Github user or organization whose repositories should all be tracked")
+	fs.Var(&opts.discoverTopics, "discover-topic", "Track only discovered repositories having at least one of the topics")
+	fs.StringVar(&opts.discoverName, "discover-name", getStrEnv("WORKFLOW_DISCOVER_NAME"), "Regular expression that the name of discovered repositories must match")
+	fs.StringVar(&opts.discoverVisibility, "discover-visibility", getStrEnvOr("WORKFLOW_DISCOVER_VISIBILITY", github.VisibilityAll), "Visibility of discovered repositories (all, public, private)")
+	fs.BoolVar(&opts.discoverArchived, "discover-archived", getBoolEnvOr("WORKFLOW_DISCOVER_ARCHIVED", false), "Track archived repositories as well when discovering repositories")
+	fs.StringVar(&opts.discoverWorkflow, "discover-workflow", getStrEnv("WORKFLOW_DISCOVER_WORKFLOW"), "Regular expression that the name of workflows in discovered repositories must match")
+	fs.IntVar(&opts.serverDiscoveryInterval, "server-discovery-interval", getIntEnvOr("WORKFLOW_SERVER_DISCOVERY_INTERVAL", 60), "Interval in minutes used to rediscover repositories")
+
+	return fs, opts
+}
 
-	fs.Usage = func() {
-		fmt.Printf("Usage: %s [global flags] '<workflow>'\n", ClientName)
-		fmt.Printf("\nglobal flags:\n")
-		fs.PrintDefaults()
-		fmt.Print(example)
-	}
-
-	cmdArgs := os.Args[1:]
-	if err := fs.Parse(cmdArgs); err != nil {
+// Parses the args and falls back to environment variables for list flags that were not passed
+func parseOptions(fs *flag.FlagSet, opts *options, args []string) {
+	if err := fs.Parse(args); err != nil {
 		log.Fatal(err)
 	}
 
@@ -130,6 +440,21 @@ func main() {
 	if !isFlagPassed(fs, "repo") {
 		opts.repos = getStrArrayEnv("WORKFLOW_REPO")
 	}
+	if !isFlagPassed(fs, "runners-org") {
+		opts.orgRunners = getStrArrayEnv("WORKFLOW_RUNNERS_ORG")
+	}
+	if !isFlagPassed(fs, "deployment") {
+		opts.deployments = getStrArrayEnv("WORKFLOW_DEPLOYMENT")
+	}
+	if !isFlagPassed(fs, "discover-owner") {
+		opts.discoverOwners = getStrArrayEnv("WORKFLOW_DISCOVER_OWNER")
+	}
+	if !isFlagPassed(fs, "discover-topic") {
+		opts.discoverTopics = getStrArrayEnv("WORKFLOW_DISCOVER_TOPIC")
+	}
+	if !isFlagPassed(fs, "repo-poll-interval") {
+		opts.repoPollIntervals = getStrArrayEnv("WORKFLOW_REPO_POLL_INTERVAL")
+	}
 
 	cliArgs := fs.Args()
 	if len(cliArgs) == 0 {
@@ -137,54 +462,90 @@ func main() {
 	} else {
 		opts.workflows = parseWorkflowCliArgs(cliArgs)
 	}
-
-	if *version {
-		fmt.Printf("Version: %s\n", Version)
-		return
-	}
-
-	if isValid, msg := opts.isValid(); !isValid {
-		fmt.Printf("error: %s\n\n", msg)
-		fs.Usage()
-		os.Exit(1)
-	}
-
-	var err error = nil
-	if opts.serverMod {
-		err = executeAsServer(opts)
-	} else {
-		err = executeAsCmd(opts)
-	}
-
-	if err != nil {
-		log.Fatalln(err)
-	}
 }
 
 func executeAsServer(opts *options) error {
 	filters := newWorkflowFilters(opts)
+	// invalid deployments, latest options and poll intervals are reported by isCommonValid
+	deployments, _ := opts.getDeploymentSelectors()
+	latestOpts, _ := opts.GetLatestOptions()
+	repoPollIntervals, _ := opts.getRepoPollIntervals()
 
 	srvOpts := &backend.Options{
-		Port:                opts.serverPort,
-		Filters:             filters,
-		PollInterval:        time.Duration(opts.serverPollInterval) * time.Minute,
-		LatestOnly:          opts.latestOnly,
-		ParseWorkflowParams: opts.parseParams,
+		Port:                     opts.serverPort,
+		Filters:                  filters,
+		PollInterval:             time.Duration(opts.serverPollInterval) * time.Minute,
+		LatestOnly:               opts.latestOnly,
+		ParseWorkflowParams:      opts.parseParams,
+		ParseWorkflowDefinitions: opts.parseDefinitions,
+		Discovery:                opts.newDiscoveryFiltersOrNil(),
+		DiscoveryInterval:        time.Duration(opts.serverDiscoveryInterval) * time.Minute,
+		RepoRunners:              opts.repoRunners,
+		OrgRunners:               opts.orgRunners,
+		WorkflowReport:           opts.workflowReport,
+		StaleAfter:               opts.getStaleAfter(),
+		FlakyReport:              opts.flakyReport,
+		Deployments:              deployments,
+		RegressionReport:         opts.regressionReport,
+		Regression:               opts.getRegressionOptions(),
+		History:                  opts.history,
+		Timezone:                 opts.getTimezone(),
+		Retention:                time.Duration(opts.retentionDays) * 24 * time.Hour,
+		ConfigFile:               opts.configPath,
+		AdminToken:               opts.adminToken,
+		Limit:                    opts.GetLimit(),
+		Latest:                   latestOpts,
+		RepoPollIntervals:        repoPollIntervals,
+		AdaptivePolling:          opts.serverAdaptivePoll,
+		ActivePollInterval:       time.Duration(opts.serverActivePollInterval) * time.Second,
+		MaxPollInterval:          time.Duration(opts.serverMaxPollInterval) * time.Minute,
+		StartupJitter:            time.Duration(opts.serverStartupJitter) * time.Second,
+		PollTimeout:              time.Duration(opts.serverPollTimeout) * time.Second,
+		ShutdownTimeout:          time.Duration(opts.serverShutdownTimeout) * time.Second,
+		PathPrefix:               opts.serverPathPrefix,
+	}
+
+	client, rateLimits, err := newTrackedGithubClient(context.Background(), opts)
+	if err != nil {
+		return err
 	}
+	srvOpts.RateLimits = rateLimits
 
-	client := newGithubClient(context.Background(), opts)
+	if opts.snapshotPath != "" {
+		if srvOpts.Snapshot, err = snapshot.ReadFile(opts.snapshotPath); err != nil {
+			return err
+		}
+	}
+
+	if opts.storePath != "" {
+		runStore, err := store.Open(opts.storePath)
+		if err != nil {
+			return err
+		}
+		defer runStore.Close()
+		srvOpts.Store = runStore
+	}
 	server := backend.NewServer(client, srvOpts)
 
-	return server.Start()
+	// the store is closed once the server stopped polling
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.Run(ctx)
 }
 
 func executeAsCmd(opts *options) error {
 	ctx := context.Background()
-	client := newGithubClient(ctx, opts)
-	filters := newWorkflowFilters(opts)
+	client, err := newGithubClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+	discoveredFilters, err := discoverMultiple(ctx, client, opts.newDiscoveryFiltersOrNil())
+	if err != nil {
+		return err
+	}
+	filters := appendDiscoveredFilters(newWorkflowFilters(opts), discoveredFilters)
 
 	var workflowRuns []*github.WorkflowRun
-	var err error
 
 	if opts.latestOnly {
 		workflowRuns, err = fetchMultiple(ctx, filters, client.FetchLatestWorkflowRuns)
@@ -198,7 +559,7 @@ func executeAsCmd(opts *options) error {
 
 	if opts.parseParams {
 		for _, filter := range filters {
-			if err := client.EnrichWorkflowRunsWithParams(ctx, filter, workflowRuns); err != nil {
+			if err := client.EnrichWorkflowRunsWithParams(ctx, filter, filterRepoRuns(filter, workflowRuns)); err != nil {
 				return err
 			}
 		}
@@ -225,14 +586,73 @@ func fetchMultiple(ctx context.Context, filters []*github.WorkflowFilter, fetche
 	return allRuns, nil
 }
 
+func filterRepoRuns(filter *github.WorkflowFilter, runs []*github.WorkflowRun) []*github.WorkflowRun {
+	result := make([]*github.WorkflowRun, 0)
+	for _, run := range runs {
+		if run.WorkflowOwner == filter.Owner && run.WorkflowRepo == filter.Repo {
+			result = append(result, run)
+		}
+	}
+	return result
+}
+
+func discoverMultiple(ctx context.Context, client github.WorkflowClient, discoveryFilters []*github.DiscoveryFilter) ([]*github.WorkflowFilter, error) {
+	allFilters := make([]*github.WorkflowFilter, 0)
+	for _, discoveryFilter := range discoveryFilters {
+		filters, err := client.DiscoverRepositories(ctx, discoveryFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		allFilters = append(allFilters, filters...)
+	}
+	return allFilters, nil
+}
+
+// The explicit filters followed by the discovered ones, a repository that is tracked explicitly or discovered through
+// several owners is only fetched once
+func appendDiscoveredFilters(filters, discovered []*github.WorkflowFilter) []*github.WorkflowFilter {
+	seen := map[string]bool{}
+	for _, filter := range filters {
+		seen[filter.GetRepoId().String()] = true
+	}
+
+	for _, filter := range discovered {
+		if seen[filter.GetRepoId().String()] {
+			continue
+		}
+		seen[filter.GetRepoId().String()] = true
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
 func formatCmdOutput(runs []*github.WorkflowRun, opts *options) (string, error) {
 	if opts.formatMod == "json" {
 		return formatter.ToJson(runs)
 	}
+	if opts.history > 0 {
+		return formatter.ToAsciiHistory(runs, opts.history)
+	}
 	return formatter.ToAscii(runs)
 }
 
-func newGithubClient(ctx context.Context, opts *options) *github.WorkflowClient {
+func newGithubClient(ctx context.Context, opts *options) (github.WorkflowClient, error) {
+	client, _, err := newTrackedGithubClient(ctx, opts)
+	return client, err
+}
+
+// Creates a github client whose responses update the returned rate limit tracker
+func newTrackedGithubClient(ctx context.Context, opts *options) (github.WorkflowClient, *github.RateLimitTracker, error) {
+	if opts.replayDir != "" {
+		transport, err := github.NewReplayTransport(opts.replayDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		tracker := github.NewRateLimitTracker(transport)
+		return github.NewWorkflowClient(&http.Client{Transport: tracker}), tracker, nil
+	}
+
 	var client *http.Client = nil
 	if opts.token != "" {
 		ts := oauth2.StaticTokenSource(
@@ -240,26 +660,42 @@ func newGithubClient(ctx context.Context, opts *options) *github.WorkflowClient
 		)
 		client = oauth2.NewClient(ctx, ts)
 	} else {
-		client = nil
+		client = &http.Client{Transport: http.DefaultTransport}
 	}
 
-	return github.NewWorkflowClient(client)
+	if opts.recordDir != "" {
+		transport, err := github.NewRecordingTransport(opts.recordDir, client.Transport)
+		if err != nil {
+			return nil, nil, err
+		}
+		client.Transport = transport
+	}
+
+	tracker := github.NewRateLimitTracker(client.Transport)
+	client.Transport = tracker
+	return github.NewWorkflowClient(client), tracker, nil
 }
 
 func newWorkflowFilters(opts *options) []*github.WorkflowFilter {
 	filters := make([]*github.WorkflowFilter, 0)
+	// invalid options are reported by isValid
+	latestOpts, _ := opts.GetLatestOptions()
 
 	// repos, owners and workflows should have the same length
 	for i := range opts.repos {
 		owner := opts.owners[i]
 		repo := opts.repos[i]
-		workflows := opts.workflows[i]
+		workflows := []string{}
+		if i < len(opts.workflows) {
+			workflows = opts.workflows[i]
+		}
 
 		filter := &github.WorkflowFilter{
 			Owner:         owner,
 			Repo:          repo,
 			WorkflowNames: workflows,
 			Limit:         opts.GetLimit(),
+			Latest:        latestOpts,
 		}
 
 		filters = append(filters, filter)
@@ -268,6 +704,38 @@ func newWorkflowFilters(opts *options) []*github.WorkflowFilter {
 	return filters
 }
 
+func (opts *options) newDiscoveryFiltersOrNil() []*github.DiscoveryFilter {
+	if len(opts.discoverOwners) == 0 {
+		return nil
+	}
+
+	// invalid patterns are reported by isDiscoveryValid
+	var namePattern, workflowPattern *regexp.Regexp
+	if opts.discoverName != "" {
+		namePattern, _ = regexp.Compile(opts.discoverName)
+	}
+	if opts.discoverWorkflow != "" {
+		workflowPattern, _ = regexp.Compile(opts.discoverWorkflow)
+	}
+
+	latestOpts, _ := opts.GetLatestOptions()
+	filters := make([]*github.DiscoveryFilter, 0)
+	for _, owner := range opts.discoverOwners {
+		filters = append(filters, &github.DiscoveryFilter{
+			Owner:           owner,
+			Topics:          opts.discoverTopics,
+			NamePattern:     namePattern,
+			Visibility:      opts.discoverVisibility,
+			IncludeArchived: opts.discoverArchived,
+			WorkflowPattern: workflowPattern,
+			Limit:           opts.GetLimit(),
+			Latest:          latestOpts,
+		})
+	}
+
+	return filters
+}
+
 // Parse an environment variable strings that has the format: "foo,bar,zar;far,mar,gar"
 func loadEnvVarWorkflows() [][]string {
 	allWorkflowCsvs := os.Getenv("WORKFLOW_CSV")
@@ -361,6 +829,21 @@ func getIntEnvOr(name string, other int) int {
 	return intValue
 }
 
+func getFloatEnvOr(name string, other float64) float64 {
+	value := os.Getenv(name)
+
+	if value == "" {
+		return other
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("environment variable %s=%s can't be parsed to float", name, value)
+	}
+
+	return floatValue
+}
+
 func getBoolEnvOr(name string, other bool) bool {
 	value := os.Getenv(name)
 
@@ -0,0 +1,146 @@
+// Package config persists the repositories tracked by a server as a json file so that they can be changed at runtime
+// without restarting the server.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Repositories can't be polled more often, so that a single repository can't use up the rate limit of the github API
+const MinPollInterval = 10 * time.Second
+
+// The dashboard serves the pages and APIs that aren't about an owner under this name, so it can't be tracked as an owner
+const ReservedOwner = "_"
+
+// Owners and repositories consist of the characters github allows in their names
+var nameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type Config struct {
+	Repositories []*Repository `json:"repositories"`
+}
+
+// A tracked repository and its tracked workflows, all workflows are tracked if Workflows is empty
+type Repository struct {
+	Owner     string   `json:"owner"`
+	Repo      string   `json:"repo"`
+	Workflows []string `json:"workflows,omitempty"`
+	// Interval in which the repository is polled as a duration, e.g. 30s, the poll interval of the server is used if empty
+	PollInterval string `json:"pollInterval,omitempty"`
+}
+
+// The poll interval of the repository, zero if the poll interval of the server is used
+func (r *Repository) GetPollInterval() (time.Duration, error) {
+	if r.PollInterval == "" {
+		return 0, nil
+	}
+	return ParsePollInterval(r.PollInterval)
+}
+
+func (r *Repository) Validate() error {
+	if !nameRegex.MatchString(r.Owner) || !nameRegex.MatchString(r.Repo) {
+		return fmt.Errorf("invalid repository owner=%s, repo=%s", r.Owner, r.Repo)
+	}
+	if r.Owner == ReservedOwner {
+		return fmt.Errorf("owner %s is reserved by the dashboard", ReservedOwner)
+	}
+	for _, workflow := range r.Workflows {
+		if workflow == "" {
+			return fmt.Errorf("workflow names of repository %s/%s can't be empty", r.Owner, r.Repo)
+		}
+	}
+	if _, err := r.GetPollInterval(); err != nil {
+		return fmt.Errorf("invalid poll interval of repository %s/%s, err: %s", r.Owner, r.Repo, err)
+	}
+	return nil
+}
+
+// Parses a duration of at least MinPollInterval, e.g. 30s or 2m
+func ParsePollInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval < MinPollInterval {
+		return 0, fmt.Errorf("poll interval must be >= %s, poll interval=%s", MinPollInterval, value)
+	}
+	return interval, nil
+}
+
+func (c *Config) Validate() error {
+	seen := map[string]bool{}
+	for _, repo := range c.Repositories {
+		if err := repo.Validate(); err != nil {
+			return err
+		}
+
+		id := fmt.Sprintf("%s/%s", repo.Owner, repo.Repo)
+		if seen[id] {
+			return fmt.Errorf("repository %s is configured twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Sorts the repositories by owner and name
+func (c *Config) Sort() {
+	sort.Slice(c.Repositories, func(i, j int) bool {
+		if c.Repositories[i].Owner != c.Repositories[j].Owner {
+			return c.Repositories[i].Owner < c.Repositories[j].Owner
+		}
+		return c.Repositories[i].Repo < c.Repositories[j].Repo
+	})
+}
+
+// Reads the config written by Save, nil if the file doesn't exist
+func Load(path string) (*Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config file '%s' failed, err: %s", path, err)
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(bytes, config); err != nil {
+		return nil, fmt.Errorf("parsing config file '%s' failed, err: %s", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file '%s', err: %s", path, err)
+	}
+	return config, nil
+}
+
+// Writes the config to a temporary file that replaces the file, so that a failed write never leaves a partial config
+func Save(path string, config *Config) error {
+	bytes, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing config file '%s' failed, err: %s", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(bytes, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing config file '%s' failed, err: %s", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing config file '%s' failed, err: %s", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing config file '%s' failed, err: %s", path, err)
+	}
+	return nil
+}
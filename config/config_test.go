@@ -0,0 +1,59 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repositories.json")
+
+	loaded, err := Load(path)
+	if err != nil || loaded != nil {
+		t.Fatalf("got config %+v and error %v for a missing file, wanted neither", loaded, err)
+	}
+
+	saved := &Config{Repositories: []*Repository{
+		{Owner: "foo", Repo: "bar", Workflows: []string{"build", "release"}, PollInterval: "30s"},
+		{Owner: "foo", Repo: "baz"},
+	}}
+	if err := Save(path, saved); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	loaded, err = Load(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if !reflect.DeepEqual(saved, loaded) {
+		t.Errorf("got %+v, wanted the saved config", loaded)
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("got %d files, wanted only the config without temporary files", len(files))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []*Config{
+		{Repositories: []*Repository{{Owner: "foo"}}},
+		{Repositories: []*Repository{{Owner: "foo", Repo: "bar/baz"}}},
+		{Repositories: []*Repository{{Owner: ReservedOwner, Repo: "bar"}}},
+		{Repositories: []*Repository{{Owner: "foo", Repo: "bar", Workflows: []string{""}}}},
+		{Repositories: []*Repository{{Owner: "foo", Repo: "bar"}, {Owner: "foo", Repo: "bar"}}},
+		{Repositories: []*Repository{{Owner: "foo", Repo: "bar", PollInterval: "5s"}}},
+		{Repositories: []*Repository{{Owner: "foo", Repo: "bar", PollInterval: "often"}}},
+	}
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("got no error for %+v", config.Repositories)
+		}
+	}
+
+	if err := (&Config{Repositories: []*Repository{{Owner: "foo", Repo: "bar.js", Workflows: []string{"Build and test"}}}}).Validate(); err != nil {
+		t.Errorf("got error: %s", err)
+	}
+}
@@ -15,16 +15,28 @@ func ToAscii(runs []*github.WorkflowRun) (string, error) {
 
 	header := []string{"workflow", "#", "status", "branch", "commiter", "commit msg", "commit", "commit time", "run time"}
 
+	if containsActiveRuns(runs) {
+		header = append(header, "running")
+	}
+
 	if containsParams(runs) {
 		header = append(header, "params")
 	}
 
+	streaks := failingStreaksByLatestRun(runs)
+	if len(streaks) > 0 {
+		header = append(header, "broken since")
+	}
+
 	table.SetHeader(header)
 	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
 	table.SetCenterSeparator("|")
 
 	for _, worfklowRun := range runs {
-		row := mapAsciiRow(worfklowRun, containsParams(runs))
+		row := mapAsciiRow(worfklowRun, containsActiveRuns(runs), containsParams(runs))
+		if len(streaks) > 0 {
+			row = append(row, brokenSince(worfklowRun, streaks))
+		}
 		table.Append(row)
 	}
 	table.Render()
@@ -32,7 +44,7 @@ func ToAscii(runs []*github.WorkflowRun) (string, error) {
 	return output.String(), nil
 }
 
-func mapAsciiRow(run *github.WorkflowRun, includeParams bool) []string {
+func mapAsciiRow(run *github.WorkflowRun, includeActiveRun bool, includeParams bool) []string {
 
 	var commitSha = run.JobCommitSha
 	if len(run.JobCommitSha) > 10 {
@@ -50,6 +62,14 @@ func mapAsciiRow(run *github.WorkflowRun, includeParams bool) []string {
 		run.JobCommitTime.UTC().String(),
 		run.JobRunTime.UTC().String()}
 
+	if includeActiveRun {
+		if run.ActiveRun != nil {
+			asciRow = append(asciRow, fmt.Sprintf("#%d %s", run.ActiveRun.JobRunNumber, run.ActiveRun.JobStatus))
+		} else {
+			asciRow = append(asciRow, "")
+		}
+	}
+
 	if includeParams {
 		if run.WorkflowParams != nil {
 			asciRow = append(asciRow, mapAsciiParams(run.WorkflowParams.Params))
@@ -61,6 +81,16 @@ func mapAsciiRow(run *github.WorkflowRun, includeParams bool) []string {
 	return asciRow
 }
 
+func containsActiveRuns(runs []*github.WorkflowRun) bool {
+	for _, run := range runs {
+		if run.ActiveRun != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
 func containsParams(runs []*github.WorkflowRun) bool {
 	for _, run := range runs {
 		if run.WorkflowParams != nil {
@@ -0,0 +1,121 @@
+package formatter
+
+import (
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+var definitionsHtmlTmpl = template.Must(template.New("definitionsTable").Parse(definitionsHtml))
+
+func DefinitionsToHTML(definitions []*github.WorkflowDefinition, now time.Time) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	models := make([]*definitionModel, len(definitions))
+	for i, definition := range definitions {
+		models[i] = adaptDefinitionModel(definition, now)
+	}
+
+	if err := definitionsHtmlTmpl.Execute(body, models); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type definitionModel struct {
+	WorkflowName        string
+	Path                string
+	State               string
+	Events              string
+	PushBranches        string
+	PullRequestBranches string
+	Schedules           string
+	DispatchInputs      []string
+	LastScheduledRun    string
+	MissedSchedule      bool
+	ExpectedRun         string
+}
+
+func adaptDefinitionModel(definition *github.WorkflowDefinition, now time.Time) *definitionModel {
+	inputs := make([]string, 0)
+	for _, input := range definition.DispatchInputs {
+		value := input.Name
+		if input.Required {
+			value += " (required)"
+		}
+		if input.Default != "" {
+			value += " = " + input.Default
+		}
+		inputs = append(inputs, value)
+	}
+
+	lastScheduledRun := ""
+	if definition.IsScheduled() {
+		lastScheduledRun = "never"
+		if !definition.LastScheduledRun.IsZero() {
+			lastScheduledRun = timeSince(definition.LastScheduledRun)
+		}
+	}
+
+	expectedRun := ""
+	missed := definition.MissedSchedule(now, github.DefaultScheduleDelay)
+	if missed {
+		expectedRun = timeSince(definition.ExpectedScheduledRun(now, github.DefaultScheduleDelay))
+	}
+
+	return &definitionModel{
+		WorkflowName:        definition.WorkflowName,
+		Path:                definition.Path,
+		State:               definition.State,
+		Events:              strings.Join(definition.Events, ", "),
+		PushBranches:        strings.Join(definition.PushBranches, ", "),
+		PullRequestBranches: strings.Join(definition.PullRequestBranches, ", "),
+		Schedules:           strings.Join(definition.Schedules, ", "),
+		DispatchInputs:      inputs,
+		LastScheduledRun:    lastScheduledRun,
+		MissedSchedule:      missed,
+		ExpectedRun:         expectedRun,
+	}
+}
+
+const definitionsHtml = `
+<table>
+	<thead>
+		<tr>
+			<th>Workflow</th>
+			<th>State</th>
+			<th>Triggers</th>
+			<th>Push Branches</th>
+			<th>PR Branches</th>
+			<th>Schedule</th>
+			<th>Last Scheduled Run</th>
+			<th>Dispatch Inputs</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .}}
+			<tr>
+				<td title="{{.Path}}">{{.WorkflowName}}</td>
+				<td>{{.State}}</td>
+				<td>{{.Events}}</td>
+				<td>{{.PushBranches}}</td>
+				<td>{{.PullRequestBranches}}</td>
+				<td><code>{{.Schedules}}</code></td>
+				{{if .MissedSchedule}}
+					<td><b>missed, last run {{.LastScheduledRun}}, expected {{.ExpectedRun}}</b></td>
+				{{else}}
+					<td>{{.LastScheduledRun}}</td>
+				{{end}}
+				<td>
+					{{range .DispatchInputs}}
+						{{.}}<br/>
+					{{end}}
+				</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+`
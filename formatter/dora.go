@@ -0,0 +1,118 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+var deliveryMetricsHtmlTmpl = template.Must(template.New("deliveryMetricsTable").Parse(deliveryMetricsHtml))
+
+func DeliveryMetricsToAscii(metrics []*stats.DeliveryMetrics) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	table.SetHeader([]string{"deployment", "from", "to", "deployments", "per day", "lead time median", "lead time p90",
+		"change failure rate", "mttr", "restores"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, model := range adaptDeliveryMetricsModels(metrics) {
+		table.Append([]string{model.Deployment, model.From, model.To, fmt.Sprintf("%d", model.Deployments), model.Frequency,
+			model.LeadTimeMedian, model.LeadTimeP90, model.ChangeFailureRate, model.MeanTimeToRestore, fmt.Sprintf("%d", model.Restores)})
+	}
+	table.Render()
+
+	return output.String(), nil
+}
+
+func DeliveryMetricsToJson(metrics []*stats.DeliveryMetrics) (string, error) {
+	bytes, err := json.Marshal(metrics)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func DeliveryMetricsToHTML(metrics []*stats.DeliveryMetrics) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	if err := deliveryMetricsHtmlTmpl.Execute(body, adaptDeliveryMetricsModels(metrics)); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type deliveryMetricsModel struct {
+	Deployment        string
+	From              string
+	To                string
+	Deployments       int
+	Frequency         string
+	LeadTimeMedian    string
+	LeadTimeP90       string
+	ChangeFailureRate string
+	MeanTimeToRestore string
+	Restores          int
+}
+
+func adaptDeliveryMetricsModels(metrics []*stats.DeliveryMetrics) []*deliveryMetricsModel {
+	result := make([]*deliveryMetricsModel, len(metrics))
+	for i, m := range metrics {
+		result[i] = &deliveryMetricsModel{
+			Deployment:        m.Deployment,
+			From:              m.From.UTC().Format(time.RFC3339),
+			To:                m.To.UTC().Format(time.RFC3339),
+			Deployments:       m.Deployments,
+			Frequency:         fmt.Sprintf("%.2f", m.DeploymentFrequency),
+			LeadTimeMedian:    formatDuration(m.LeadTime.Median, m.LeadTime.Count),
+			LeadTimeP90:       formatDuration(m.LeadTime.P90, m.LeadTime.Count),
+			ChangeFailureRate: formatRate(m.ChangeFailureRate, m.Deployments),
+			MeanTimeToRestore: formatDuration(m.TimeToRestore.Mean, m.TimeToRestore.Count),
+			Restores:          m.TimeToRestore.Count,
+		}
+	}
+	return result
+}
+
+const deliveryMetricsHtml = `
+{{if .}}
+<table>
+	<thead>
+		<tr>
+			<th>Deployment</th>
+			<th>From</th>
+			<th>To</th>
+			<th>Deployments</th>
+			<th>Per Day</th>
+			<th>Lead Time (median / p90)</th>
+			<th>Change Failure Rate</th>
+			<th>Time To Restore (mean)</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .}}
+			<tr>
+				<td>{{.Deployment}}</td>
+				<td>{{.From}}</td>
+				<td>{{.To}}</td>
+				<td>{{.Deployments}}</td>
+				<td><b>{{.Frequency}}</b></td>
+				<td><b>{{.LeadTimeMedian}}</b> / {{.LeadTimeP90}}</td>
+				<td><b>{{.ChangeFailureRate}}</b></td>
+				<td><b>{{.MeanTimeToRestore}}</b> ({{.Restores}} restores)</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+{{else}}
+<p>No deployments found.</p>
+{{end}}
+`
@@ -0,0 +1,50 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+func TestDeliveryMetricsToHTML(t *testing.T) {
+	from := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	metrics := []*stats.DeliveryMetrics{
+		{
+			Deployment:          "foo/bar/deploy@main",
+			From:                from,
+			To:                  from.Add(7 * 24 * time.Hour),
+			Deployments:         4,
+			DeploymentFrequency: 0.5,
+			LeadTime:            stats.DurationStats{Count: 3, Median: 90 * time.Minute, P90: 3 * time.Hour},
+			ChangeFailureRate:   0.25,
+			TimeToRestore:       stats.DurationStats{Count: 1, Mean: 30 * time.Minute},
+		},
+		{Deployment: "foo/bar/deploy@release", From: from, To: from},
+	}
+
+	output, err := DeliveryMetricsToHTML(metrics)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, want := range []string{
+		"<td>foo/bar/deploy@main</td>",
+		"<td>2022-03-01T00:00:00Z</td>",
+		"<td>2022-03-08T00:00:00Z</td>",
+		"<td><b>0.50</b></td>",
+		"<td><b>1h30m0s</b> / 3h0m0s</td>",
+		"<td><b>25.0%</b></td>",
+		"<td><b>30m0s</b> (1 restores)</td>",
+		// a window without deployments has no metrics
+		"<td><b>-</b> / -</td>",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("got metrics without %q:\n%s", want, output)
+		}
+	}
+
+	if output, err = DeliveryMetricsToHTML([]*stats.DeliveryMetrics{}); err != nil || !strings.Contains(string(output), "No deployments found.") {
+		t.Errorf("got %s with error %v, wanted no deployments", output, err)
+	}
+}
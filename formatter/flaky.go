@@ -0,0 +1,161 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+var flakinessHtmlTmpl = template.Must(template.New("flakinessTable").Parse(flakinessHtml))
+
+// max number of evidence links shown per workflow or job
+const maxFlakyEvidence = 5
+
+func FlakinessToAscii(reports []*stats.FlakinessReport) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	table.SetHeader([]string{"repository", "workflow", "job", "score", "flaky", "failures", "runs", "evidence"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+
+	for _, model := range adaptFlakinessModels(reports) {
+		evidence := &strings.Builder{}
+		for _, e := range model.Evidence {
+			evidence.WriteString(fmt.Sprintf("%s (%s) %s\n", e.Reason, e.CommitSha, e.URL))
+		}
+
+		table.Append([]string{
+			model.Repository,
+			model.WorkflowName,
+			model.JobName,
+			model.Score,
+			fmt.Sprintf("%d", model.FlakyFailures),
+			fmt.Sprintf("%d", model.Failures),
+			fmt.Sprintf("%d", model.Runs),
+			evidence.String(),
+		})
+	}
+	table.Render()
+
+	return output.String(), nil
+}
+
+func FlakinessToJson(reports []*stats.FlakinessReport) (string, error) {
+	bytes, err := json.Marshal(reports)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func FlakinessToHTML(reports []*stats.FlakinessReport) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	if err := flakinessHtmlTmpl.Execute(body, adaptFlakinessModels(reports)); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type flakinessModel struct {
+	Repository    string
+	WorkflowName  string
+	JobName       string
+	Score         string
+	FlakyFailures int
+	Failures      int
+	Runs          int
+	Evidence      []*flakyEvidenceModel
+}
+
+type flakyEvidenceModel struct {
+	Reason    string
+	CommitSha string
+	Branch    string
+	// The failing run or job, the passing one if the failure is unknown
+	URL       string
+	PassedURL string
+}
+
+func adaptFlakinessModels(reports []*stats.FlakinessReport) []*flakinessModel {
+	result := make([]*flakinessModel, len(reports))
+	for i, report := range reports {
+		evidence := make([]*flakyEvidenceModel, 0)
+		for _, e := range report.Evidence {
+			if len(evidence) == maxFlakyEvidence {
+				break
+			}
+
+			url := e.FailedURL
+			if url == "" {
+				url = e.PassedURL
+			}
+			evidence = append(evidence, &flakyEvidenceModel{
+				Reason:    e.Reason,
+				CommitSha: truncateStr(e.CommitSha, 10),
+				Branch:    e.Branch,
+				URL:       url,
+				PassedURL: e.PassedURL,
+			})
+		}
+
+		result[i] = &flakinessModel{
+			Repository:    fmt.Sprintf("%s/%s", report.WorkflowOwner, report.WorkflowRepo),
+			WorkflowName:  report.WorkflowName,
+			JobName:       report.JobName,
+			Score:         fmt.Sprintf("%.1f%%", report.Score*100),
+			FlakyFailures: report.FlakyFailures,
+			Failures:      report.Failures,
+			Runs:          report.Runs,
+			Evidence:      evidence,
+		}
+	}
+	return result
+}
+
+const flakinessHtml = `
+{{if .}}
+<table>
+	<thead>
+		<tr>
+			<th>Repository</th>
+			<th>Workflow</th>
+			<th>Job</th>
+			<th>Score</th>
+			<th>Flaky</th>
+			<th>Failures</th>
+			<th>Runs</th>
+			<th>Evidence</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .}}
+			<tr>
+				<td>{{.Repository}}</td>
+				<td>{{.WorkflowName}}</td>
+				<td>{{.JobName}}</td>
+				<td><b>{{.Score}}</b></td>
+				<td>{{.FlakyFailures}}</td>
+				<td>{{.Failures}}</td>
+				<td>{{.Runs}}</td>
+				<td>
+					{{range .Evidence}}
+						<a href="{{.URL}}">{{.Reason}}</a> on {{.Branch}} ({{.CommitSha}}), <a href="{{.PassedURL}}">passed</a><br/>
+					{{end}}
+				</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+{{else}}
+<p>No flaky workflows found.</p>
+{{end}}
+`
@@ -0,0 +1,196 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+// The values a heatmap can be colored by
+const (
+	HeatmapRuns        = "runs"
+	HeatmapFailureRate = "failure-rate"
+	HeatmapQueueTime   = "queue-time"
+)
+
+var HeatmapMetrics = []string{HeatmapRuns, HeatmapFailureRate, HeatmapQueueTime}
+
+var heatmapHtmlTmpl = template.Must(template.New("heatmapTable").Parse(heatmapHtml))
+
+// RGB color of the cells with the max value of each metric
+var heatmapColors = map[string]string{
+	HeatmapRuns:        "13, 110, 253",
+	HeatmapFailureRate: "220, 53, 69",
+	HeatmapQueueTime:   "253, 126, 20",
+}
+
+func IsHeatmapMetric(metric string) bool {
+	_, ok := heatmapColors[metric]
+	return ok
+}
+
+func HeatmapToAscii(heatmap *stats.Heatmap, metric string) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	header := []string{heatmap.Timezone}
+	for hour := 0; hour < 24; hour++ {
+		header = append(header, fmt.Sprintf("%02d", hour))
+	}
+	table.SetHeader(header)
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, row := range adaptHeatmapModel(heatmap, metric).Rows {
+		values := []string{row.Weekday}
+		for _, cell := range row.Cells {
+			values = append(values, cell.Value)
+		}
+		table.Append(values)
+	}
+	table.Render()
+
+	return fmt.Sprintf("%s from %s to %s, %d runs\n%s", metric, heatmap.From.UTC().Format(time.RFC3339),
+		heatmap.To.UTC().Format(time.RFC3339), heatmap.Runs, output.String()), nil
+}
+
+func HeatmapToJson(heatmap *stats.Heatmap) (string, error) {
+	bytes, err := json.Marshal(heatmap)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func HeatmapToHTML(heatmap *stats.Heatmap, metric string) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	if err := heatmapHtmlTmpl.Execute(body, adaptHeatmapModel(heatmap, metric)); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type heatmapModel struct {
+	Metric string
+	Hours  []string
+	Rows   []*heatmapRowModel
+}
+
+type heatmapRowModel struct {
+	Weekday string
+	Cells   []*heatmapCellModel
+}
+
+type heatmapCellModel struct {
+	Value string
+	Title string
+	Color template.CSS
+}
+
+func adaptHeatmapModel(heatmap *stats.Heatmap, metric string) *heatmapModel {
+	max := 0.0
+	for _, cell := range heatmap.Cells {
+		if value, ok := heatmapValue(cell, metric); ok && value > max {
+			max = value
+		}
+	}
+
+	result := &heatmapModel{Metric: metric, Hours: make([]string, 24), Rows: make([]*heatmapRowModel, 0)}
+	for hour := range result.Hours {
+		result.Hours[hour] = fmt.Sprintf("%02d", hour)
+	}
+
+	for _, weekday := range stats.HeatmapWeekdays {
+		row := &heatmapRowModel{Weekday: weekday.String()[:3], Cells: make([]*heatmapCellModel, 24)}
+		for hour := 0; hour < 24; hour++ {
+			cell := heatmap.Cell(weekday, hour)
+			model := &heatmapCellModel{
+				Value: formatHeatmapValue(cell, metric),
+				Title: fmt.Sprintf("%s %02d:00, %d runs, %s failed, queued %s (median)", weekday, hour, cell.Runs,
+					formatRate(cell.FailureRate, cell.Completed), formatDuration(cell.QueueTime.Median, cell.QueueTime.Count)),
+			}
+
+			alpha := 0.0
+			if value, ok := heatmapValue(cell, metric); ok && max > 0 {
+				alpha = value / max
+			}
+			model.Color = template.CSS(fmt.Sprintf("rgba(%s, %.2f)", heatmapColors[metric], alpha))
+
+			row.Cells[hour] = model
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result
+}
+
+// The value of the metric, false if the cell has none
+func heatmapValue(cell *stats.HeatmapCell, metric string) (float64, bool) {
+	switch metric {
+	case HeatmapFailureRate:
+		return cell.FailureRate, cell.Completed > 0
+	case HeatmapQueueTime:
+		return cell.QueueTime.Median.Seconds(), cell.QueueTime.Count > 0
+	default:
+		return float64(cell.Runs), cell.Runs > 0
+	}
+}
+
+func formatHeatmapValue(cell *stats.HeatmapCell, metric string) string {
+	if _, ok := heatmapValue(cell, metric); !ok {
+		return ""
+	}
+
+	switch metric {
+	case HeatmapFailureRate:
+		return fmt.Sprintf("%.0f%%", cell.FailureRate*100)
+	case HeatmapQueueTime:
+		return compactDuration(cell.QueueTime.Median)
+	default:
+		return fmt.Sprintf("%d", cell.Runs)
+	}
+}
+
+// Formats a duration in its largest unit to fit into a heatmap cell, e.g. 45s, 12m or 2h
+func compactDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+}
+
+const heatmapHtml = `
+<table>
+	<thead>
+		<tr>
+			<th></th>
+			{{range .Hours}}
+				<th>{{.}}</th>
+			{{end}}
+		</tr>
+	</thead>
+	<tbody>
+		{{range .Rows}}
+			<tr>
+				<th>{{.Weekday}}</th>
+				{{range .Cells}}
+					<td title="{{.Title}}" style="background-color: {{.Color}}; text-align: center">{{.Value}}</td>
+				{{end}}
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+`
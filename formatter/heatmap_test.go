@@ -0,0 +1,50 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+func TestHeatmapToHTML(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newHistoryRun("build", "main", "success", 0, time.Minute),
+		newHistoryRun("build", "main", "failure", 1, time.Minute),
+		newHistoryRun("build", "main", "success", 60, time.Minute),
+	}
+	heatmap := stats.ComputeHeatmap(runs, time.UTC, time.Time{})
+
+	tests := []struct {
+		metric string
+		want   []string
+	}{
+		{HeatmapRuns, []string{
+			`<th>Wed</th>`,
+			`<td title="Wednesday 12:00, 2 runs, 50.0% failed, queued 0s (median)" style="background-color: rgba(13, 110, 253, 1.00); text-align: center">2</td>`,
+			`<td title="Wednesday 13:00, 1 runs, 0.0% failed, queued 0s (median)" style="background-color: rgba(13, 110, 253, 0.50); text-align: center">1</td>`,
+			`<td title="Wednesday 14:00, 0 runs, - failed, queued - (median)" style="background-color: rgba(13, 110, 253, 0.00); text-align: center"></td>`,
+		}},
+		{HeatmapFailureRate, []string{
+			`style="background-color: rgba(220, 53, 69, 1.00); text-align: center">50%</td>`,
+			`style="background-color: rgba(220, 53, 69, 0.00); text-align: center">0%</td>`,
+		}},
+	}
+
+	for _, test := range tests {
+		output, err := HeatmapToHTML(heatmap, test.metric)
+		if err != nil {
+			t.Fatalf("%s: got error: %s", test.metric, err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("%s: got heatmap without %q:\n%s", test.metric, want, output)
+			}
+		}
+		if rows := strings.Count(string(output), "<th>"); rows != 7+25 {
+			t.Errorf("%s: got %d header cells, wanted a row of 24 hours and 7 weekdays", test.metric, rows)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package formatter
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+var historyHtmlTmpl = template.Must(template.New("historyTable").Parse(historyHtml))
+
+// Bars of the duration sparkline from the shortest to the longest run
+var durationBars = []rune("▁▂▃▄▅▆▇█")
+
+// Prints a row per workflow and branch with sparklines of the conclusions and durations of the last size runs
+func ToAsciiHistory(runs []*github.WorkflowRun, size int) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	table.SetHeader([]string{"workflow", "branch", "latest", "status", "history", "durations", "last duration"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+
+	for _, model := range adaptHistoryModels(runs, size, func(*github.WorkflowRun) string { return "" }) {
+		conclusions := &strings.Builder{}
+		durations := &strings.Builder{}
+		for _, glyph := range model.Runs {
+			conclusions.WriteString(glyph.Conclusion)
+			durations.WriteString(glyph.Duration)
+		}
+
+		table.Append([]string{
+			model.WorkflowName,
+			model.Branch,
+			fmt.Sprintf("%d", model.Latest.JobRunNumber),
+			model.Latest.JobStatus,
+			conclusions.String(),
+			durations.String(),
+			model.LastDuration,
+		})
+	}
+	table.Render()
+
+	return output.String(), nil
+}
+
+func ToHTMLHistory(runs []*github.WorkflowRun, size int) (template.HTML, error) {
+	return ToHTMLHistoryWithCustomLink(runs, size, func(r *github.WorkflowRun) string { return "" })
+}
+
+// Renders a row per workflow and branch with sparklines of the last size runs, every glyph links to its run
+func ToHTMLHistoryWithCustomLink(runs []*github.WorkflowRun, size int, titleUrlFunc func(*github.WorkflowRun) string) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	if err := historyHtmlTmpl.Execute(body, adaptHistoryModels(runs, size, titleUrlFunc)); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type historyModel struct {
+	WorkflowName string
+	WorkflowURL  string
+	Branch       string
+	Latest       *github.WorkflowRun
+	LastDuration string
+	// From the oldest to the newest run
+	Runs []*historyGlyphModel
+}
+
+type historyGlyphModel struct {
+	URL        string
+	Title      string
+	Conclusion string
+	Color      string
+	Duration   string
+}
+
+func adaptHistoryModels(runs []*github.WorkflowRun, size int, titleUrlFunc func(*github.WorkflowRun) string) []*historyModel {
+	result := make([]*historyModel, 0)
+	for _, group := range groupHistory(runs) {
+		if size > 0 && len(group) > size {
+			group = group[len(group)-size:]
+		}
+
+		latest := group[len(group)-1]
+		model := &historyModel{
+			WorkflowName: latest.WorkflowName,
+			WorkflowURL:  titleUrlFunc(latest),
+			Branch:       latest.JobBranch,
+			Latest:       latest,
+			LastDuration: "-",
+			Runs:         make([]*historyGlyphModel, len(group)),
+		}
+		if duration, ok := stats.Duration(latest); ok {
+			model.LastDuration = formatDuration(duration, 1)
+		}
+
+		bars := durationSparkline(group)
+		for i, run := range group {
+			conclusion, color := conclusionGlyph(run)
+			model.Runs[i] = &historyGlyphModel{
+				URL:        run.JobHTMLURL,
+				Title:      historyTitle(run),
+				Conclusion: conclusion,
+				Color:      color,
+				Duration:   bars[i],
+			}
+		}
+
+		result = append(result, model)
+	}
+	return result
+}
+
+// Runs of each workflow and branch from the oldest to the newest, in order of the first run of each group
+func groupHistory(runs []*github.WorkflowRun) [][]*github.WorkflowRun {
+	groups := map[string][]*github.WorkflowRun{}
+	keys := make([]string, 0)
+	for _, run := range runs {
+		key := fmt.Sprintf("%s/%s/%s@%s", run.WorkflowOwner, run.WorkflowRepo, run.WorkflowName, run.JobBranch)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], run)
+	}
+
+	result := make([][]*github.WorkflowRun, len(keys))
+	for i, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].JobRunTime.Before(group[j].JobRunTime)
+		})
+		result[i] = group
+	}
+	return result
+}
+
+func conclusionGlyph(run *github.WorkflowRun) (string, string) {
+	switch {
+	case !run.IsCompleted():
+		return "•", "goldenrod"
+	case stats.IsSuccess(run):
+		return "✓", "green"
+	case stats.IsFailure(run):
+		return "✗", "red"
+	default:
+		return "-", "grey"
+	}
+}
+
+// A bar per run scaled between the shortest and the longest duration, a space for runs without a duration
+func durationSparkline(runs []*github.WorkflowRun) []string {
+	durations := make([]time.Duration, len(runs))
+	var min, max time.Duration = -1, -1
+	for i, run := range runs {
+		duration, ok := stats.Duration(run)
+		if !ok {
+			durations[i] = -1
+			continue
+		}
+		durations[i] = duration
+		if min < 0 || duration < min {
+			min = duration
+		}
+		if duration > max {
+			max = duration
+		}
+	}
+
+	result := make([]string, len(runs))
+	for i, duration := range durations {
+		switch {
+		case duration < 0:
+			result[i] = " "
+		case max == min:
+			result[i] = string(durationBars[len(durationBars)/2])
+		default:
+			index := int(float64(duration-min) / float64(max-min) * float64(len(durationBars)-1))
+			result[i] = string(durationBars[index])
+		}
+	}
+	return result
+}
+
+func historyTitle(run *github.WorkflowRun) string {
+	title := fmt.Sprintf("#%d %s", run.JobRunNumber, run.JobStatus)
+	if run.JobConclusion != "" {
+		title = fmt.Sprintf("#%d %s", run.JobRunNumber, run.JobConclusion)
+	}
+	if duration, ok := stats.Duration(run); ok {
+		title += fmt.Sprintf(" in %s", formatDuration(duration, 1))
+	}
+	return fmt.Sprintf("%s, %s %s", title, truncateStr(run.JobCommitSha, 10), run.JobCommitMessage)
+}
+
+const historyHtml = `
+<table>
+	<thead>
+		<tr>
+			<th>Workflow</th>
+			<th>Branch</th>
+			<th>Latest</th>
+			<th>Status</th>
+			<th>History</th>
+			<th>Durations</th>
+			<th>Last Duration</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .}}
+			<tr>
+				{{if eq .WorkflowURL ""}}
+					<td>{{.WorkflowName}}</td>
+				{{else}}
+					<td><a href="{{.WorkflowURL}}">{{.WorkflowName}}</a></td>
+				{{end}}
+				<td><b>{{.Branch}}</b></td>
+				<td><a href="{{.Latest.JobHTMLURL}}">#{{.Latest.JobRunNumber}}</a></td>
+				<td><b>{{.Latest.JobStatus}}</b></td>
+				<td>{{range .Runs}}<a href="{{.URL}}" title="{{.Title}}" style="color: {{.Color}}; text-decoration: none">{{.Conclusion}}</a>{{end}}</td>
+				<td>{{range .Runs}}<a href="{{.URL}}" title="{{.Title}}" style="text-decoration: none">{{.Duration}}</a>{{end}}</td>
+				<td>{{.LastDuration}}</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+`
@@ -0,0 +1,146 @@
+package formatter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+var historyStart = time.Date(2022, 3, 9, 12, 0, 0, 0, time.UTC)
+
+// A completed run of the workflow and branch created n minutes after the start, a zero duration leaves the start time out
+func newHistoryRun(workflow, branch, conclusion string, n int, duration time.Duration) *github.WorkflowRun {
+	created := historyStart.Add(time.Duration(n) * time.Minute)
+	run := &github.WorkflowRun{
+		WorkflowOwner: "foo",
+		WorkflowRepo:  "bar",
+		WorkflowName:  workflow,
+		JobBranch:     branch,
+		JobRunNumber:  n,
+		JobStatus:     github.StatusCompleted,
+		JobConclusion: conclusion,
+		JobRunTime:    created,
+		JobUpdateTime: created.Add(duration),
+	}
+	if duration > 0 {
+		run.JobStartTime = created
+	}
+	return run
+}
+
+func TestDurationSparkline(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      []string
+	}{
+		{"scaled", []time.Duration{time.Minute, 8 * time.Minute, 15 * time.Minute}, []string{"▁", "▄", "█"}},
+		{"equal", []time.Duration{time.Minute, time.Minute}, []string{"▅", "▅"}},
+		{"without duration", []time.Duration{time.Minute, 0, 2 * time.Minute}, []string{"▁", " ", "█"}},
+		{"no durations", []time.Duration{0, 0}, []string{" ", " "}},
+		{"no runs", []time.Duration{}, []string{}},
+	}
+
+	for _, test := range tests {
+		runs := make([]*github.WorkflowRun, len(test.durations))
+		for i, duration := range test.durations {
+			runs[i] = newHistoryRun("build", "main", "success", i, duration)
+		}
+
+		if got := durationSparkline(runs); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, wanted %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestConclusionGlyph(t *testing.T) {
+	queued := newHistoryRun("build", "main", "", 0, 0)
+	queued.JobStatus = github.StatusQueued
+
+	tests := []struct {
+		run   *github.WorkflowRun
+		glyph string
+		color string
+	}{
+		{queued, "•", "goldenrod"},
+		{newHistoryRun("build", "main", "success", 0, 0), "✓", "green"},
+		{newHistoryRun("build", "main", "failure", 0, 0), "✗", "red"},
+		{newHistoryRun("build", "main", "timed_out", 0, 0), "✗", "red"},
+		{newHistoryRun("build", "main", "cancelled", 0, 0), "-", "grey"},
+	}
+
+	for _, test := range tests {
+		if glyph, color := conclusionGlyph(test.run); glyph != test.glyph || color != test.color {
+			t.Errorf("%s/%s: got %s in %s, wanted %s in %s", test.run.JobStatus, test.run.JobConclusion, glyph, color, test.glyph, test.color)
+		}
+	}
+}
+
+func TestAdaptHistoryModels(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newHistoryRun("build", "main", "failure", 3, 2*time.Minute),
+		newHistoryRun("build", "dev", "success", 2, time.Minute),
+		newHistoryRun("build", "main", "success", 1, time.Minute),
+		newHistoryRun("release", "main", "success", 4, 0),
+		newHistoryRun("build", "main", "success", 0, time.Minute),
+	}
+
+	tests := []struct {
+		size int
+		want []string
+	}{
+		{0, []string{"build@main ✓✓✗ ▁▁█ 2m0s", "build@dev ✓ ▅ 1m0s", "release@main ✓   -"}},
+		{2, []string{"build@main ✓✗ ▁█ 2m0s", "build@dev ✓ ▅ 1m0s", "release@main ✓   -"}},
+	}
+
+	for _, test := range tests {
+		got := make([]string, 0)
+		for _, model := range adaptHistoryModels(runs, test.size, func(*github.WorkflowRun) string { return "" }) {
+			conclusions, durations := &strings.Builder{}, &strings.Builder{}
+			for _, glyph := range model.Runs {
+				conclusions.WriteString(glyph.Conclusion)
+				durations.WriteString(glyph.Duration)
+			}
+			got = append(got, model.WorkflowName+"@"+model.Branch+" "+conclusions.String()+" "+durations.String()+" "+model.LastDuration)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("size %d: got %q, wanted %q", test.size, got, test.want)
+		}
+	}
+}
+
+func TestToAsciiHistory(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newHistoryRun("build", "main", "success", 0, time.Minute),
+		newHistoryRun("build", "main", "failure", 1, 3*time.Minute),
+	}
+
+	output, err := ToAsciiHistory(runs, 0)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, want := range []string{"build", "main", "✓✗", "▁█", "3m0s"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("got history without %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestToHTMLHistoryWithCustomLink(t *testing.T) {
+	runs := []*github.WorkflowRun{newHistoryRun("build", "main", "failure", 0, time.Minute)}
+	runs[0].JobHTMLURL = "https://github.com/foo/bar/actions/runs/1"
+
+	output, err := ToHTMLHistoryWithCustomLink(runs, 0, func(*github.WorkflowRun) string { return "/foo/bar/build" })
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, want := range []string{`<a href="/foo/bar/build">build</a>`, `style="color: red; text-decoration: none">✗</a>`, `title="#0 failure in 1m0s, `} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("got history without %q:\n%s", want, output)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
 )
 
 var workflowRunHtmlTmpl = template.Must(template.New("workflowTable").Parse(workflowRunHtml))
@@ -20,9 +21,12 @@ func ToHTMLWithCustomLink(runs []*github.WorkflowRun, titleUrlFunc func(*github.
 	tableRows := &strings.Builder{}
 
 	dataModel := &multipleWorkflowRunsDataModel{
-		Workflows: adaptMultipleWorkflowModels(runs, titleUrlFunc),
-		DisplayParams: containsParams(runs),
+		Workflows:         adaptMultipleWorkflowModels(runs, titleUrlFunc),
+		DisplayActiveRuns: containsActiveRuns(runs),
+		DisplayParams:     containsParams(runs),
 	}
+	dataModel.Columns = countColumns(dataModel)
+	addStatsSummaries(dataModel.Workflows, runs)
 	err := workflowRunHtmlTmpl.Execute(tableRows, dataModel)
 
 	if err != nil {
@@ -33,8 +37,37 @@ func ToHTMLWithCustomLink(runs []*github.WorkflowRun, titleUrlFunc func(*github.
 }
 
 type multipleWorkflowRunsDataModel struct {
-	Workflows []*workflowRunModel
-	DisplayParams bool
+	Workflows         []*workflowRunModel
+	DisplayActiveRuns bool
+	DisplayParams     bool
+	Columns           int
+}
+
+func countColumns(dataModel *multipleWorkflowRunsDataModel) int {
+	columns := 9
+	if dataModel.DisplayActiveRuns {
+		columns++
+	}
+	if dataModel.DisplayParams {
+		columns++
+	}
+	return columns
+}
+
+// Sets a stats summary on the first run of each workflow having more than one run
+func addStatsSummaries(models []*workflowRunModel, runs []*github.WorkflowRun) {
+	summaries := map[string]*stats.WorkflowStats{}
+	for _, s := range stats.Compute(runs, stats.Options{}) {
+		summaries[fmt.Sprintf("%s/%s/%s", s.WorkflowOwner, s.WorkflowRepo, s.WorkflowName)] = s
+	}
+
+	for i, run := range runs {
+		key := fmt.Sprintf("%s/%s/%s", run.WorkflowOwner, run.WorkflowRepo, run.WorkflowName)
+		if summary, ok := summaries[key]; ok && summary.Runs > 1 {
+			models[i].Summary = statsSummary(summary)
+		}
+		delete(summaries, key)
+	}
 }
 
 func adaptMultipleWorkflowModels(runs []*github.WorkflowRun, titleUrlFunc func(*github.WorkflowRun) string) []*workflowRunModel {
@@ -46,6 +79,11 @@ func adaptMultipleWorkflowModels(runs []*github.WorkflowRun, titleUrlFunc func(*
 }
 
 func adaptWorkflowModel(run *github.WorkflowRun, titleUrlFunc func(*github.WorkflowRun) string) *workflowRunModel {
+	var activeRun *workflowRunModel = nil
+	if run.ActiveRun != nil {
+		activeRun = adaptWorkflowModel(run.ActiveRun, titleUrlFunc)
+	}
+
 	return &workflowRunModel{
 		WorkflowName:     run.WorkflowName,
 		WorkflowURL:      titleUrlFunc(run),
@@ -64,6 +102,7 @@ func adaptWorkflowModel(run *github.WorkflowRun, titleUrlFunc func(*github.Workf
 		JobCommitMessage: run.JobCommitMessage,
 		JobCommitTime:    timeSince(run.JobCommitTime),
 		JobRunParams:     adaptParams(run.WorkflowParams),
+		ActiveRun:        activeRun,
 	}
 }
 
@@ -123,6 +162,8 @@ type workflowRunModel struct {
 	JobCommitMessage string
 	JobCommitTime    string
 	JobRunParams     []string
+	ActiveRun        *workflowRunModel
+	Summary          string
 }
 
 const workflowRunHtml = `
@@ -138,6 +179,9 @@ const workflowRunHtml = `
 			<th>Commit</th>
 			<th>Commit Time</th>
 			<th>Run Time</th>
+			{{if .DisplayActiveRuns}}
+				<th>Running</th>
+			{{end}}
 			{{if .DisplayParams}}
 				<th>Params</th>
 			{{end}}
@@ -145,6 +189,11 @@ const workflowRunHtml = `
 	</thead>
 	<tbody>
 		{{range .Workflows}}
+			{{if .Summary}}
+				<tr>
+					<td colspan="{{$.Columns}}"><i>{{.WorkflowName}}: {{.Summary}}</i></td>
+				</tr>
+			{{end}}
 			<tr>
 				{{if eq .WorkflowURL ""}}
 					<td>{{.WorkflowName}}</td>
@@ -159,6 +208,13 @@ const workflowRunHtml = `
 				<td>{{.JobCommitSha}}</td>
 				<td>{{.JobCommitTime}}</td>
 				<td>{{.JobRunTime}}</td>
+				{{if $.DisplayActiveRuns}}
+					<td>
+						{{with .ActiveRun}}
+							<a href="{{.JobHTMLURL}}">#{{.JobRunNumber}}</a> {{.JobStatus}}
+						{{end}}
+					</td>
+				{{end}}
 				{{if $.DisplayParams}}
 					<td>
 						{{range .JobRunParams}}
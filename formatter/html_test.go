@@ -0,0 +1,34 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func TestToHTMLStatsSummaryRow(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newHistoryRun("build", "main", "failure", 1, 3*time.Minute),
+		newHistoryRun("build", "main", "success", 0, time.Minute),
+		newHistoryRun("release", "main", "success", 2, time.Minute),
+	}
+
+	output, err := ToHTML(runs)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	html := string(output)
+
+	summary := `<td colspan="9"><i>build: 2 runs, 50.0% success, 50.0% failure, duration median `
+	if strings.Count(html, summary) != 1 {
+		t.Errorf("got runs without a single summary row %q:\n%s", summary, html)
+	}
+	if strings.Index(html, summary) > strings.Index(html, "<td>build</td>") {
+		t.Errorf("got the summary row after the first run of the workflow:\n%s", html)
+	}
+	if strings.Contains(html, "<i>release:") {
+		t.Errorf("got a summary row for a workflow with a single run:\n%s", html)
+	}
+}
@@ -0,0 +1,211 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+var regressionsHtmlTmpl = template.Must(template.New("regressionsTable").Parse(regressionsHtml))
+var regressionBannersHtmlTmpl = template.Must(template.New("regressionsBanner").Parse(regressionBannersHtml))
+
+func RegressionsToAscii(regressions []*stats.DurationRegression) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	table.SetHeader([]string{"repository", "workflow", "job", "branch", "baseline median", "recent median", "ratio",
+		"shifted between", "anomalies"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+
+	for _, model := range adaptRegressionModels(regressions) {
+		shift := ""
+		if model.Regressed {
+			shift = fmt.Sprintf("#%d %s...#%d %s", model.LastFastRun, model.LastFastSha, model.FirstSlowRun, model.FirstSlowSha)
+		}
+
+		table.Append([]string{
+			model.Repository,
+			model.WorkflowName,
+			model.JobName,
+			model.Branch,
+			model.BaselineMedian,
+			model.RecentMedian,
+			model.Ratio,
+			shift,
+			fmt.Sprintf("%d", len(model.Anomalies)),
+		})
+	}
+	table.Render()
+
+	return output.String(), nil
+}
+
+func RegressionsToJson(regressions []*stats.DurationRegression) (string, error) {
+	bytes, err := json.Marshal(regressions)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func RegressionsToHTML(regressions []*stats.DurationRegression) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	if err := regressionsHtmlTmpl.Execute(body, adaptRegressionModels(regressions)); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+// Renders a banner for every duration regression, empty if nothing regressed
+func RegressionBannersToHTML(regressions []*stats.DurationRegression) (template.HTML, error) {
+	models := make([]*regressionModel, 0)
+	for _, model := range adaptRegressionModels(regressions) {
+		if model.Regressed {
+			models = append(models, model)
+		}
+	}
+
+	if len(models) == 0 {
+		return "", nil
+	}
+
+	body := &strings.Builder{}
+	if err := regressionBannersHtmlTmpl.Execute(body, models); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type regressionModel struct {
+	Repository     string
+	WorkflowName   string
+	JobName        string
+	Branch         string
+	BaselineMedian string
+	RecentMedian   string
+	Ratio          string
+	Regressed      bool
+	LastFastURL    string
+	LastFastRun    int
+	LastFastSha    string
+	FirstSlowURL   string
+	FirstSlowRun   int
+	FirstSlowSha   string
+	CompareURL     string
+	Anomalies      []*anomalyModel
+}
+
+type anomalyModel struct {
+	URL      string
+	Run      int
+	Duration string
+	Factor   string
+}
+
+func adaptRegressionModels(regressions []*stats.DurationRegression) []*regressionModel {
+	result := make([]*regressionModel, len(regressions))
+	for i, r := range regressions {
+		model := &regressionModel{
+			Repository:     fmt.Sprintf("%s/%s", r.WorkflowOwner, r.WorkflowRepo),
+			WorkflowName:   r.WorkflowName,
+			JobName:        r.JobName,
+			Branch:         r.Branch,
+			BaselineMedian: formatDuration(r.Baseline.Median, r.Baseline.Count),
+			RecentMedian:   formatDuration(r.Recent.Median, r.Recent.Count),
+			Ratio:          fmt.Sprintf("%.2fx", r.Ratio),
+			Regressed:      r.Regressed,
+			CompareURL:     r.CompareURL,
+			Anomalies:      make([]*anomalyModel, len(r.Anomalies)),
+		}
+
+		if r.Regressed {
+			model.LastFastURL, model.LastFastRun, model.LastFastSha = adaptShiftRun(r.LastFast)
+			model.FirstSlowURL, model.FirstSlowRun, model.FirstSlowSha = adaptShiftRun(r.FirstSlow)
+		}
+
+		for j, a := range r.Anomalies {
+			model.Anomalies[j] = &anomalyModel{
+				URL:      a.URL,
+				Run:      a.Run.JobRunNumber,
+				Duration: formatDuration(a.Duration, 1),
+				Factor:   fmt.Sprintf("%.1fx", a.Factor),
+			}
+		}
+
+		result[i] = model
+	}
+	return result
+}
+
+func adaptShiftRun(run *github.WorkflowRun) (string, int, string) {
+	return run.JobHTMLURL, run.JobRunNumber, truncateStr(run.JobCommitSha, 10)
+}
+
+const regressionsHtml = `
+{{if .}}
+<table>
+	<thead>
+		<tr>
+			<th>Repository</th>
+			<th>Workflow</th>
+			<th>Job</th>
+			<th>Branch</th>
+			<th>Baseline Median</th>
+			<th>Recent Median</th>
+			<th>Ratio</th>
+			<th>Shifted Between</th>
+			<th>Anomalies</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .}}
+			<tr>
+				<td>{{.Repository}}</td>
+				<td>{{.WorkflowName}}</td>
+				<td>{{.JobName}}</td>
+				<td>{{.Branch}}</td>
+				<td>{{.BaselineMedian}}</td>
+				<td>{{.RecentMedian}}</td>
+				<td>{{if .Regressed}}<b>{{.Ratio}}</b>{{else}}{{.Ratio}}{{end}}</td>
+				<td>
+					{{if .Regressed}}
+						<a href="{{.LastFastURL}}">#{{.LastFastRun}}</a> <code>{{.LastFastSha}}</code> ...
+						<a href="{{.FirstSlowURL}}">#{{.FirstSlowRun}}</a> <code>{{.FirstSlowSha}}</code>
+						{{if .CompareURL}}<br/><a href="{{.CompareURL}}">compare changes</a>{{end}}
+					{{end}}
+				</td>
+				<td>
+					{{range .Anomalies}}
+						<a href="{{.URL}}">#{{.Run}}</a> {{.Duration}} ({{.Factor}})<br/>
+					{{end}}
+				</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+{{else}}
+<p>No duration regressions found.</p>
+{{end}}
+`
+
+const regressionBannersHtml = `
+{{range .}}
+	<blockquote>
+		<b>{{.WorkflowName}}</b>{{if .JobName}} / <b>{{.JobName}}</b>{{end}} on <b>{{.Branch}}</b> got slower,
+		median {{.BaselineMedian}} → {{.RecentMedian}} ({{.Ratio}}),
+		shifted between <a href="{{.LastFastURL}}">#{{.LastFastRun}}</a> and <a href="{{.FirstSlowURL}}">#{{.FirstSlowRun}}</a>
+		{{if .CompareURL}}, <a href="{{.CompareURL}}">compare changes</a>{{end}}
+	</blockquote>
+{{end}}
+`
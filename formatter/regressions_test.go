@@ -0,0 +1,100 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+// A regression of build on main shifting between runs 1 and 2 with an anomaly in run 3, and a job that didn't regress
+func newTestRegressions() []*stats.DurationRegression {
+	fast := newHistoryRun("build", "main", "success", 1, time.Minute)
+	fast.JobHTMLURL = "https://github.com/foo/bar/actions/runs/1"
+	fast.JobCommitSha = "aaaaaaaaaaaaaaaa"
+	slow := newHistoryRun("build", "main", "success", 2, 3*time.Minute)
+	slow.JobHTMLURL = "https://github.com/foo/bar/actions/runs/2"
+	slow.JobCommitSha = "bbbbbbbbbbbbbbbb"
+	outlier := newHistoryRun("build", "main", "success", 3, 5*time.Minute)
+
+	return []*stats.DurationRegression{
+		{
+			WorkflowOwner: "foo",
+			WorkflowRepo:  "bar",
+			WorkflowName:  "build",
+			Branch:        "main",
+			Baseline:      stats.DurationStats{Count: 5, Median: time.Minute},
+			Recent:        stats.DurationStats{Count: 3, Median: 3 * time.Minute},
+			Ratio:         3,
+			Regressed:     true,
+			LastFast:      fast,
+			FirstSlow:     slow,
+			CompareURL:    "https://github.com/foo/bar/compare/aaaaaaaaaaaaaaaa...bbbbbbbbbbbbbbbb",
+			Anomalies: []*stats.DurationAnomaly{
+				{Run: outlier, URL: "https://github.com/foo/bar/actions/runs/3", Duration: 5 * time.Minute, Factor: 5},
+			},
+		},
+		{
+			WorkflowOwner: "foo",
+			WorkflowRepo:  "bar",
+			WorkflowName:  "build",
+			JobName:       "lint",
+			Branch:        "main",
+			Baseline:      stats.DurationStats{Count: 5, Median: time.Minute},
+			Recent:        stats.DurationStats{Count: 3, Median: time.Minute},
+			Ratio:         1,
+			Anomalies:     []*stats.DurationAnomaly{},
+		},
+	}
+}
+
+func TestRegressionsToHTML(t *testing.T) {
+	output, err := RegressionsToHTML(newTestRegressions())
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, want := range []string{
+		"<td>foo/bar</td>",
+		"<td><b>3.00x</b></td>",
+		"<td>1.00x</td>",
+		`<a href="https://github.com/foo/bar/actions/runs/1">#1</a> <code>aaaaaaaaaa</code> ...`,
+		`<a href="https://github.com/foo/bar/actions/runs/2">#2</a> <code>bbbbbbbbbb</code>`,
+		`<a href="https://github.com/foo/bar/compare/aaaaaaaaaaaaaaaa...bbbbbbbbbbbbbbbb">compare changes</a>`,
+		`<a href="https://github.com/foo/bar/actions/runs/3">#3</a> 5m0s (5.0x)`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("got regressions without %q:\n%s", want, output)
+		}
+	}
+
+	if output, err = RegressionsToHTML([]*stats.DurationRegression{}); err != nil || !strings.Contains(string(output), "No duration regressions found.") {
+		t.Errorf("got %s with error %v, wanted no regressions", output, err)
+	}
+}
+
+func TestRegressionBannersToHTML(t *testing.T) {
+	regressions := newTestRegressions()
+
+	output, err := RegressionBannersToHTML(regressions)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, want := range []string{
+		"<b>build</b> on <b>main</b> got slower",
+		"median 1m0s → 3m0s (3.00x)",
+		`shifted between <a href="https://github.com/foo/bar/actions/runs/1">#1</a> and <a href="https://github.com/foo/bar/actions/runs/2">#2</a>`,
+		`<a href="https://github.com/foo/bar/compare/aaaaaaaaaaaaaaaa...bbbbbbbbbbbbbbbb">compare changes</a>`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("got banner without %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(string(output), "lint") {
+		t.Errorf("got a banner for a job that didn't regress:\n%s", output)
+	}
+
+	if output, err = RegressionBannersToHTML(regressions[1:]); err != nil || output != "" {
+		t.Errorf("got banner %s with error %v, wanted none while nothing regressed", output, err)
+	}
+}
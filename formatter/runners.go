@@ -0,0 +1,148 @@
+package formatter
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+var runnersHtmlTmpl = template.Must(template.New("runnersTable").Parse(runnersHtml))
+
+func RunnersToHTML(runners []*github.Runner, queuedJobs []*github.QueuedJob) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	dataModel := &runnersDataModel{
+		Runners:    adaptRunnerModels(runners),
+		QueuedJobs: adaptQueuedJobModels(queuedJobs),
+	}
+
+	if err := runnersHtmlTmpl.Execute(body, dataModel); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type runnersDataModel struct {
+	Runners    []*runnerModel
+	QueuedJobs []*queuedJobModel
+}
+
+type runnerModel struct {
+	Scope  string
+	Name   string
+	OS     string
+	Labels string
+	Status string
+	Online bool
+	Busy   bool
+}
+
+type queuedJobModel struct {
+	Repository    string
+	WorkflowName  string
+	RunNumber     int
+	JobName       string
+	JobHTMLURL    string
+	Labels        string
+	QueuedFor     string
+	WaitingReason string
+}
+
+func adaptRunnerModels(runners []*github.Runner) []*runnerModel {
+	result := make([]*runnerModel, len(runners))
+	for i, runner := range runners {
+		result[i] = &runnerModel{
+			Scope:  github.RunnerScope{Owner: runner.Owner, Repo: runner.Repo}.String(),
+			Name:   runner.Name,
+			OS:     runner.OS,
+			Labels: strings.Join(runner.Labels, ", "),
+			Status: runner.Status,
+			Online: runner.IsOnline(),
+			Busy:   runner.Busy,
+		}
+	}
+	return result
+}
+
+func adaptQueuedJobModels(jobs []*github.QueuedJob) []*queuedJobModel {
+	result := make([]*queuedJobModel, len(jobs))
+	for i, job := range jobs {
+		result[i] = &queuedJobModel{
+			Repository:    github.RepoId{Owner: job.Owner, Name: job.Repo}.String(),
+			WorkflowName:  job.WorkflowName,
+			RunNumber:     job.RunNumber,
+			JobName:       job.JobName,
+			JobHTMLURL:    job.JobHTMLURL,
+			Labels:        strings.Join(job.Labels, ", "),
+			QueuedFor:     timeSince(job.QueuedSince),
+			WaitingReason: job.WaitingReason,
+		}
+	}
+	return result
+}
+
+const runnersHtml = `
+<h3>Queued jobs</h3>
+{{if .QueuedJobs}}
+<table>
+	<thead>
+		<tr>
+			<th>Repository</th>
+			<th>Workflow</th>
+			<th>#</th>
+			<th>Job</th>
+			<th>Labels</th>
+			<th>Queued</th>
+			<th>Waiting Reason</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .QueuedJobs}}
+			<tr>
+				<td>{{.Repository}}</td>
+				<td>{{.WorkflowName}}</td>
+				<td>#{{.RunNumber}}</td>
+				<td><a href="{{.JobHTMLURL}}">{{.JobName}}</a></td>
+				<td>{{.Labels}}</td>
+				<td>{{.QueuedFor}}</td>
+				<td><b>{{.WaitingReason}}</b></td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+{{else}}
+<p>No jobs are waiting for a runner.</p>
+{{end}}
+
+<h3>Runners</h3>
+{{if .Runners}}
+<table>
+	<thead>
+		<tr>
+			<th>Scope</th>
+			<th>Name</th>
+			<th>OS</th>
+			<th>Labels</th>
+			<th>Status</th>
+			<th>Busy</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .Runners}}
+			<tr>
+				<td>{{.Scope}}</td>
+				<td>{{.Name}}</td>
+				<td>{{.OS}}</td>
+				<td>{{.Labels}}</td>
+				<td>{{if .Online}}{{.Status}}{{else}}<b>{{.Status}}</b>{{end}}</td>
+				<td>{{if .Busy}}busy{{else}}idle{{end}}</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+{{else}}
+<p>No self-hosted runners found.</p>
+{{end}}
+`
@@ -0,0 +1,97 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+func StatsToAscii(workflowStats []*stats.WorkflowStats) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	header := []string{"repository", "workflow"}
+	if containsBranches(workflowStats) {
+		header = append(header, "branch")
+	}
+	header = append(header, "from", "to", "runs", "success", "failure", "cancelled",
+		"mean", "median", "p90", "p95", "queue median", "queue p95")
+
+	table.SetHeader(header)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, s := range workflowStats {
+		row := []string{fmt.Sprintf("%s/%s", s.WorkflowOwner, s.WorkflowRepo), s.WorkflowName}
+		if containsBranches(workflowStats) {
+			row = append(row, s.Branch)
+		}
+		row = append(row,
+			s.From.UTC().Format(time.RFC3339),
+			s.To.UTC().Format(time.RFC3339),
+			fmt.Sprintf("%d", s.Runs),
+			formatRate(s.SuccessRate, s.Completed),
+			formatRate(s.FailureRate, s.Completed),
+			formatRate(s.CancelledRate, s.Completed),
+			formatDuration(s.Duration.Mean, s.Duration.Count),
+			formatDuration(s.Duration.Median, s.Duration.Count),
+			formatDuration(s.Duration.P90, s.Duration.Count),
+			formatDuration(s.Duration.P95, s.Duration.Count),
+			formatDuration(s.QueueTime.Median, s.QueueTime.Count),
+			formatDuration(s.QueueTime.P95, s.QueueTime.Count))
+		table.Append(row)
+	}
+	table.Render()
+
+	return output.String(), nil
+}
+
+func StatsToJson(workflowStats []*stats.WorkflowStats) (string, error) {
+	bytes, err := json.Marshal(workflowStats)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// One line summary of the stats of a workflow shown above its runs
+func statsSummary(s *stats.WorkflowStats) string {
+	return fmt.Sprintf("%d runs, %s success, %s failure, duration median %s p95 %s, queued median %s",
+		s.Runs,
+		formatRate(s.SuccessRate, s.Completed),
+		formatRate(s.FailureRate, s.Completed),
+		formatDuration(s.Duration.Median, s.Duration.Count),
+		formatDuration(s.Duration.P95, s.Duration.Count),
+		formatDuration(s.QueueTime.Median, s.QueueTime.Count))
+}
+
+func containsBranches(workflowStats []*stats.WorkflowStats) bool {
+	for _, s := range workflowStats {
+		if s.Branch != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Formats a rate as a percentage, "-" if there are no completed runs
+func formatRate(rate float64, completed int) string {
+	if completed == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// Formats a duration rounded to seconds, "-" if there are no durations
+func formatDuration(d time.Duration, count int) string {
+	if count == 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
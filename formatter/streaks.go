@@ -0,0 +1,105 @@
+package formatter
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+var streaksHtmlTmpl = template.Must(template.New("streaksBanner").Parse(streaksHtml))
+
+// Renders a banner for every failing streak, empty if nothing is failing
+func StreaksToHTML(streaks []*stats.Streak, now time.Time) (template.HTML, error) {
+	models := make([]*streakModel, 0)
+	for _, streak := range streaks {
+		if streak.Failing {
+			models = append(models, adaptStreakModel(streak, now))
+		}
+	}
+
+	if len(models) == 0 {
+		return "", nil
+	}
+
+	body := &strings.Builder{}
+	if err := streaksHtmlTmpl.Execute(body, models); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type streakModel struct {
+	WorkflowName  string
+	Branch        string
+	Length        int
+	RedFor        string
+	FirstRunURL   string
+	FirstRun      int
+	CommitSha     string
+	CommitAuthor  string
+	CommitMessage string
+	LastGreenURL  string
+	LastGreenRun  int
+	CompareURL    string
+}
+
+func adaptStreakModel(streak *stats.Streak, now time.Time) *streakModel {
+	model := &streakModel{
+		WorkflowName:  streak.WorkflowName,
+		Branch:        streak.Branch,
+		Length:        streak.Length,
+		RedFor:        streak.RedFor(now).Round(time.Minute).String(),
+		FirstRunURL:   streak.First.JobHTMLURL,
+		FirstRun:      streak.First.JobRunNumber,
+		CommitSha:     truncateStr(streak.First.JobCommitSha, 10),
+		CommitAuthor:  streak.First.JobCommitAuthor,
+		CommitMessage: streak.First.JobCommitMessage,
+		CompareURL:    streak.CompareURL,
+	}
+
+	if streak.LastSuccess != nil {
+		model.LastGreenURL = streak.LastSuccess.JobHTMLURL
+		model.LastGreenRun = streak.LastSuccess.JobRunNumber
+	}
+
+	return model
+}
+
+// Describes how long the workflow of the run has been failing on its branch if the run is the latest of a failing streak
+func brokenSince(run *github.WorkflowRun, streaks map[*github.WorkflowRun]*stats.Streak) string {
+	streak, ok := streaks[run]
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%s, %d runs since #%d %s",
+		streak.RedFor(time.Now()).Round(time.Minute), streak.Length, streak.First.JobRunNumber, truncateStr(streak.First.JobCommitSha, 10))
+}
+
+// The failing streaks of the runs by the latest run of each streak
+func failingStreaksByLatestRun(runs []*github.WorkflowRun) map[*github.WorkflowRun]*stats.Streak {
+	result := map[*github.WorkflowRun]*stats.Streak{}
+	for _, streak := range stats.ComputeStreaks(runs) {
+		if streak.Failing {
+			result[streak.Latest] = streak
+		}
+	}
+	return result
+}
+
+const streaksHtml = `
+{{range .}}
+	<blockquote>
+		<b>{{.WorkflowName}}</b> on <b>{{.Branch}}</b> is failing for {{.RedFor}} ({{.Length}} runs),
+		broken by <a href="{{.FirstRunURL}}">#{{.FirstRun}}</a> <code>{{.CommitSha}}</code> {{.CommitAuthor}}: {{.CommitMessage}}
+		{{if .LastGreenURL}}
+			<br/>last green <a href="{{.LastGreenURL}}">#{{.LastGreenRun}}</a>{{if .CompareURL}}, <a href="{{.CompareURL}}">compare changes</a>{{end}}
+		{{end}}
+	</blockquote>
+{{end}}
+`
@@ -0,0 +1,76 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/stats"
+)
+
+func TestStreaksToHTML(t *testing.T) {
+	green := newHistoryRun("build", "main", "success", 1, time.Minute)
+	green.JobHTMLURL = "https://github.com/foo/bar/actions/runs/1"
+	red := newHistoryRun("build", "main", "failure", 2, time.Minute)
+	red.JobHTMLURL = "https://github.com/foo/bar/actions/runs/2"
+	red.JobCommitSha = "0123456789abcdef"
+	red.JobCommitAuthor = "alice"
+	red.JobCommitMessage = "Break the build"
+
+	failing := &stats.Streak{
+		WorkflowName: "build",
+		Branch:       "main",
+		Failing:      true,
+		Length:       3,
+		First:        red,
+		Latest:       red,
+		LastSuccess:  green,
+		CompareURL:   "https://github.com/foo/bar/compare/aaa...0123456789abcdef",
+	}
+	passing := &stats.Streak{WorkflowName: "lint", Branch: "main", Length: 2, First: green, Latest: green}
+
+	output, err := StreaksToHTML([]*stats.Streak{failing, passing}, red.JobRunTime.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	for _, want := range []string{
+		`<b>build</b> on <b>main</b> is failing for 1h30m0s (3 runs)`,
+		`broken by <a href="https://github.com/foo/bar/actions/runs/2">#2</a> <code>0123456789</code> alice: Break the build`,
+		`last green <a href="https://github.com/foo/bar/actions/runs/1">#1</a>, <a href="https://github.com/foo/bar/compare/aaa...0123456789abcdef">compare changes</a>`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("got banner without %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(string(output), "lint") {
+		t.Errorf("got a banner for a passing streak:\n%s", output)
+	}
+
+	// without a green run before the streak there is nothing to compare
+	failing.LastSuccess, failing.CompareURL = nil, ""
+	if output, err = StreaksToHTML([]*stats.Streak{failing}, red.JobRunTime); err != nil || strings.Contains(string(output), "last green") {
+		t.Errorf("got banner %s with error %v, wanted no last green run", output, err)
+	}
+
+	if output, err = StreaksToHTML([]*stats.Streak{passing}, red.JobRunTime); err != nil || output != "" {
+		t.Errorf("got banner %s with error %v, wanted none while nothing is failing", output, err)
+	}
+}
+
+func TestBrokenSince(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newHistoryRun("build", "main", "success", 0, time.Minute),
+		newHistoryRun("build", "main", "failure", 1, time.Minute),
+		newHistoryRun("build", "main", "failure", 2, time.Minute),
+	}
+	runs[1].JobCommitSha = "0123456789abcdef"
+
+	streaks := failingStreaksByLatestRun(runs)
+	if got := brokenSince(runs[2], streaks); !strings.HasSuffix(got, ", 2 runs since #1 0123456789") {
+		t.Errorf("got %q, wanted the streak since #1", got)
+	}
+	if got := brokenSince(runs[1], streaks); got != "" {
+		t.Errorf("got %q, wanted nothing for a run that isn't the latest of a streak", got)
+	}
+}
@@ -0,0 +1,116 @@
+package formatter
+
+import (
+	"encoding/json"
+	"html/template"
+	"strings"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/olekukonko/tablewriter"
+)
+
+var workflowsHtmlTmpl = template.Must(template.New("workflowsTable").Parse(workflowsHtml))
+
+func WorkflowsToAscii(workflows []*github.Workflow) (string, error) {
+	output := &strings.Builder{}
+	table := tablewriter.NewWriter(output)
+
+	table.SetHeader([]string{"repository", "workflow", "path", "state", "last run", "stale"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, model := range adaptWorkflowModels(workflows) {
+		stale := ""
+		if model.Stale {
+			stale = "stale"
+		}
+		table.Append([]string{model.Repository, model.WorkflowName, model.Path, model.State, model.LastRun, stale})
+	}
+	table.Render()
+
+	return output.String(), nil
+}
+
+func WorkflowsToJson(workflows []*github.Workflow) (string, error) {
+	bytes, err := json.Marshal(workflows)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func WorkflowsToHTML(workflows []*github.Workflow) (template.HTML, error) {
+	body := &strings.Builder{}
+
+	if err := workflowsHtmlTmpl.Execute(body, adaptWorkflowModels(workflows)); err != nil {
+		return "", err
+	}
+
+	return template.HTML(body.String()), nil
+}
+
+type workflowModel struct {
+	Repository   string
+	WorkflowName string
+	Path         string
+	HTMLURL      string
+	State        string
+	Active       bool
+	LastRun      string
+	LastRunURL   string
+	Stale        bool
+}
+
+func adaptWorkflowModels(workflows []*github.Workflow) []*workflowModel {
+	result := make([]*workflowModel, len(workflows))
+	for i, workflow := range workflows {
+		lastRun := "never"
+		if !workflow.LastRunTime.IsZero() {
+			lastRun = timeSince(workflow.LastRunTime)
+		}
+
+		result[i] = &workflowModel{
+			Repository:   github.RepoId{Owner: workflow.WorkflowOwner, Name: workflow.WorkflowRepo}.String(),
+			WorkflowName: workflow.WorkflowName,
+			Path:         workflow.Path,
+			HTMLURL:      workflow.HTMLURL,
+			State:        workflow.State,
+			Active:       workflow.IsActive(),
+			LastRun:      lastRun,
+			LastRunURL:   workflow.LastRunURL,
+			Stale:        workflow.Stale,
+		}
+	}
+	return result
+}
+
+const workflowsHtml = `
+{{if .}}
+<table>
+	<thead>
+		<tr>
+			<th>Repository</th>
+			<th>Workflow</th>
+			<th>State</th>
+			<th>Last Run</th>
+		</tr>
+	</thead>
+	<tbody>
+		{{range .}}
+			<tr>
+				<td>{{.Repository}}</td>
+				<td title="{{.Path}}"><a href="{{.HTMLURL}}">{{.WorkflowName}}</a></td>
+				<td>{{if .Active}}{{.State}}{{else}}<b>{{.State}}</b>{{end}}</td>
+				<td>
+					{{if .LastRunURL}}<a href="{{.LastRunURL}}">{{.LastRun}}</a>{{else}}{{.LastRun}}{{end}}
+					{{if .Stale}}<b>(stale)</b>{{end}}
+				</td>
+			</tr>
+		{{end}}
+	</tbody>
+</table>
+{{else}}
+<p>No workflows found.</p>
+{{end}}
+`
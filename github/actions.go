@@ -9,7 +9,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	g "github.com/google/go-github/v42/github"
@@ -31,12 +34,17 @@ type WorkflowRun struct {
 	JobStatus        string             `json:"jobStatus"`
 	JobEvent         string             `json:"jobEvent"`
 	JobRunTime       time.Time          `json:"jobRunTime"`
+	JobRunAttempt    int                `json:"jobRunAttempt"`
+	JobStartTime     time.Time          `json:"jobStartTime"`
+	JobUpdateTime    time.Time          `json:"jobUpdateTime"`
 	JobBranch        string             `json:"jobBranch"`
 	JobCommitSha     string             `json:"jobCommitSha"`
 	JobCommitAuthor  string             `json:"jobCommitAuthor"`
 	JobCommitMessage string             `json:"jobCommitMessage"`
 	JobCommitTime    time.Time          `json:"jobCommitTitle"`
 	WorkflowParams   *WorkflowRunParams `json:"worfklowParams"`
+	// The latest queued or in progress run newer than this one, set only when fetching the latest completed runs
+	ActiveRun *WorkflowRun `json:"activeRun,omitempty"`
 }
 
 type WorkflowRunParams struct {
@@ -53,13 +61,34 @@ type WorkflowFilter struct {
 	Owner         string
 	Repo          string
 	WorkflowNames []string
-	Limit         int
+	// Used to narrow down the tracked workflows when WorkflowNames is empty
+	WorkflowPattern *regexp.Regexp
+	Limit           int
+	// Used only when fetching the latest workflow runs
+	Latest LatestOptions
+	// Fetch only runs created at or after the time by workflow name, every page of them regardless of Limit. The runs of
+	// workflows missing are fetched as usual
+	CreatedSince map[string]time.Time
 }
 
 func (f WorkflowFilter) GetRepoId() *RepoId {
 	return &RepoId{Owner: f.Owner, Name: f.Repo}
 }
 
+// Whether the runs of the workflow are fetched with the filter
+func (f WorkflowFilter) TracksWorkflow(name string) bool {
+	if len(f.WorkflowNames) == 0 {
+		return f.WorkflowPattern == nil || f.WorkflowPattern.MatchString(name)
+	}
+
+	for _, workflowName := range f.WorkflowNames {
+		if workflowName == name {
+			return true
+		}
+	}
+	return false
+}
+
 type RepoId struct {
 	Owner string
 	Name  string
@@ -69,53 +98,69 @@ func (r RepoId) String() string {
 	return fmt.Sprintf("%s/%s", r.Owner, r.Name)
 }
 
-type WorkflowClient struct {
-	client g.Client
+// The github workflow operations used by the CLI and the server
+type WorkflowClient interface {
+	FetchWorkflowRuns(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowRun, error)
+	FetchLatestWorkflowRuns(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowRun, error)
+	FetchWorkflowRunParams(ctx context.Context, filter *WorkflowFilter, runId int) (*WorkflowRunParams, error)
+	EnrichWorkflowRunsWithParams(ctx context.Context, filter *WorkflowFilter, runs []*WorkflowRun) error
+	DiscoverRepositories(ctx context.Context, filter *DiscoveryFilter) ([]*WorkflowFilter, error)
+	FetchRunners(ctx context.Context, scope *RunnerScope) ([]*Runner, error)
+	FetchQueuedJobs(ctx context.Context, filter *WorkflowFilter) ([]*QueuedJob, error)
+	FetchWorkflowDefinitions(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowDefinition, error)
+	FetchWorkflows(ctx context.Context, filter *WorkflowFilter) ([]*Workflow, error)
+	EnableWorkflow(ctx context.Context, filter *WorkflowFilter, workflowName string) error
+	DisableWorkflow(ctx context.Context, filter *WorkflowFilter, workflowName string) error
+	FetchRunJobs(ctx context.Context, filter *WorkflowFilter, runId int) ([]*RunJob, error)
+}
+
+// WorkflowClient backed by the github REST API
+type apiWorkflowClient struct {
+	client *g.Client
 }
 
-func NewWorkflowClient(httpClient *http.Client) *WorkflowClient {
-	return &WorkflowClient{
-		client: *g.NewClient(httpClient),
+func NewWorkflowClient(httpClient *http.Client) WorkflowClient {
+	return &apiWorkflowClient{
+		client: g.NewClient(httpClient),
 	}
 }
 
-func (c *WorkflowClient) FetchWorkflowRuns(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowRun, error) {
-	runs, err := queryAndAdaptWorkflowRuns(&c.client, ctx, filter)
+// Creates a client for a github API compatible server other than api.github.com, e.g. a fake used in tests
+func NewWorkflowClientWithBaseURL(httpClient *http.Client, baseURL string) (WorkflowClient, error) {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
 	}
 
-	return sortWorkflowRuns(runs), nil
+	client := g.NewClient(httpClient)
+	client.BaseURL = parsedURL
+
+	return &apiWorkflowClient{client: client}, nil
 }
 
-func (c *WorkflowClient) FetchLatestWorkflowRuns(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowRun, error) {
-	runs, err := c.FetchWorkflowRuns(ctx, filter)
+func (c *apiWorkflowClient) FetchWorkflowRuns(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowRun, error) {
+	runs, err := queryAndAdaptWorkflowRuns(c.client, ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	latestRuns := map[string]*WorkflowRun{}
-
-	for _, run := range runs {
-		if existing, ok := latestRuns[run.WorkflowName]; ok {
-			if existing.JobRunTime.Before(run.JobRunTime) {
-				latestRuns[run.WorkflowName] = run
-			}
-		} else {
-			latestRuns[run.WorkflowName] = run
-		}
-	}
-
-	result := make([]*WorkflowRun, 0)
+	return SortWorkflowRuns(runs), nil
+}
 
-	for _, run := range latestRuns {
-		result = append(result, run)
+func (c *apiWorkflowClient) FetchLatestWorkflowRuns(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowRun, error) {
+	runs, err := c.FetchWorkflowRuns(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	return sortWorkflowRuns(result), nil
+	return LatestWorkflowRuns(runs, filter.Latest), nil
 }
 
-func (c *WorkflowClient) EnrichWorkflowRunsWithParams(ctx context.Context, filter *WorkflowFilter, runs []*WorkflowRun) error {
+func (c *apiWorkflowClient) EnrichWorkflowRunsWithParams(ctx context.Context, filter *WorkflowFilter, runs []*WorkflowRun) error {
 	for _, run := range runs {
 		params, err := c.FetchWorkflowRunParams(ctx, filter, run.JobRunID)
 		if err != nil {
@@ -127,13 +172,13 @@ func (c *WorkflowClient) EnrichWorkflowRunsWithParams(ctx context.Context, filte
 	return nil
 }
 
-func (c *WorkflowClient) FetchWorkflowRunParams(ctx context.Context, filter *WorkflowFilter, runId int) (*WorkflowRunParams, error) {
-	url, _, err := c.client.Actions.GetWorkflowRunLogs(ctx, filter.Owner, filter.Repo, int64(runId), true)
+func (c *apiWorkflowClient) FetchWorkflowRunParams(ctx context.Context, filter *WorkflowFilter, runId int) (*WorkflowRunParams, error) {
+	logsURL, _, err := c.client.Actions.GetWorkflowRunLogs(ctx, filter.Owner, filter.Repo, int64(runId), true)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	req, err := http.NewRequest("GET", logsURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +249,9 @@ func queryAndAdaptWorkflowRuns(client *g.Client, ctx context.Context, filter *Wo
 			JobStatus:        workflowRun.GetStatus(),
 			JobEvent:         workflowRun.GetEvent(),
 			JobRunTime:       workflowRun.CreatedAt.Time,
+			JobRunAttempt:    workflowRun.GetRunAttempt(),
+			JobStartTime:     workflowRun.GetRunStartedAt().Time,
+			JobUpdateTime:    workflowRun.GetUpdatedAt().Time,
 			JobBranch:        workflowRun.GetHeadBranch(),
 			JobCommitSha:     commitSha,
 			JobCommitAuthor:  commitAuthor,
@@ -224,9 +272,9 @@ func queryWorkflowRuns(client *g.Client, ctx context.Context, filter *WorkflowFi
 
 	currentFilter := *filter
 	if len(filter.WorkflowNames) == 0 {
-		allWorkflows := make([]string, len(existingWorkflows))
-		for i, workflow := range existingWorkflows {
-			allWorkflows[i] = workflow.GetName()
+		allWorkflows := make([]string, 0)
+		for _, workflow := range selectWorkflows(existingWorkflows, filter) {
+			allWorkflows = append(allWorkflows, workflow.GetName())
 		}
 		currentFilter.WorkflowNames = allWorkflows
 	}
@@ -243,14 +291,28 @@ func queryWorkflowRuns(client *g.Client, ctx context.Context, filter *WorkflowFi
 
 	filteredRuns := make([]*g.WorkflowRun, 0)
 	for name, id := range workflowIds {
-		currentPage := 0 // just the first page is retrieved, paging is not implemented for listing workflow runs
+		currentPage := 0 // just the first page is retrieved unless the runs are created since a time
 		pageOptions := newWorkflowRunPageOption(currentPage, filter.Limit)
-		workflowRuns, _, err := client.Actions.ListWorkflowRunsByID(ctx, filter.Owner, filter.Repo, int64(id), pageOptions)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't retrieve workflow runs for workflow '%s', err: %s", name, err)
+		since, isIncremental := filter.CreatedSince[name]
+		if isIncremental {
+			pageOptions = newWorkflowRunPageOption(currentPage, maxPageSize)
+			pageOptions.Created = ">=" + since.UTC().Format(time.RFC3339)
 		}
 
-		filteredRuns = append(filteredRuns, workflowRuns.WorkflowRuns...)
+		for {
+			workflowRuns, resp, err := client.Actions.ListWorkflowRunsByID(ctx, filter.Owner, filter.Repo, int64(id), pageOptions)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't retrieve workflow runs for workflow '%s', err: %s", name, err)
+			}
+
+			filteredRuns = append(filteredRuns, workflowRuns.WorkflowRuns...)
+
+			// every run created since the time is fetched, the runs beyond the first page would be lost otherwise
+			if !isIncremental || resp.NextPage == 0 {
+				break
+			}
+			pageOptions.Page = resp.NextPage
+		}
 	}
 
 	return filteredRuns, nil
@@ -281,6 +343,18 @@ func listAllWorkflows(client *g.Client, ctx context.Context, filter *WorkflowFil
 	return allResults, nil
 }
 
+// The workflows with the names of the filter or all workflows matching its pattern if no names are set,
+// names that don't belong to any workflow are ignored
+func selectWorkflows(workflows []*g.Workflow, filter *WorkflowFilter) []*g.Workflow {
+	result := make([]*g.Workflow, 0)
+	for _, workflow := range workflows {
+		if filter.TracksWorkflow(workflow.GetName()) {
+			result = append(result, workflow)
+		}
+	}
+	return result
+}
+
 func newWorkflowRunPageOption(page, limit int) *g.ListWorkflowRunsOptions {
 	pageSize := limit
 	if limit > maxPageSize || limit <= 0 {
@@ -303,7 +377,8 @@ func resolveWorkflowId(name string, workflows []*g.Workflow) int {
 	return -1
 }
 
-func sortWorkflowRuns(runs []*WorkflowRun) []*WorkflowRun {
+// Sorts the runs by workflow name and from the newest to the oldest run of each workflow
+func SortWorkflowRuns(runs []*WorkflowRun) []*WorkflowRun {
 	sort.Slice(runs, func(i, j int) bool {
 		if runs[i].WorkflowName == runs[j].WorkflowName {
 			return runs[i].JobRunTime.After(runs[j].JobRunTime)
@@ -0,0 +1,48 @@
+package github_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestFetchWorkflowRunsCreatedSince(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	since := time.Now().Add(-time.Hour)
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 1, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: since.Add(-time.Minute)})
+	// more runs created since the last sync than fit in a single page
+	for id := int64(2); id < 152; id++ {
+		fake.AddRun("foo", "bar", githubtest.Run{ID: id, WorkflowID: 1, Status: "completed", Conclusion: "success", CreatedAt: since.Add(time.Duration(id) * time.Second)})
+	}
+
+	filter := &github.WorkflowFilter{Owner: "foo", Repo: "bar", WorkflowNames: []string{"build"}, Limit: 10}
+	runs, err := fake.Client().FetchWorkflowRuns(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(runs) != 10 {
+		t.Errorf("got %d runs, wanted the limit of 10 without a created time", len(runs))
+	}
+
+	filter.CreatedSince = map[string]time.Time{"build": since}
+	runs, err = fake.Client().FetchWorkflowRuns(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(runs) != 150 {
+		t.Fatalf("got %d runs, wanted all 150 runs created since the time", len(runs))
+	}
+	seen := map[int]bool{}
+	for _, run := range runs {
+		if run.JobRunID == 1 || seen[run.JobRunID] {
+			t.Errorf("got run %d again or created before the time", run.JobRunID)
+		}
+		seen[run.JobRunID] = true
+	}
+}
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how far back a schedule is searched for its previous activation, covers even yearly schedules
+const maxCronLookBack = 5 * 366 * 24 * time.Hour
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronWeekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// A POSIX cron expression as used by the schedule trigger of github workflows, all times are in UTC
+type CronSchedule struct {
+	expr        string
+	minutes     []bool
+	hours       []bool
+	daysOfMonth []bool
+	months      []bool
+	daysOfWeek  []bool
+	// when both day fields are restricted a day matches if either of them matches
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression '%s' must have 5 fields but has %d", expr, len(fields))
+	}
+
+	var err error
+	schedule := &CronSchedule{
+		expr:          expr,
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}
+
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minutes in cron expression '%s', err: %s", expr, err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hours in cron expression '%s', err: %s", expr, err)
+	}
+	if schedule.daysOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid days of month in cron expression '%s', err: %s", expr, err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid months in cron expression '%s', err: %s", expr, err)
+	}
+	// 7 is an alias of sunday
+	if schedule.daysOfWeek, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid days of week in cron expression '%s', err: %s", expr, err)
+	}
+	schedule.daysOfWeek[0] = schedule.daysOfWeek[0] || schedule.daysOfWeek[7]
+
+	return schedule, nil
+}
+
+func (c *CronSchedule) String() string {
+	return c.expr
+}
+
+// The latest time at or before t at which the schedule fires, zero if it never fired within the look back period
+func (c *CronSchedule) Prev(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute)
+	limit := t.Add(-maxCronLookBack)
+
+	for t.After(limit) {
+		if !c.matchesDay(t) {
+			// jump to the last minute of the previous day
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+			continue
+		}
+
+		if !c.hours[t.Hour()] {
+			t = t.Truncate(time.Hour).Add(-time.Minute)
+			continue
+		}
+
+		if !c.minutes[t.Minute()] {
+			t = t.Add(-time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *CronSchedule) matchesDay(t time.Time) bool {
+	if !c.months[int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth := c.daysOfMonth[t.Day()]
+	dayOfWeek := c.daysOfWeek[int(t.Weekday())]
+
+	if !c.anyDayOfMonth && !c.anyDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+
+	return dayOfMonth && dayOfWeek
+}
+
+// Parses a comma separated list of values, ranges and steps, e.g. "*/15", "1-5", "mon,wed,fri", "0-30/10"
+func parseCronField(field string, min, max int, names map[string]int) ([]bool, error) {
+	result := make([]bool, max+1)
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step '%s'", part[i+1:])
+			}
+		}
+
+		from, to := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+
+			var err error
+			if from, err = parseCronValue(bounds[0], names); err != nil {
+				return nil, err
+			}
+
+			to = from
+			if len(bounds) == 2 {
+				if to, err = parseCronValue(bounds[1], names); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting from 5
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("range '%s' is not within %d-%d", rangePart, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			result[v] = true
+		}
+	}
+
+	return result, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", value)
+	}
+	return v, nil
+}
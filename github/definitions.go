@@ -0,0 +1,268 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	g "github.com/google/go-github/v42/github"
+	"gopkg.in/yaml.v3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EventPush             = "push"
+	EventPullRequest      = "pull_request"
+	EventSchedule         = "schedule"
+	EventWorkflowDispatch = "workflow_dispatch"
+)
+
+// github does not guarantee scheduled runs to be on time, runs are delayed by up to an hour under high load
+const DefaultScheduleDelay = time.Hour
+
+// the directory of workflow files, workflows with other paths (e.g. dynamic ones created by github) have no file
+const workflowsDir = ".github/workflows/"
+
+// The triggers of a workflow as declared in its .github/workflows/*.yml file
+type WorkflowDefinition struct {
+	WorkflowOwner string `json:"workflowOwner"`
+	WorkflowRepo  string `json:"workflowRepo"`
+	WorkflowName  string `json:"workflowName"`
+	WorkflowID    int    `json:"workflowId"`
+	Path          string `json:"path"`
+	// One of active, disabled_manually, disabled_inactivity
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"createdAt"`
+	// All events triggering the workflow
+	Events              []string        `json:"events"`
+	PushBranches        []string        `json:"pushBranches,omitempty"`
+	PullRequestBranches []string        `json:"pullRequestBranches,omitempty"`
+	Schedules           []string        `json:"schedules,omitempty"`
+	DispatchInputs      []DispatchInput `json:"dispatchInputs,omitempty"`
+	// Creation time of the latest run triggered by a schedule, zero if it never ran on schedule
+	LastScheduledRun time.Time `json:"lastScheduledRun"`
+}
+
+type DispatchInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+func (d *WorkflowDefinition) IsScheduled() bool {
+	return len(d.Schedules) > 0
+}
+
+// The latest time any of the schedules should have triggered a run at, allowing for the given delay
+// (github does not guarantee schedules to be on time), zero if there is no such time.
+func (d *WorkflowDefinition) ExpectedScheduledRun(now time.Time, delay time.Duration) time.Time {
+	expected := time.Time{}
+	for _, expr := range d.Schedules {
+		schedule, err := ParseCron(expr)
+		if err != nil {
+			continue
+		}
+
+		if prev := schedule.Prev(now.Add(-delay)); prev.After(expected) {
+			expected = prev
+		}
+	}
+
+	// schedules can't be missed before the workflow existed
+	if expected.Before(d.CreatedAt) {
+		return time.Time{}
+	}
+
+	return expected
+}
+
+// Whether the workflow didn't run although one of its schedules should have triggered it
+func (d *WorkflowDefinition) MissedSchedule(now time.Time, delay time.Duration) bool {
+	expected := d.ExpectedScheduledRun(now, delay)
+	if expected.IsZero() {
+		return false
+	}
+
+	return d.LastScheduledRun.Before(expected)
+}
+
+// Used to decode the triggers of a workflow file, the `on` key can be a single event, a list or a mapping of events
+type workflowFile struct {
+	Name string    `yaml:"name"`
+	On   yaml.Node `yaml:"on"`
+}
+
+type branchTrigger struct {
+	Branches []string `yaml:"branches"`
+}
+
+type scheduleTrigger struct {
+	Cron string `yaml:"cron"`
+}
+
+type dispatchTrigger struct {
+	Inputs map[string]struct {
+		Description string `yaml:"description"`
+		Type        string `yaml:"type"`
+		Default     string `yaml:"default"`
+		Required    bool   `yaml:"required"`
+	} `yaml:"inputs"`
+}
+
+// Parses the triggers of a workflow file, the owner, repo and workflow fields are left empty
+func ParseWorkflowDefinition(content []byte) (*WorkflowDefinition, error) {
+	file := &workflowFile{}
+	if err := yaml.Unmarshal(content, file); err != nil {
+		return nil, err
+	}
+
+	definition := &WorkflowDefinition{WorkflowName: file.Name, Events: []string{}}
+
+	switch file.On.Kind {
+	case 0:
+		return nil, fmt.Errorf("workflow has no 'on' triggers")
+	case yaml.ScalarNode:
+		definition.Events = append(definition.Events, file.On.Value)
+	case yaml.SequenceNode:
+		if err := file.On.Decode(&definition.Events); err != nil {
+			return nil, err
+		}
+	case yaml.MappingNode:
+		triggers := map[string]yaml.Node{}
+		if err := file.On.Decode(&triggers); err != nil {
+			return nil, err
+		}
+		if err := definition.decodeTriggers(triggers); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported 'on' triggers at line %d", file.On.Line)
+	}
+
+	sort.Strings(definition.Events)
+	return definition, nil
+}
+
+func (d *WorkflowDefinition) decodeTriggers(triggers map[string]yaml.Node) error {
+	for event, node := range triggers {
+		d.Events = append(d.Events, event)
+
+		// events without any configuration are null
+		if node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		switch event {
+		case EventPush, EventPullRequest:
+			trigger := &branchTrigger{}
+			if err := node.Decode(trigger); err != nil {
+				return fmt.Errorf("invalid '%s' trigger, err: %s", event, err)
+			}
+			if event == EventPush {
+				d.PushBranches = trigger.Branches
+			} else {
+				d.PullRequestBranches = trigger.Branches
+			}
+		case EventSchedule:
+			schedules := make([]scheduleTrigger, 0)
+			if err := node.Decode(&schedules); err != nil {
+				return fmt.Errorf("invalid '%s' trigger, err: %s", event, err)
+			}
+			for _, schedule := range schedules {
+				d.Schedules = append(d.Schedules, schedule.Cron)
+			}
+		case EventWorkflowDispatch:
+			trigger := &dispatchTrigger{}
+			if err := node.Decode(trigger); err != nil {
+				return fmt.Errorf("invalid '%s' trigger, err: %s", event, err)
+			}
+			for name, input := range trigger.Inputs {
+				d.DispatchInputs = append(d.DispatchInputs, DispatchInput{
+					Name:        name,
+					Description: input.Description,
+					Type:        input.Type,
+					Default:     input.Default,
+					Required:    input.Required,
+				})
+			}
+			sort.Slice(d.DispatchInputs, func(i, j int) bool {
+				return d.DispatchInputs[i].Name < d.DispatchInputs[j].Name
+			})
+		}
+	}
+
+	return nil
+}
+
+// Fetches and parses the workflow file of every workflow matching the filter. For scheduled workflows
+// the latest run triggered by a schedule is fetched as well. Workflows whose definition can't be fetched or parsed are
+// skipped.
+func (c *apiWorkflowClient) FetchWorkflowDefinitions(ctx context.Context, filter *WorkflowFilter) ([]*WorkflowDefinition, error) {
+	existingWorkflows, err := listAllWorkflows(c.client, ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*WorkflowDefinition, 0)
+	for _, workflow := range selectWorkflows(existingWorkflows, filter) {
+		if !strings.HasPrefix(workflow.GetPath(), workflowsDir) {
+			continue
+		}
+
+		definition, err := c.fetchWorkflowDefinition(ctx, filter, workflow)
+		if err != nil {
+			log.Warn("Failed fetching the definition of workflow: ", workflow.GetName(), " of repo: ", filter.GetRepoId(), ", it will be omitted, err: ", err)
+			continue
+		}
+		result = append(result, definition)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].WorkflowName < result[j].WorkflowName
+	})
+
+	return result, nil
+}
+
+func (c *apiWorkflowClient) fetchWorkflowDefinition(ctx context.Context, filter *WorkflowFilter, workflow *g.Workflow) (*WorkflowDefinition, error) {
+	content, _, _, err := c.client.Repositories.GetContents(ctx, filter.Owner, filter.Repo, workflow.GetPath(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch workflow file '%s', err: %s", workflow.GetPath(), err)
+	}
+
+	fileContent, err := content.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode workflow file '%s', err: %s", workflow.GetPath(), err)
+	}
+
+	definition, err := ParseWorkflowDefinition([]byte(fileContent))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse workflow file '%s', err: %s", workflow.GetPath(), err)
+	}
+
+	definition.WorkflowOwner = filter.Owner
+	definition.WorkflowRepo = filter.Repo
+	definition.WorkflowName = workflow.GetName()
+	definition.WorkflowID = int(workflow.GetID())
+	definition.Path = workflow.GetPath()
+	definition.State = workflow.GetState()
+	definition.CreatedAt = workflow.GetCreatedAt().Time
+
+	if definition.IsScheduled() {
+		opts := &g.ListWorkflowRunsOptions{Event: EventSchedule, ListOptions: *newPageOption(0, 1)}
+		runs, _, err := c.client.Actions.ListWorkflowRunsByID(ctx, filter.Owner, filter.Repo, workflow.GetID(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't retrieve scheduled runs of workflow '%s', err: %s", workflow.GetName(), err)
+		}
+		if len(runs.WorkflowRuns) > 0 {
+			definition.LastScheduledRun = runs.WorkflowRuns[0].GetCreatedAt().Time
+		}
+	}
+
+	return definition, nil
+}
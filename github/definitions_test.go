@@ -0,0 +1,105 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWorkflowDefinition(t *testing.T) {
+	definition, err := ParseWorkflowDefinition([]byte(`
+name: Nightly
+on:
+  push:
+    branches: [main, 'release/**']
+  schedule:
+    - cron: '30 2 * * 1-5'
+    - cron: '0 12 * * sun'
+  workflow_dispatch:
+    inputs:
+      version:
+        description: Version to build
+        required: true
+      dry-run:
+        type: boolean
+        default: 'false'
+  pull_request:
+jobs:
+  build:
+    runs-on: ubuntu-latest
+`))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := &WorkflowDefinition{
+		WorkflowName: "Nightly",
+		Events:       []string{"pull_request", "push", "schedule", "workflow_dispatch"},
+		PushBranches: []string{"main", "release/**"},
+		Schedules:    []string{"30 2 * * 1-5", "0 12 * * sun"},
+		DispatchInputs: []DispatchInput{
+			{Name: "dry-run", Type: "boolean", Default: "false"},
+			{Name: "version", Description: "Version to build", Required: true},
+		},
+	}
+
+	if !reflect.DeepEqual(definition, want) {
+		t.Errorf("got %+v, wanted %+v", definition, want)
+	}
+}
+
+func TestParseWorkflowDefinitionShortForms(t *testing.T) {
+	for content, want := range map[string][]string{
+		"on: push":                  {"push"},
+		"on: [push, pull_request]":  {"pull_request", "push"},
+		"'on':\n  workflow_call:\n": {"workflow_call"},
+	} {
+		definition, err := ParseWorkflowDefinition([]byte(content))
+		if err != nil {
+			t.Fatalf("%s: got error: %s", content, err)
+		}
+		if !reflect.DeepEqual(definition.Events, want) {
+			t.Errorf("%s: got %v, wanted %v", content, definition.Events, want)
+		}
+	}
+}
+
+func TestCronPrev(t *testing.T) {
+	now := time.Date(2022, 3, 9, 10, 17, 0, 0, time.UTC) // a wednesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2022, 3, 9, 10, 15, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2022, 3, 9, 2, 30, 0, 0, time.UTC)},
+		{"0 12 * * sun", time.Date(2022, 3, 6, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("%s: got error: %s", test.expr, err)
+		}
+		if got := schedule.Prev(now); !got.Equal(test.want) {
+			t.Errorf("%s: got %s, wanted %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestMissedSchedule(t *testing.T) {
+	now := time.Date(2022, 3, 9, 10, 17, 0, 0, time.UTC)
+	definition := &WorkflowDefinition{Schedules: []string{"0 3 * * *"}}
+
+	definition.LastScheduledRun = time.Date(2022, 3, 9, 3, 5, 0, 0, time.UTC)
+	if definition.MissedSchedule(now, DefaultScheduleDelay) {
+		t.Errorf("expected the schedule not to be missed")
+	}
+
+	definition.LastScheduledRun = time.Date(2022, 2, 1, 3, 5, 0, 0, time.UTC)
+	if !definition.MissedSchedule(now, DefaultScheduleDelay) {
+		t.Errorf("expected the schedule to be missed")
+	}
+}
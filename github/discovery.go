@@ -0,0 +1,174 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	g "github.com/google/go-github/v42/github"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	VisibilityAll     = "all"
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+const organizationType = "Organization"
+
+// Use this as a filter to discover all repositories of an owner (user or organization) that should be tracked
+type DiscoveryFilter struct {
+	Owner string
+	// Repositories having at least one of the topics are kept, all repositories are kept if empty
+	Topics []string
+	// Repositories whose name does not match the pattern are skipped, all repositories are kept if nil
+	NamePattern *regexp.Regexp
+	// One of all, public or private, all repositories are kept if empty
+	Visibility      string
+	IncludeArchived bool
+	// Workflows whose name does not match the pattern are skipped, all workflows are tracked if nil
+	WorkflowPattern *regexp.Regexp
+	Limit           int
+	Latest          LatestOptions
+}
+
+func (f *DiscoveryFilter) Validate() error {
+	if f.Owner == "" {
+		return fmt.Errorf("discovery owner can't be empty")
+	}
+
+	switch f.Visibility {
+	case "", VisibilityAll, VisibilityPublic, VisibilityPrivate:
+		return nil
+	default:
+		return fmt.Errorf(`visibility "%s" not supported`, f.Visibility)
+	}
+}
+
+// Lists all repositories of the owner matching the discovery filter and returns a workflow filter for each of them
+func (c *apiWorkflowClient) DiscoverRepositories(ctx context.Context, filter *DiscoveryFilter) ([]*WorkflowFilter, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	repos, err := listAllRepositories(c.client, ctx, filter.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list repositories of owner '%s', err: %s", filter.Owner, err)
+	}
+
+	result := make([]*WorkflowFilter, 0)
+	for _, repo := range repos {
+		if !filter.matches(repo) {
+			continue
+		}
+
+		result = append(result, &WorkflowFilter{
+			Owner:           filter.Owner,
+			Repo:            repo.GetName(),
+			WorkflowNames:   []string{},
+			WorkflowPattern: filter.WorkflowPattern,
+			Limit:           filter.Limit,
+			Latest:          filter.Latest,
+		})
+	}
+
+	return result, nil
+}
+
+func (f *DiscoveryFilter) matches(repo *g.Repository) bool {
+	if repo.GetArchived() && !f.IncludeArchived {
+		return false
+	}
+
+	if f.NamePattern != nil && !f.NamePattern.MatchString(repo.GetName()) {
+		return false
+	}
+
+	if f.Visibility != "" && f.Visibility != VisibilityAll && f.Visibility != repositoryVisibility(repo) {
+		return false
+	}
+
+	if len(f.Topics) == 0 {
+		return true
+	}
+
+	for _, wanted := range f.Topics {
+		for _, topic := range repo.Topics {
+			if wanted == topic {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func repositoryVisibility(repo *g.Repository) string {
+	// internal repositories of enterprises are neither public nor private
+	if repo.GetVisibility() != "" {
+		return repo.GetVisibility()
+	}
+
+	if repo.GetPrivate() {
+		return VisibilityPrivate
+	}
+
+	return VisibilityPublic
+}
+
+// Lists the repositories of an organization or of a user, the private repositories of a user are only listed if the token
+// belongs to the user
+func listAllRepositories(client *g.Client, ctx context.Context, owner string) ([]*g.Repository, error) {
+	user, _, err := client.Users.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticated := false
+	if user.GetType() != organizationType {
+		authenticated = isAuthenticatedUser(client, ctx, owner)
+	}
+
+	allResults := make([]*g.Repository, 0)
+	page := 0
+	for {
+		var repos []*g.Repository
+		var resp *g.Response
+
+		if user.GetType() == organizationType {
+			opts := &g.RepositoryListByOrgOptions{Type: VisibilityAll, ListOptions: *newPageOption(page, maxPageSize)}
+			repos, resp, err = client.Repositories.ListByOrg(ctx, owner, opts)
+		} else if authenticated {
+			// only the repositories of the authenticated user include the private ones
+			opts := &g.RepositoryListOptions{Visibility: VisibilityAll, Affiliation: "owner", ListOptions: *newPageOption(page, maxPageSize)}
+			repos, resp, err = client.Repositories.List(ctx, "", opts)
+		} else {
+			opts := &g.RepositoryListOptions{Type: "owner", ListOptions: *newPageOption(page, maxPageSize)}
+			repos, resp, err = client.Repositories.List(ctx, owner, opts)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		allResults = append(allResults, repos...)
+
+		if resp.NextPage == 0 {
+			return allResults, nil
+		}
+		page = resp.NextPage
+	}
+}
+
+// Whether the token of the client belongs to the user, false without a token
+func isAuthenticatedUser(client *g.Client, ctx context.Context, login string) bool {
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		log.Debug("Couldn't get the authenticated user, only public repositories of user: ", login, " are discovered, err: ", err)
+		return false
+	}
+	return strings.EqualFold(user.GetLogin(), login)
+}
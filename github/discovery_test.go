@@ -0,0 +1,81 @@
+package github_test
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func addDiscoveryRepositories(fake *githubtest.Server, owner string) {
+	fake.AddRepository(owner, githubtest.Repository{Name: "api", Visibility: "public", Topics: []string{"backend"}})
+	fake.AddRepository(owner, githubtest.Repository{Name: "api-legacy", Visibility: "public", Archived: true})
+	fake.AddRepository(owner, githubtest.Repository{Name: "secrets", Visibility: "private", Topics: []string{"backend"}})
+	fake.AddRepository(owner, githubtest.Repository{Name: "web", Visibility: "public", Topics: []string{"frontend"}})
+}
+
+func discoveredRepos(t *testing.T, fake *githubtest.Server, filter *github.DiscoveryFilter) []string {
+	t.Helper()
+	filters, err := fake.Client().DiscoverRepositories(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	repos := make([]string, 0)
+	for _, f := range filters {
+		if f.Owner != filter.Owner {
+			t.Errorf("got owner %s, wanted %s", f.Owner, filter.Owner)
+		}
+		repos = append(repos, f.Repo)
+	}
+	return repos
+}
+
+func TestDiscoverRepositories(t *testing.T) {
+	tests := []struct {
+		name   string
+		owner  githubtest.Owner
+		filter github.DiscoveryFilter
+		want   []string
+	}{
+		{"organization", githubtest.Owner{Login: "acme", Organization: true}, github.DiscoveryFilter{}, []string{"api", "secrets", "web"}},
+		{"other user", githubtest.Owner{Login: "foo"}, github.DiscoveryFilter{}, []string{"api", "web"}},
+		{"authenticated user", githubtest.Owner{Login: "foo", Authenticated: true}, github.DiscoveryFilter{}, []string{"api", "secrets", "web"}},
+		{"public", githubtest.Owner{Login: "acme", Organization: true}, github.DiscoveryFilter{Visibility: github.VisibilityPublic}, []string{"api", "web"}},
+		{"private", githubtest.Owner{Login: "foo", Authenticated: true}, github.DiscoveryFilter{Visibility: github.VisibilityPrivate}, []string{"secrets"}},
+		{"archived", githubtest.Owner{Login: "acme", Organization: true}, github.DiscoveryFilter{IncludeArchived: true}, []string{"api", "api-legacy", "secrets", "web"}},
+		{"name pattern", githubtest.Owner{Login: "acme", Organization: true}, github.DiscoveryFilter{NamePattern: regexp.MustCompile("^api"), IncludeArchived: true}, []string{"api", "api-legacy"}},
+		{"topics", githubtest.Owner{Login: "acme", Organization: true}, github.DiscoveryFilter{Topics: []string{"backend"}}, []string{"api", "secrets"}},
+	}
+
+	for _, test := range tests {
+		fake := githubtest.NewServer()
+		fake.AddOwner(test.owner)
+		addDiscoveryRepositories(fake, test.owner.Login)
+
+		filter := test.filter
+		filter.Owner = test.owner.Login
+		if got := discoveredRepos(t, fake, &filter); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, wanted %v", test.name, got, test.want)
+		}
+		fake.Close()
+	}
+}
+
+func TestDiscoverRepositoriesOfUserOtherThanAuthenticated(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	fake.AddOwner(githubtest.Owner{Login: "me", Authenticated: true})
+	fake.AddOwner(githubtest.Owner{Login: "foo"})
+	addDiscoveryRepositories(fake, "me")
+	addDiscoveryRepositories(fake, "foo")
+
+	want := []string{"api", "web"}
+	if got := discoveredRepos(t, fake, &github.DiscoveryFilter{Owner: "foo"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	g "github.com/google/go-github/v42/github"
+)
+
+// A job of a workflow run
+type RunJob struct {
+	RunID       int       `json:"runId"`
+	JobID       int64     `json:"jobId"`
+	JobName     string    `json:"jobName"`
+	Status      string    `json:"status"`
+	Conclusion  string    `json:"conclusion"`
+	HTMLURL     string    `json:"htmlUrl"`
+	StartedAt   time.Time `json:"startedAt"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// Fetches the jobs of all attempts of a run, a job that was re-run is returned once per attempt
+func (c *apiWorkflowClient) FetchRunJobs(ctx context.Context, filter *WorkflowFilter, runId int) ([]*RunJob, error) {
+	result := make([]*RunJob, 0)
+	opts := &g.ListWorkflowJobsOptions{Filter: "all", ListOptions: *newPageOption(0, maxPageSize)}
+
+	for {
+		jobs, resp, err := c.client.Actions.ListWorkflowJobs(ctx, filter.Owner, filter.Repo, int64(runId), opts)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't list jobs of run %d of '%s/%s', err: %s", runId, filter.Owner, filter.Repo, err)
+		}
+
+		for _, job := range jobs.Jobs {
+			result = append(result, &RunJob{
+				RunID:       runId,
+				JobID:       job.GetID(),
+				JobName:     job.GetName(),
+				Status:      job.GetStatus(),
+				Conclusion:  job.GetConclusion(),
+				HTMLURL:     job.GetHTMLURL(),
+				StartedAt:   job.GetStartedAt().Time,
+				CompletedAt: job.GetCompletedAt().Time,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package github_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestFetchRunJobsOfAllAttempts(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 10, WorkflowID: 1, RunAttempt: 2, Status: "completed", Conclusion: "success", CreatedAt: time.Now()})
+	fake.AddJob("foo", "bar", githubtest.Job{ID: 100, RunID: 10, RunAttempt: 1, Name: "test", Status: "completed", Conclusion: "failure"})
+	fake.AddJob("foo", "bar", githubtest.Job{ID: 101, RunID: 10, RunAttempt: 2, Name: "test", Status: "completed", Conclusion: "success"})
+
+	jobs, err := fake.Client().FetchRunJobs(context.Background(), &github.WorkflowFilter{Owner: "foo", Repo: "bar"}, 10)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, wanted the jobs of both attempts", len(jobs))
+	}
+
+	if jobs[0].Conclusion != "failure" || jobs[1].Conclusion != "success" {
+		t.Errorf("got conclusions %s and %s, wanted failure and success", jobs[0].Conclusion, jobs[1].Conclusion)
+	}
+}
@@ -0,0 +1,102 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	StatusCompleted  = "completed"
+	StatusQueued     = "queued"
+	StatusInProgress = "in_progress"
+)
+
+// Defines which runs are considered to be of the same kind when only the latest run of each kind is kept
+type GroupBy string
+
+const (
+	GroupByWorkflow GroupBy = "workflow"
+	GroupByBranch   GroupBy = "branch"
+	GroupByEvent    GroupBy = "event"
+)
+
+func ParseGroupBy(value string) (GroupBy, error) {
+	switch groupBy := GroupBy(strings.ToLower(value)); groupBy {
+	case GroupByWorkflow, GroupByBranch, GroupByEvent:
+		return groupBy, nil
+	case "":
+		return GroupByWorkflow, nil
+	default:
+		return "", fmt.Errorf(`group by "%s" not supported`, value)
+	}
+}
+
+// Used to narrow down which runs are kept when fetching the latest workflow runs
+type LatestOptions struct {
+	// Keep the latest run per workflow (default), per workflow and branch or per workflow and event
+	GroupBy GroupBy
+	// Ignore queued and in progress runs when picking the latest one, they are attached to it as its ActiveRun instead
+	CompletedOnly bool
+}
+
+// Whether a single run per workflow is enough to find the latest runs
+func (o LatestOptions) NeedsSingleRun() bool {
+	return (o.GroupBy == "" || o.GroupBy == GroupByWorkflow) && !o.CompletedOnly
+}
+
+func (r *WorkflowRun) IsCompleted() bool {
+	return r.JobStatus == StatusCompleted
+}
+
+func (r *WorkflowRun) groupKey(groupBy GroupBy) string {
+	key := fmt.Sprintf("%s/%s/%s", r.WorkflowOwner, r.WorkflowRepo, r.WorkflowName)
+	switch groupBy {
+	case GroupByBranch:
+		return key + "@" + r.JobBranch
+	case GroupByEvent:
+		return key + "#" + r.JobEvent
+	default:
+		return key
+	}
+}
+
+// Keeps only the latest run of each group. When only completed runs are considered, the latest queued or
+// in progress run of the group is attached to the latest completed one as its ActiveRun. Groups that have
+// no completed runs yet keep their latest active run.
+func LatestWorkflowRuns(runs []*WorkflowRun, opts LatestOptions) []*WorkflowRun {
+	latestRuns := map[string]*WorkflowRun{}
+	latestActiveRuns := map[string]*WorkflowRun{}
+
+	for _, run := range runs {
+		key := run.groupKey(opts.GroupBy)
+
+		latest := latestRuns
+		if opts.CompletedOnly && !run.IsCompleted() {
+			latest = latestActiveRuns
+		}
+
+		if existing, ok := latest[key]; !ok || existing.JobRunTime.Before(run.JobRunTime) {
+			latest[key] = run
+		}
+	}
+
+	result := make([]*WorkflowRun, 0)
+
+	for key, run := range latestRuns {
+		if activeRun, ok := latestActiveRuns[key]; ok && activeRun.JobRunTime.After(run.JobRunTime) {
+			// copy so that the runs passed in are not mutated
+			runCopy := *run
+			runCopy.ActiveRun = activeRun
+			run = &runCopy
+		}
+		result = append(result, run)
+	}
+
+	for key, activeRun := range latestActiveRuns {
+		if _, ok := latestRuns[key]; !ok {
+			result = append(result, activeRun)
+		}
+	}
+
+	return SortWorkflowRuns(result)
+}
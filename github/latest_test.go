@@ -0,0 +1,62 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatestWorkflowRuns(t *testing.T) {
+	now := time.Now()
+	run := func(id int, branch, status, conclusion string, age time.Duration) *WorkflowRun {
+		return &WorkflowRun{WorkflowName: "build", JobRunID: id, JobBranch: branch, JobStatus: status, JobConclusion: conclusion, JobRunTime: now.Add(-age)}
+	}
+
+	runs := []*WorkflowRun{
+		run(1, "main", "completed", "failure", 4*time.Hour),
+		run(2, "main", "completed", "success", 3*time.Hour),
+		run(3, "feature", "completed", "failure", 2*time.Hour),
+		run(4, "main", "in_progress", "", time.Hour),
+		run(5, "other", "queued", "", time.Minute),
+	}
+
+	tests := []struct {
+		name          string
+		opts          LatestOptions
+		wantRunIds    []int
+		wantActiveIds []int
+	}{
+		{"per workflow", LatestOptions{}, []int{5}, []int{0}},
+		{"per workflow completed", LatestOptions{CompletedOnly: true}, []int{3}, []int{5}},
+		{"per branch", LatestOptions{GroupBy: GroupByBranch}, []int{5, 4, 3}, []int{0, 0, 0}},
+		{"per branch completed", LatestOptions{GroupBy: GroupByBranch, CompletedOnly: true}, []int{5, 3, 2}, []int{0, 0, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := LatestWorkflowRuns(runs, test.opts)
+
+			gotRunIds := make([]int, 0)
+			gotActiveIds := make([]int, 0)
+			for _, r := range got {
+				gotRunIds = append(gotRunIds, r.JobRunID)
+				if r.ActiveRun != nil {
+					gotActiveIds = append(gotActiveIds, r.ActiveRun.JobRunID)
+				} else {
+					gotActiveIds = append(gotActiveIds, 0)
+				}
+			}
+
+			if !reflect.DeepEqual(gotRunIds, test.wantRunIds) {
+				t.Errorf("got runs %v, wanted %v", gotRunIds, test.wantRunIds)
+			}
+			if !reflect.DeepEqual(gotActiveIds, test.wantActiveIds) {
+				t.Errorf("got active runs %v, wanted %v", gotActiveIds, test.wantActiveIds)
+			}
+		})
+	}
+
+	if runs[1].ActiveRun != nil {
+		t.Errorf("expected the passed in runs not to be mutated")
+	}
+}
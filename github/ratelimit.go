@@ -0,0 +1,73 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The rate limit of the github API as reported by the latest response
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// A transport that keeps the rate limit headers of the latest github response so that the remaining requests can be
+// reported without calling the rate limit API
+type RateLimitTracker struct {
+	next http.RoundTripper
+
+	mu        sync.Mutex
+	rateLimit *RateLimit
+}
+
+func NewRateLimitTracker(next http.RoundTripper) *RateLimitTracker {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RateLimitTracker{next: next}
+}
+
+func (t *RateLimitTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if rateLimit, ok := parseRateLimit(resp.Header); ok {
+		t.mu.Lock()
+		t.rateLimit = rateLimit
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// The rate limit of the latest response that had one, false if no response had one yet
+func (t *RateLimitTracker) RateLimit() (RateLimit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.rateLimit == nil {
+		return RateLimit{}, false
+	}
+	return *t.rateLimit, true
+}
+
+func parseRateLimit(header http.Header) (*RateLimit, bool) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return nil, false
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return nil, false
+	}
+
+	rateLimit := &RateLimit{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rateLimit.Reset = time.Unix(reset, 0)
+	}
+	return rateLimit, true
+}
@@ -0,0 +1,39 @@
+package github_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestRateLimitTracker(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+
+	tracker := github.NewRateLimitTracker(nil)
+	client, _ := github.NewWorkflowClientWithBaseURL(&http.Client{Transport: tracker}, fake.URL())
+	filter := &github.WorkflowFilter{Owner: "foo", Repo: "bar"}
+
+	if _, err := client.FetchWorkflowRuns(context.Background(), filter); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if _, ok := tracker.RateLimit(); ok {
+		t.Errorf("got a rate limit from responses without rate limit headers")
+	}
+
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	fake.SetRateLimit(&githubtest.RateLimit{Limit: 5000, Remaining: 4321, Reset: reset})
+	if _, err := client.FetchWorkflowRuns(context.Background(), filter); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	rateLimit, ok := tracker.RateLimit()
+	if !ok || rateLimit.Limit != 5000 || rateLimit.Remaining != 4321 || !rateLimit.Reset.Equal(reset) {
+		t.Errorf("got rate limit %+v, wanted 4321 of 5000 remaining until %s", rateLimit, reset)
+	}
+}
@@ -0,0 +1,223 @@
+package github
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// query params and headers whose values must never end up in a recording, e.g. the signature of log download urls
+var sensitiveNameRegex = regexp.MustCompile(`(?i)(sig|token|credential|secret|password|jwt|auth|cookie)`)
+
+// A single request/response pair as stored on disk
+type recordedInteraction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Creates a transport that writes every request/response going through it to dir as a fixture
+// that can be served back by a replay transport. Tokens and auth headers are scrubbed.
+func NewRecordingTransport(dir string, next http.RoundTripper) (http.RoundTripper, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &recordingTransport{dir: dir, next: next}, nil
+}
+
+// Creates a transport that serves the fixtures written by a recording transport without any network access.
+// Requests that were never recorded fail with an error.
+func NewReplayTransport(dir string) (http.RoundTripper, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay path %s is not a directory", dir)
+	}
+
+	return &replayTransport{dir: dir}, nil
+}
+
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := &recordedInteraction{
+		Request: recordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL.String()),
+			Header: scrubHeader(req.Header),
+		},
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     scrubHeader(resp.Header),
+			Body:       body,
+		},
+	}
+
+	if err := writeInteraction(t.dir, interaction); err != nil {
+		return nil, fmt.Errorf("failed recording %s %s, err: %s", req.Method, interaction.Request.URL, err)
+	}
+
+	return resp, nil
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := scrubURL(req.URL.String())
+
+	fileBytes, err := ioutil.ReadFile(filepath.Join(t.dir, interactionFileName(req.Method, key)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recording found for %s %s", req.Method, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &recordedInteraction{}
+	if err := json.Unmarshal(fileBytes, interaction); err != nil {
+		return nil, fmt.Errorf("failed reading recording of %s %s, err: %s", req.Method, key, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+func writeInteraction(dir string, interaction *recordedInteraction) error {
+	fileBytes, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that concurrent recordings of the same request never interleave
+	tmpFile, err := ioutil.TempFile(dir, ".recording-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmpFile.Write(fileBytes); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filepath.Join(dir, interactionFileName(interaction.Request.Method, interaction.Request.URL)))
+}
+
+// Recordings are keyed by the method and the scrubbed url, a request recorded multiple times keeps only its latest response
+func interactionFileName(method, scrubbedURL string) string {
+	hash := sha256.Sum256([]byte(method + " " + scrubbedURL))
+	return fmt.Sprintf("%s-%s.json", strings.ToLower(method), hex.EncodeToString(hash[:])[0:16])
+}
+
+func scrubURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	parsedURL.User = nil
+	query := parsedURL.Query()
+	for name := range query {
+		if sensitiveNameRegex.MatchString(name) {
+			query.Set(name, redacted)
+		}
+	}
+	parsedURL.RawQuery = query.Encode()
+
+	return parsedURL.String()
+}
+
+func scrubHeader(header http.Header) http.Header {
+	result := http.Header{}
+	for name, values := range header {
+		if sensitiveNameRegex.MatchString(name) {
+			continue
+		}
+
+		if http.CanonicalHeaderKey(name) == "Location" || http.CanonicalHeaderKey(name) == "Link" {
+			scrubbed := make([]string, len(values))
+			for i, value := range values {
+				scrubbed[i] = scrubLinks(value)
+			}
+			result[name] = scrubbed
+			continue
+		}
+
+		result[name] = values
+	}
+	return result
+}
+
+// Link headers contain multiple urls in angle brackets, e.g. <https://api.github.com/...?page=2>; rel="next"
+var linkRegex = regexp.MustCompile(`<([^>]*)>`)
+
+func scrubLinks(value string) string {
+	if !strings.Contains(value, "<") {
+		return scrubURL(value)
+	}
+
+	return linkRegex.ReplaceAllStringFunc(value, func(link string) string {
+		return "<" + scrubURL(link[1:len(link)-1]) + ">"
+	})
+}
@@ -0,0 +1,87 @@
+package github_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	filter := &github.WorkflowFilter{Owner: "foo", Repo: "bar"}
+
+	fake := githubtest.NewServer()
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 10, WorkflowID: 1, Status: "completed", Conclusion: "success"})
+	fake.SetRunLogs("foo", "bar", 10, map[string]string{
+		"build/1_checkout.txt": "2022-02-24T11:10:24.8627684Z env:\n2022-02-24T11:10:24.8628366Z   version: 1.2.3\n2022-02-24T11:10:24.8629861Z ##[endgroup]\n",
+	})
+
+	recorder, err := github.NewRecordingTransport(dir, nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	recordingClient, _ := github.NewWorkflowClientWithBaseURL(&http.Client{Transport: &authTransport{next: recorder}}, fake.URL())
+	recordedRuns, err := recordingClient.FetchWorkflowRuns(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	recordedParams, err := recordingClient.FetchWorkflowRunParams(context.Background(), filter, 10)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	// nothing should be fetched from the network while replaying
+	fake.Close()
+
+	replayer, err := github.NewReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	replayClient, _ := github.NewWorkflowClientWithBaseURL(&http.Client{Transport: replayer}, fake.URL())
+	replayedRuns, err := replayClient.FetchWorkflowRuns(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	replayedParams, err := replayClient.FetchWorkflowRunParams(context.Background(), filter, 10)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if !reflect.DeepEqual(recordedRuns, replayedRuns) {
+		t.Errorf("got %+v, wanted %+v", replayedRuns, recordedRuns)
+	}
+	if !reflect.DeepEqual(recordedParams, replayedParams) {
+		t.Errorf("got %+v, wanted %+v", replayedParams, recordedParams)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		body, _ := ioutil.ReadFile(file)
+		for _, secret := range []string{"secret-token", "fake-signature"} {
+			if strings.Contains(string(body), secret) {
+				t.Errorf("recording %s contains %s", file, secret)
+			}
+		}
+	}
+}
+
+// adds the auth header inside the recorded transport like an oauth2 transport would
+type authTransport struct {
+	next http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer secret-token")
+	return t.next.RoundTrip(req)
+}
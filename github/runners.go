@@ -0,0 +1,239 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	g "github.com/google/go-github/v42/github"
+)
+
+const (
+	RunnerOnline  = "online"
+	RunnerOffline = "offline"
+)
+
+// labels of runners hosted by github, jobs running on them never wait for a self-hosted runner
+var githubHostedLabelRegex = regexp.MustCompile(`(?i)^(ubuntu|windows|macos)-`)
+
+type Runner struct {
+	Owner string `json:"owner"`
+	// empty for runners of an organization
+	Repo   string   `json:"repo,omitempty"`
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	OS     string   `json:"os"`
+	Labels []string `json:"labels"`
+	Status string   `json:"status"`
+	Busy   bool     `json:"busy"`
+}
+
+func (r *Runner) IsOnline() bool {
+	return r.Status == RunnerOnline
+}
+
+// Whether the runner has all of the labels, i.e. a job with the labels can be picked up by it
+func (r *Runner) HasLabels(labels []string) bool {
+	for _, label := range labels {
+		found := false
+		for _, runnerLabel := range r.Labels {
+			if strings.EqualFold(label, runnerLabel) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// The runners of a repository or of an organization if Repo is empty
+type RunnerScope struct {
+	Owner string
+	Repo  string
+}
+
+func (s RunnerScope) String() string {
+	if s.Repo == "" {
+		return s.Owner
+	}
+	return fmt.Sprintf("%s/%s", s.Owner, s.Repo)
+}
+
+// A job of a queued or in progress run that is still waiting for a runner
+type QueuedJob struct {
+	Owner        string    `json:"owner"`
+	Repo         string    `json:"repo"`
+	WorkflowName string    `json:"workflowName"`
+	RunID        int       `json:"runId"`
+	RunNumber    int       `json:"runNumber"`
+	JobID        int64     `json:"jobId"`
+	JobName      string    `json:"jobName"`
+	JobHTMLURL   string    `json:"jobHtmlUrl"`
+	Labels       []string  `json:"labels"`
+	QueuedSince  time.Time `json:"queuedSince"`
+	// Why the job is still waiting, set by CorrelateQueuedJobs
+	WaitingReason string `json:"waitingReason,omitempty"`
+}
+
+func (j *QueuedJob) IsGithubHosted() bool {
+	for _, label := range j.Labels {
+		if strings.EqualFold(label, "self-hosted") {
+			return false
+		}
+	}
+
+	for _, label := range j.Labels {
+		if githubHostedLabelRegex.MatchString(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *apiWorkflowClient) FetchRunners(ctx context.Context, scope *RunnerScope) ([]*Runner, error) {
+	allRunners := make([]*g.Runner, 0)
+	page := 0
+	for {
+		var runners *g.Runners
+		var resp *g.Response
+		var err error
+
+		if scope.Repo == "" {
+			runners, resp, err = c.client.Actions.ListOrganizationRunners(ctx, scope.Owner, newPageOption(page, maxPageSize))
+		} else {
+			runners, resp, err = c.client.Actions.ListRunners(ctx, scope.Owner, scope.Repo, newPageOption(page, maxPageSize))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't list runners of '%s', err: %s", scope, err)
+		}
+
+		allRunners = append(allRunners, runners.Runners...)
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	result := make([]*Runner, 0)
+	for _, runner := range allRunners {
+		labels := make([]string, 0)
+		for _, label := range runner.Labels {
+			labels = append(labels, label.GetName())
+		}
+
+		result = append(result, &Runner{
+			Owner:  scope.Owner,
+			Repo:   scope.Repo,
+			ID:     runner.GetID(),
+			Name:   runner.GetName(),
+			OS:     runner.GetOS(),
+			Labels: labels,
+			Status: runner.GetStatus(),
+			Busy:   runner.GetBusy(),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Fetches the jobs of all queued and in progress runs of the repository that are still waiting for a runner
+func (c *apiWorkflowClient) FetchQueuedJobs(ctx context.Context, filter *WorkflowFilter) ([]*QueuedJob, error) {
+	result := make([]*QueuedJob, 0)
+
+	for _, status := range []string{StatusQueued, StatusInProgress} {
+		opts := &g.ListWorkflowRunsOptions{Status: status, ListOptions: *newPageOption(0, maxPageSize)}
+		runs, _, err := c.client.Actions.ListRepositoryWorkflowRuns(ctx, filter.Owner, filter.Repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't list %s runs of '%s/%s', err: %s", status, filter.Owner, filter.Repo, err)
+		}
+
+		for _, run := range runs.WorkflowRuns {
+			jobs, _, err := c.client.Actions.ListWorkflowJobs(ctx, filter.Owner, filter.Repo, run.GetID(), &g.ListWorkflowJobsOptions{ListOptions: *newPageOption(0, maxPageSize)})
+			if err != nil {
+				return nil, fmt.Errorf("couldn't list jobs of run %d of '%s/%s', err: %s", run.GetID(), filter.Owner, filter.Repo, err)
+			}
+
+			for _, job := range jobs.Jobs {
+				if job.GetStatus() != StatusQueued {
+					continue
+				}
+
+				queuedSince := run.GetCreatedAt().Time
+				if job.StartedAt != nil && !job.GetStartedAt().Time.IsZero() {
+					// jobs that are queued have the time they were queued at as start time
+					queuedSince = job.GetStartedAt().Time
+				}
+
+				result = append(result, &QueuedJob{
+					Owner:        filter.Owner,
+					Repo:         filter.Repo,
+					WorkflowName: run.GetName(),
+					RunID:        int(run.GetID()),
+					RunNumber:    run.GetRunNumber(),
+					JobID:        job.GetID(),
+					JobName:      job.GetName(),
+					JobHTMLURL:   job.GetHTMLURL(),
+					Labels:       job.Labels,
+					QueuedSince:  queuedSince,
+				})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].QueuedSince.Before(result[j].QueuedSince)
+	})
+
+	return result, nil
+}
+
+// Explains why each queued job is still waiting by matching its labels against the known runners
+// of its repository and of the repository owner (organization runners).
+func CorrelateQueuedJobs(jobs []*QueuedJob, runners []*Runner) {
+	for _, job := range jobs {
+		if job.IsGithubHosted() {
+			job.WaitingReason = "waiting for a github hosted runner"
+			continue
+		}
+
+		matching, online, idle := 0, 0, 0
+		for _, runner := range runners {
+			if runner.Owner != job.Owner || (runner.Repo != "" && runner.Repo != job.Repo) {
+				continue
+			}
+			if !runner.HasLabels(job.Labels) {
+				continue
+			}
+
+			matching++
+			if runner.IsOnline() {
+				online++
+				if !runner.Busy {
+					idle++
+				}
+			}
+		}
+
+		labels := strings.Join(job.Labels, ", ")
+		switch {
+		case matching == 0:
+			job.WaitingReason = fmt.Sprintf("no runner with labels [%s] is registered", labels)
+		case online == 0:
+			job.WaitingReason = fmt.Sprintf("none of the %d runners with labels [%s] is online", matching, labels)
+		case idle == 0:
+			job.WaitingReason = fmt.Sprintf("all %d online runners with labels [%s] are busy", online, labels)
+		default:
+			job.WaitingReason = fmt.Sprintf("%d idle runners with labels [%s] are online", idle, labels)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package github_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestCorrelateQueuedJobs(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	fake.AddOwner(githubtest.Owner{Login: "foo", Organization: true})
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 10, WorkflowID: 1, Status: "queued", CreatedAt: time.Now()})
+	fake.AddJob("foo", "bar", githubtest.Job{ID: 100, RunID: 10, Name: "train", Status: "queued", Labels: []string{"self-hosted", "gpu"}})
+	fake.AddJob("foo", "bar", githubtest.Job{ID: 101, RunID: 10, Name: "lint", Status: "queued", Labels: []string{"ubuntu-latest"}})
+	fake.AddJob("foo", "bar", githubtest.Job{ID: 102, RunID: 10, Name: "test", Status: "queued", Labels: []string{"self-hosted", "linux"}})
+	fake.AddRunner("foo", "", githubtest.Runner{ID: 1, Name: "gpu-1", Labels: []string{"self-hosted", "gpu"}, Status: "offline"})
+	fake.AddRunner("foo", "bar", githubtest.Runner{ID: 2, Name: "linux-1", Labels: []string{"self-hosted", "linux"}, Status: "online", Busy: true})
+
+	client := fake.Client()
+	ctx := context.Background()
+
+	orgRunners, err := client.FetchRunners(ctx, &github.RunnerScope{Owner: "foo"})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	repoRunners, err := client.FetchRunners(ctx, &github.RunnerScope{Owner: "foo", Repo: "bar"})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	jobs, err := client.FetchQueuedJobs(ctx, &github.WorkflowFilter{Owner: "foo", Repo: "bar"})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	github.CorrelateQueuedJobs(jobs, append(orgRunners, repoRunners...))
+
+	want := map[string]string{
+		"train": "none of the 1 runners with labels [self-hosted, gpu] is online",
+		"lint":  "waiting for a github hosted runner",
+		"test":  "all 1 online runners with labels [self-hosted, linux] are busy",
+	}
+
+	if len(jobs) != len(want) {
+		t.Fatalf("got %d queued jobs, wanted %d", len(jobs), len(want))
+	}
+
+	for _, job := range jobs {
+		if job.WaitingReason != want[job.JobName] {
+			t.Errorf("job %s: got reason %q, wanted %q", job.JobName, job.WaitingReason, want[job.JobName])
+		}
+	}
+}
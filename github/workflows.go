@@ -0,0 +1,132 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	g "github.com/google/go-github/v42/github"
+)
+
+const (
+	WorkflowActive             = "active"
+	WorkflowDisabledManually   = "disabled_manually"
+	WorkflowDisabledInactivity = "disabled_inactivity"
+)
+
+// A workflow of a repository together with its latest run
+type Workflow struct {
+	WorkflowOwner string    `json:"workflowOwner"`
+	WorkflowRepo  string    `json:"workflowRepo"`
+	WorkflowName  string    `json:"workflowName"`
+	WorkflowID    int       `json:"workflowId"`
+	Path          string    `json:"path"`
+	State         string    `json:"state"`
+	HTMLURL       string    `json:"htmlUrl"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	// Creation time of the latest run, zero if the workflow never ran
+	LastRunTime time.Time `json:"lastRunTime"`
+	LastRunURL  string    `json:"lastRunUrl,omitempty"`
+	// Whether the workflow hasn't run for a while, set by MarkStaleWorkflows
+	Stale bool `json:"stale"`
+}
+
+func (w *Workflow) IsActive() bool {
+	return w.State == WorkflowActive
+}
+
+// The time the workflow was last active at, i.e. its latest run or its creation if it never ran
+func (w *Workflow) LastActivity() time.Time {
+	if w.LastRunTime.IsZero() {
+		return w.CreatedAt
+	}
+	return w.LastRunTime
+}
+
+// Marks the workflows that haven't run within the given period before now as stale
+func MarkStaleWorkflows(workflows []*Workflow, now time.Time, staleAfter time.Duration) {
+	for _, workflow := range workflows {
+		workflow.Stale = now.Sub(workflow.LastActivity()) > staleAfter
+	}
+}
+
+// Fetches all workflows matching the filter together with their latest run
+func (c *apiWorkflowClient) FetchWorkflows(ctx context.Context, filter *WorkflowFilter) ([]*Workflow, error) {
+	existingWorkflows, err := listAllWorkflows(c.client, ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Workflow, 0)
+	for _, workflow := range selectWorkflows(existingWorkflows, filter) {
+		runs, _, err := c.client.Actions.ListWorkflowRunsByID(ctx, filter.Owner, filter.Repo, workflow.GetID(), newWorkflowRunPageOption(0, 1))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't retrieve the latest run of workflow '%s', err: %s", workflow.GetName(), err)
+		}
+
+		result = append(result, adaptWorkflow(filter, workflow, runs.WorkflowRuns))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].WorkflowName < result[j].WorkflowName
+	})
+
+	return result, nil
+}
+
+func (c *apiWorkflowClient) EnableWorkflow(ctx context.Context, filter *WorkflowFilter, workflowName string) error {
+	id, err := c.resolveWorkflowId(ctx, filter, workflowName)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Actions.EnableWorkflowByID(ctx, filter.Owner, filter.Repo, int64(id))
+	return err
+}
+
+func (c *apiWorkflowClient) DisableWorkflow(ctx context.Context, filter *WorkflowFilter, workflowName string) error {
+	id, err := c.resolveWorkflowId(ctx, filter, workflowName)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Actions.DisableWorkflowByID(ctx, filter.Owner, filter.Repo, int64(id))
+	return err
+}
+
+func (c *apiWorkflowClient) resolveWorkflowId(ctx context.Context, filter *WorkflowFilter, workflowName string) (int, error) {
+	existingWorkflows, err := listAllWorkflows(c.client, ctx, filter)
+	if err != nil {
+		return -1, err
+	}
+
+	id := resolveWorkflowId(workflowName, existingWorkflows)
+	if id == -1 {
+		return -1, fmt.Errorf("can't resolve ID of workflow with name '%s'", workflowName)
+	}
+
+	return id, nil
+}
+
+func adaptWorkflow(filter *WorkflowFilter, workflow *g.Workflow, latestRuns []*g.WorkflowRun) *Workflow {
+	result := &Workflow{
+		WorkflowOwner: filter.Owner,
+		WorkflowRepo:  filter.Repo,
+		WorkflowName:  workflow.GetName(),
+		WorkflowID:    int(workflow.GetID()),
+		Path:          workflow.GetPath(),
+		State:         workflow.GetState(),
+		HTMLURL:       workflow.GetHTMLURL(),
+		CreatedAt:     workflow.GetCreatedAt().Time,
+		UpdatedAt:     workflow.GetUpdatedAt().Time,
+	}
+
+	if len(latestRuns) > 0 {
+		result.LastRunTime = latestRuns[0].GetCreatedAt().Time
+		result.LastRunURL = latestRuns[0].GetHTMLURL()
+	}
+
+	return result
+}
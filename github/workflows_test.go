@@ -0,0 +1,100 @@
+package github_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestFetchWorkflowsMarksStaleWorkflows(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	now := time.Now()
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build", CreatedAt: now.AddDate(-1, 0, 0)})
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 2, Name: "nightly", CreatedAt: now.AddDate(-1, 0, 0), State: github.WorkflowDisabledInactivity})
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 3, Name: "release", CreatedAt: now.AddDate(0, 0, -1)})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 10, WorkflowID: 1, CreatedAt: now.AddDate(0, 0, -90)})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 11, WorkflowID: 1, CreatedAt: now.AddDate(0, 0, -2)})
+	fake.AddRun("foo", "bar", githubtest.Run{ID: 12, WorkflowID: 2, CreatedAt: now.AddDate(0, 0, -70)})
+
+	workflows, err := fake.Client().FetchWorkflows(context.Background(), &github.WorkflowFilter{Owner: "foo", Repo: "bar"})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	github.MarkStaleWorkflows(workflows, now, 30*24*time.Hour)
+
+	want := map[string]bool{"build": false, "nightly": true, "release": false}
+	if len(workflows) != len(want) {
+		t.Fatalf("got %d workflows, wanted %d", len(workflows), len(want))
+	}
+
+	for _, workflow := range workflows {
+		if workflow.Stale != want[workflow.WorkflowName] {
+			t.Errorf("workflow %s: got stale=%t, wanted %t", workflow.WorkflowName, workflow.Stale, want[workflow.WorkflowName])
+		}
+	}
+
+	if !workflows[0].LastRunTime.Equal(now.AddDate(0, 0, -2)) {
+		t.Errorf("got last run %s, wanted the latest run", workflows[0].LastRunTime)
+	}
+}
+
+func TestDisableAndEnableWorkflow(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+
+	client := fake.Client()
+	ctx := context.Background()
+	filter := &github.WorkflowFilter{Owner: "foo", Repo: "bar"}
+
+	assertState := func(want string) {
+		t.Helper()
+		workflows, err := client.FetchWorkflows(ctx, filter)
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		if workflows[0].State != want {
+			t.Errorf("got state %s, wanted %s", workflows[0].State, want)
+		}
+	}
+
+	if err := client.DisableWorkflow(ctx, filter, "build"); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	assertState(github.WorkflowDisabledManually)
+
+	if err := client.EnableWorkflow(ctx, filter, "build"); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	assertState(github.WorkflowActive)
+
+	if err := client.EnableWorkflow(ctx, filter, "missing"); err == nil {
+		t.Errorf("expected an error for an unknown workflow")
+	}
+}
+
+func TestFetchWorkflowDefinitionsSkipsBadFiles(t *testing.T) {
+	fake := githubtest.NewServer()
+	defer fake.Close()
+
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build", Path: ".github/workflows/build.yml"})
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 2, Name: "broken", Path: ".github/workflows/broken.yml"})
+	fake.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 3, Name: "missing", Path: ".github/workflows/missing.yml"})
+	fake.SetFile("foo", "bar", ".github/workflows/build.yml", "on: push\n")
+	fake.SetFile("foo", "bar", ".github/workflows/broken.yml", "on: [push, [pull_request]]\n")
+
+	definitions, err := fake.Client().FetchWorkflowDefinitions(context.Background(), &github.WorkflowFilter{Owner: "foo", Repo: "bar"})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(definitions) != 1 || definitions[0].WorkflowName != "build" || definitions[0].Events[0] != "push" {
+		t.Errorf("got %d definitions, wanted only the one of build", len(definitions))
+	}
+}
@@ -0,0 +1,893 @@
+// Package githubtest provides an in-process fake of the github Actions API that can be seeded from Go code.
+// It is meant to test code built on top of github.WorkflowClient without hitting the network.
+package githubtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "github.com/google/go-github/v42/github"
+	"github.com/gorilla/mux"
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+const defaultPageSize = 30
+
+// Lists repositories regardless of their visibility
+const visibilityAll = "all"
+
+type Owner struct {
+	Login        string
+	Organization bool
+	// The user the token of the client belongs to, the private repositories of other users aren't listed
+	Authenticated bool
+}
+
+type Repository struct {
+	Name string
+	// One of public, private or internal
+	Visibility string
+	Archived   bool
+	Topics     []string
+}
+
+type Workflow struct {
+	ID        int64
+	Name      string
+	Path      string
+	State     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Run struct {
+	ID            int64
+	WorkflowID    int64
+	Name          string
+	RunNumber     int
+	RunAttempt    int
+	Status        string
+	Conclusion    string
+	Event         string
+	Branch        string
+	HTMLURL       string
+	CreatedAt     time.Time
+	RunStartedAt  time.Time
+	UpdatedAt     time.Time
+	CommitSha     string
+	CommitAuthor  string
+	CommitMessage string
+	CommitTime    time.Time
+}
+
+type Runner struct {
+	ID     int64
+	Name   string
+	OS     string
+	Labels []string
+	// One of online or offline
+	Status string
+	Busy   bool
+}
+
+type Job struct {
+	ID          int64
+	RunID       int64
+	Name        string
+	Status      string
+	Conclusion  string
+	Labels      []string
+	StartedAt   time.Time
+	CompletedAt time.Time
+	HTMLURL     string
+	// The attempt of the run the job belongs to, zero means the latest attempt
+	RunAttempt int
+}
+
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+type injectedError struct {
+	pattern   *regexp.Regexp
+	status    int
+	remaining int
+}
+
+type injectedDelay struct {
+	pattern *regexp.Regexp
+	delay   time.Duration
+}
+
+type repository struct {
+	Repository
+	workflows []*Workflow
+	runs      []*Run
+	jobs      []*Job
+	runners   []*Runner
+	logs      map[int64]map[string]string
+	files     map[string]string
+}
+
+// Fake github API, all seeding methods are safe to call while the server is serving requests
+type Server struct {
+	srv *httptest.Server
+
+	mu           sync.Mutex
+	owners       map[string]*Owner
+	repos        map[string]map[string]*repository
+	orgRunners   map[string][]*Runner
+	rateLimit    *RateLimit
+	errors       []*injectedError
+	delays       []*injectedDelay
+	requestCount int
+}
+
+// Starts a new fake github API server, it should be closed once done
+func NewServer() *Server {
+	s := &Server{
+		owners:     make(map[string]*Owner),
+		repos:      make(map[string]map[string]*repository),
+		orgRunners: make(map[string][]*Runner),
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/user", s.getAuthenticatedUser).Methods(http.MethodGet)
+	r.HandleFunc("/user/repos", s.listAuthenticatedUserRepositories).Methods(http.MethodGet)
+	r.HandleFunc("/users/{owner}", s.getOwner).Methods(http.MethodGet)
+	r.HandleFunc("/orgs/{owner}/repos", s.listRepositories(true)).Methods(http.MethodGet)
+	r.HandleFunc("/users/{owner}/repos", s.listRepositories(false)).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/workflows", s.listWorkflows).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/workflows/{id:[0-9]+}/runs", s.listWorkflowRuns).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/workflows/{id:[0-9]+}/enable", s.setWorkflowState("active")).Methods(http.MethodPut)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/workflows/{id:[0-9]+}/disable", s.setWorkflowState("disabled_manually")).Methods(http.MethodPut)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/runs", s.listRepositoryRuns).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/runs/{id:[0-9]+}/jobs", s.listJobs).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/runs/{id:[0-9]+}/logs", s.redirectToLogs).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/contents/{path:.+}", s.getContents).Methods(http.MethodGet)
+	r.HandleFunc("/repos/{owner}/{repo}/actions/runners", s.listRepositoryRunners).Methods(http.MethodGet)
+	r.HandleFunc("/orgs/{owner}/actions/runners", s.listOrganizationRunners).Methods(http.MethodGet)
+	r.HandleFunc("/_logs/{owner}/{repo}/{id:[0-9]+}.zip", s.downloadLogs).Methods(http.MethodGet)
+
+	s.srv = httptest.NewServer(s.middleware(r))
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Base URL of the fake API
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Creates a workflow client that talks to this server
+func (s *Server) Client() github.WorkflowClient {
+	client, err := github.NewWorkflowClientWithBaseURL(s.srv.Client(), s.srv.URL)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// Number of requests served so far, including the failed ones
+func (s *Server) RequestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requestCount
+}
+
+func (s *Server) AddOwner(owner Owner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owners[owner.Login] = &owner
+}
+
+func (s *Server) AddRepository(owner string, repo Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repository(owner, repo.Name).Repository = repo
+}
+
+func (s *Server) AddWorkflow(owner, repo string, workflow Workflow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if workflow.State == "" {
+		workflow.State = "active"
+	}
+	r := s.repository(owner, repo)
+	r.workflows = append(r.workflows, &workflow)
+}
+
+// Adds a run of an already added workflow, the name of the run defaults to the name of the workflow. A run with the ID
+// of an added run replaces it, e.g. with the next attempt of a re-run.
+func (s *Server) AddRun(owner, repo string, run Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repository(owner, repo)
+	if run.Name == "" {
+		for _, w := range r.workflows {
+			if w.ID == run.WorkflowID {
+				run.Name = w.Name
+			}
+		}
+	}
+	if run.RunAttempt == 0 {
+		run.RunAttempt = 1
+	}
+	for i, added := range r.runs {
+		if added.ID == run.ID {
+			r.runs[i] = &run
+			return
+		}
+	}
+	r.runs = append(r.runs, &run)
+}
+
+// Adds a job to an already added run
+func (s *Server) AddJob(owner, repo string, job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repository(owner, repo)
+	r.jobs = append(r.jobs, &job)
+}
+
+// Adds a self-hosted runner to a repository or to an organization if repo is empty
+func (s *Server) AddRunner(owner, repo string, runner Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if repo == "" {
+		s.orgRunners[owner] = append(s.orgRunners[owner], &runner)
+		return
+	}
+	r := s.repository(owner, repo)
+	r.runners = append(r.runners, &runner)
+}
+
+// Sets the log files of a run by file name, they are served zipped like the real API does
+func (s *Server) SetRunLogs(owner, repo string, runID int64, logs map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repository(owner, repo).logs[runID] = logs
+}
+
+// Sets the content of a file in the repository, e.g. the workflow file at the path of a workflow
+func (s *Server) SetFile(owner, repo, filePath, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repository(owner, repo).files[filePath] = content
+}
+
+// Sets the rate limit headers of every response, pass nil to omit them
+func (s *Server) SetRateLimit(rateLimit *RateLimit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = rateLimit
+}
+
+// Fails the next `times` requests whose path matches the pattern with the given status code,
+// times <= 0 fails all of them until ClearErrors is called
+func (s *Server) FailRequests(pathPattern string, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, &injectedError{pattern: regexp.MustCompile(pathPattern), status: status, remaining: times})
+}
+
+// Delays the responses to requests whose path matches the pattern until ClearErrors is called, requests cancelled by
+// the client end right away
+func (s *Server) DelayRequests(pathPattern string, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays = append(s.delays, &injectedDelay{pattern: regexp.MustCompile(pathPattern), delay: delay})
+}
+
+// Removes the injected errors and delays
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = nil
+	s.delays = nil
+}
+
+// must be called while holding the lock
+func (s *Server) repository(owner, name string) *repository {
+	if _, ok := s.owners[owner]; !ok {
+		s.owners[owner] = &Owner{Login: owner}
+	}
+	if _, ok := s.repos[owner]; !ok {
+		s.repos[owner] = make(map[string]*repository)
+	}
+	if _, ok := s.repos[owner][name]; !ok {
+		s.repos[owner][name] = &repository{
+			Repository: Repository{Name: name, Visibility: "public"},
+			logs:       make(map[int64]map[string]string),
+			files:      make(map[string]string),
+		}
+	}
+	return s.repos[owner][name]
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requestCount++
+		if s.rateLimit != nil {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.rateLimit.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.rateLimit.Reset.Unix(), 10))
+		}
+		status := s.injectedStatus(r.URL.Path)
+		delay := s.injectedDelay(r.URL.Path)
+		s.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if status != 0 {
+			writeJson(w, status, map[string]string{"message": fmt.Sprintf("injected error %d", status)})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// must be called while holding the lock
+func (s *Server) injectedStatus(path string) int {
+	for i, e := range s.errors {
+		if !e.pattern.MatchString(path) {
+			continue
+		}
+
+		if e.remaining > 0 {
+			e.remaining--
+			if e.remaining == 0 {
+				s.errors = append(s.errors[:i], s.errors[i+1:]...)
+			}
+		}
+		return e.status
+	}
+	return 0
+}
+
+// must be called while holding the lock
+func (s *Server) injectedDelay(path string) time.Duration {
+	for _, d := range s.delays {
+		if d.pattern.MatchString(path) {
+			return d.delay
+		}
+	}
+	return 0
+}
+
+func (s *Server) getOwner(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.owners[mux.Vars(r)["owner"]]
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	ownerType := "User"
+	if owner.Organization {
+		ownerType = "Organization"
+	}
+	writeJson(w, http.StatusOK, &g.User{Login: g.String(owner.Login), Type: g.String(ownerType)})
+}
+
+func (s *Server) getAuthenticatedUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.authenticatedUser()
+	if !ok {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"message": "Requires authentication"})
+		return
+	}
+	writeJson(w, http.StatusOK, &g.User{Login: g.String(owner.Login), Type: g.String("User")})
+}
+
+// Lists the repositories of an organization or the public repositories of a user
+func (s *Server) listRepositories(organization bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		ownerName := mux.Vars(r)["owner"]
+		owner, ok := s.owners[ownerName]
+		if !ok || owner.Organization != organization {
+			notFound(w)
+			return
+		}
+
+		visibility := visibilityAll
+		if !organization {
+			visibility = "public"
+		}
+		s.serveRepositories(w, r, ownerName, visibility)
+	}
+}
+
+// Lists the repositories owned by the authenticated user including the private ones, filtered by ?visibility=
+func (s *Server) listAuthenticatedUserRepositories(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.authenticatedUser()
+	if !ok {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"message": "Requires authentication"})
+		return
+	}
+
+	visibility := r.URL.Query().Get("visibility")
+	if visibility == "" {
+		visibility = visibilityAll
+	}
+	s.serveRepositories(w, r, owner.Login, visibility)
+}
+
+// must be called while holding the lock
+func (s *Server) authenticatedUser() (*Owner, bool) {
+	for _, owner := range s.owners {
+		if owner.Authenticated {
+			return owner, true
+		}
+	}
+	return nil, false
+}
+
+// must be called while holding the lock
+func (s *Server) serveRepositories(w http.ResponseWriter, r *http.Request, ownerName, visibility string) {
+	names := make([]string, 0)
+	for name := range s.repos[ownerName] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]*g.Repository, 0)
+	for _, name := range names {
+		repo := s.repos[ownerName][name]
+		if visibility != visibilityAll && repo.Visibility != visibility {
+			continue
+		}
+		result = append(result, &g.Repository{
+			Name:       g.String(repo.Name),
+			FullName:   g.String(ownerName + "/" + repo.Name),
+			Private:    g.Bool(repo.Visibility == "private"),
+			Visibility: g.String(repo.Visibility),
+			Archived:   g.Bool(repo.Archived),
+			Topics:     repo.Topics,
+		})
+	}
+
+	page, perPage := pageParams(r)
+	from, to := pageBounds(len(result), page, perPage)
+	setLinkHeader(w, r, len(result), page, perPage)
+	writeJson(w, http.StatusOK, result[from:to])
+}
+
+func (s *Server) listWorkflows(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	workflows := make([]*g.Workflow, 0)
+	for _, workflow := range repo.workflows {
+		workflows = append(workflows, &g.Workflow{
+			ID:        g.Int64(workflow.ID),
+			Name:      g.String(workflow.Name),
+			Path:      g.String(workflow.Path),
+			State:     g.String(workflow.State),
+			CreatedAt: &g.Timestamp{Time: workflow.CreatedAt},
+			UpdatedAt: &g.Timestamp{Time: workflow.UpdatedAt},
+		})
+	}
+
+	page, perPage := pageParams(r)
+	from, to := pageBounds(len(workflows), page, perPage)
+	setLinkHeader(w, r, len(workflows), page, perPage)
+	writeJson(w, http.StatusOK, &g.Workflows{TotalCount: g.Int(len(workflows)), Workflows: workflows[from:to]})
+}
+
+func (s *Server) setWorkflowState(state string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		repo, ok := s.findRepository(r)
+		if !ok {
+			notFound(w)
+			return
+		}
+
+		workflowID, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		for _, workflow := range repo.workflows {
+			if workflow.ID == workflowID {
+				workflow.State = state
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		notFound(w)
+	}
+}
+
+func (s *Server) listWorkflowRuns(w http.ResponseWriter, r *http.Request) {
+	workflowID, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	s.serveRuns(w, r, func(run *Run) bool { return run.WorkflowID == workflowID })
+}
+
+func (s *Server) listRepositoryRuns(w http.ResponseWriter, r *http.Request) {
+	s.serveRuns(w, r, func(run *Run) bool { return true })
+}
+
+func (s *Server) serveRuns(w http.ResponseWriter, r *http.Request, include func(*Run) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	runs := make([]*Run, 0)
+	for _, run := range repo.runs {
+		if !include(run) {
+			continue
+		}
+		if branch := query.Get("branch"); branch != "" && run.Branch != branch {
+			continue
+		}
+		if event := query.Get("event"); event != "" && run.Event != event {
+			continue
+		}
+		if status := query.Get("status"); status != "" && run.Status != status && run.Conclusion != status {
+			continue
+		}
+		// only the ">=" qualifier is supported
+		if created := query.Get("created"); strings.HasPrefix(created, ">=") {
+			if since, err := time.Parse(time.RFC3339, strings.TrimPrefix(created, ">=")); err == nil && run.CreatedAt.Before(since) {
+				continue
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	// the API returns the newest runs first
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+
+	page, perPage := pageParams(r)
+	from, to := pageBounds(len(runs), page, perPage)
+
+	result := make([]*g.WorkflowRun, 0)
+	for _, run := range runs[from:to] {
+		result = append(result, adaptRun(s.srv.URL, mux.Vars(r)["owner"], repo.Name, run))
+	}
+
+	setLinkHeader(w, r, len(runs), page, perPage)
+	writeJson(w, http.StatusOK, &g.WorkflowRuns{TotalCount: g.Int(len(runs)), WorkflowRuns: result})
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	runID, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+
+	latestAttempt := 0
+	for _, run := range repo.runs {
+		if run.ID == runID {
+			latestAttempt = run.RunAttempt
+		}
+	}
+	// by default only the jobs of the latest attempt are listed
+	allAttempts := r.URL.Query().Get("filter") == "all"
+
+	jobs := make([]*g.WorkflowJob, 0)
+	for _, job := range repo.jobs {
+		if job.RunID != runID {
+			continue
+		}
+		if !allAttempts && job.RunAttempt != 0 && job.RunAttempt != latestAttempt {
+			continue
+		}
+		jobs = append(jobs, &g.WorkflowJob{
+			ID:          g.Int64(job.ID),
+			RunID:       g.Int64(job.RunID),
+			Name:        g.String(job.Name),
+			Status:      g.String(job.Status),
+			Conclusion:  g.String(job.Conclusion),
+			Labels:      job.Labels,
+			StartedAt:   &g.Timestamp{Time: job.StartedAt},
+			CompletedAt: &g.Timestamp{Time: job.CompletedAt},
+			HTMLURL:     g.String(job.HTMLURL),
+		})
+	}
+
+	page, perPage := pageParams(r)
+	from, to := pageBounds(len(jobs), page, perPage)
+	setLinkHeader(w, r, len(jobs), page, perPage)
+	writeJson(w, http.StatusOK, &g.Jobs{TotalCount: g.Int(len(jobs)), Jobs: jobs[from:to]})
+}
+
+func (s *Server) listRepositoryRunners(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+	serveRunners(w, r, repo.runners)
+}
+
+func (s *Server) listOrganizationRunners(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.owners[mux.Vars(r)["owner"]]
+	if !ok || !owner.Organization {
+		notFound(w)
+		return
+	}
+	serveRunners(w, r, s.orgRunners[owner.Login])
+}
+
+func serveRunners(w http.ResponseWriter, r *http.Request, runners []*Runner) {
+	result := make([]*g.Runner, 0)
+	for _, runner := range runners {
+		labels := make([]*g.RunnerLabels, 0)
+		for _, label := range runner.Labels {
+			labels = append(labels, &g.RunnerLabels{Name: g.String(label)})
+		}
+
+		result = append(result, &g.Runner{
+			ID:     g.Int64(runner.ID),
+			Name:   g.String(runner.Name),
+			OS:     g.String(runner.OS),
+			Status: g.String(runner.Status),
+			Busy:   g.Bool(runner.Busy),
+			Labels: labels,
+		})
+	}
+
+	page, perPage := pageParams(r)
+	from, to := pageBounds(len(result), page, perPage)
+	setLinkHeader(w, r, len(result), page, perPage)
+	writeJson(w, http.StatusOK, &g.Runners{TotalCount: len(result), Runners: result[from:to]})
+}
+
+func (s *Server) getContents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	filePath := mux.Vars(r)["path"]
+	content, ok := repo.files[filePath]
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	writeJson(w, http.StatusOK, &g.RepositoryContent{
+		Type:     g.String("file"),
+		Name:     g.String(path.Base(filePath)),
+		Path:     g.String(filePath),
+		Encoding: g.String("base64"),
+		Content:  g.String(base64.StdEncoding.EncodeToString([]byte(content))),
+	})
+}
+
+func (s *Server) redirectToLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	runID, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if _, ok := repo.logs[runID]; !ok {
+		notFound(w)
+		return
+	}
+
+	// the real API redirects to a short lived signed url
+	location := fmt.Sprintf("%s/_logs/%s/%s/%d.zip?sig=fake-signature", s.srv.URL, mux.Vars(r)["owner"], repo.Name, runID)
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+func (s *Server) downloadLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.findRepository(r)
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	runID, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	logs, ok := repo.logs[runID]
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	body, err := zipFiles(logs)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// must be called while holding the lock
+func (s *Server) findRepository(r *http.Request) (*repository, bool) {
+	vars := mux.Vars(r)
+	repo, ok := s.repos[vars["owner"]][vars["repo"]]
+	return repo, ok
+}
+
+func adaptRun(baseURL, owner, repo string, run *Run) *g.WorkflowRun {
+	htmlURL := run.HTMLURL
+	if htmlURL == "" {
+		htmlURL = fmt.Sprintf("%s/%s/%s/actions/runs/%d", baseURL, owner, repo, run.ID)
+	}
+
+	return &g.WorkflowRun{
+		ID:           g.Int64(run.ID),
+		Name:         g.String(run.Name),
+		WorkflowID:   g.Int64(run.WorkflowID),
+		RunNumber:    g.Int(run.RunNumber),
+		RunAttempt:   g.Int(run.RunAttempt),
+		Status:       g.String(run.Status),
+		Conclusion:   g.String(run.Conclusion),
+		Event:        g.String(run.Event),
+		HeadBranch:   g.String(run.Branch),
+		HeadSHA:      g.String(run.CommitSha),
+		HTMLURL:      g.String(htmlURL),
+		LogsURL:      g.String(fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d/logs", baseURL, owner, repo, run.ID)),
+		CreatedAt:    &g.Timestamp{Time: run.CreatedAt},
+		RunStartedAt: &g.Timestamp{Time: run.RunStartedAt},
+		UpdatedAt:    &g.Timestamp{Time: run.UpdatedAt},
+		HeadCommit: &g.HeadCommit{
+			ID:        g.String(run.CommitSha),
+			Message:   g.String(run.CommitMessage),
+			Timestamp: &g.Timestamp{Time: run.CommitTime},
+			Author:    &g.CommitAuthor{Name: g.String(run.CommitAuthor)},
+		},
+	}
+}
+
+func pageParams(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = defaultPageSize
+	}
+
+	return page, perPage
+}
+
+func pageBounds(total, page, perPage int) (int, int) {
+	from := (page - 1) * perPage
+	if from > total {
+		from = total
+	}
+
+	to := from + perPage
+	if to > total {
+		to = total
+	}
+
+	return from, to
+}
+
+func setLinkHeader(w http.ResponseWriter, r *http.Request, total, page, perPage int) {
+	if page*perPage >= total {
+		return
+	}
+
+	next := *r.URL
+	query := next.Query()
+	query.Set("page", strconv.Itoa(page+1))
+	query.Set("per_page", strconv.Itoa(perPage))
+	next.RawQuery = query.Encode()
+
+	w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.String()))
+}
+
+func zipFiles(files map[string]string) ([]byte, error) {
+	buff := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buff)
+
+	names := make([]string, 0)
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, err := zipWriter.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write([]byte(files[name])); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJson(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,111 @@
+package githubtest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	"github.com/newestuser/github-workflow-dashboard/githubtest"
+)
+
+func TestFetchWorkflowRuns(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	// more workflows than fit in a single page
+	for i := 1; i <= 150; i++ {
+		srv.AddWorkflow("foo", "bar", githubtest.Workflow{ID: int64(i), Name: workflowName(i)})
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	srv.AddRun("foo", "bar", githubtest.Run{ID: 1, WorkflowID: 150, RunNumber: 1, Status: "completed", Conclusion: "failure", Branch: "main", CreatedAt: now.Add(-time.Hour)})
+	srv.AddRun("foo", "bar", githubtest.Run{ID: 2, WorkflowID: 150, RunNumber: 2, Status: "completed", Conclusion: "success", Branch: "main", CreatedAt: now})
+
+	runs, err := srv.Client().FetchWorkflowRuns(context.Background(), &github.WorkflowFilter{Owner: "foo", Repo: "bar", WorkflowNames: []string{workflowName(150)}})
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	got := make([]int, 0)
+	for _, run := range runs {
+		got = append(got, run.JobRunID)
+	}
+
+	if want := []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got run ids %v, wanted %v", got, want)
+	}
+
+	if runs[0].WorkflowName != workflowName(150) || runs[0].JobConclusion != "success" || !runs[0].JobRunTime.Equal(now) {
+		t.Errorf("got unexpected run %+v", runs[0])
+	}
+}
+
+func TestFetchWorkflowRunParams(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	srv.AddRun("foo", "bar", githubtest.Run{ID: 10, WorkflowID: 1, Status: "completed", Conclusion: "success"})
+	srv.SetRunLogs("foo", "bar", 10, map[string]string{
+		"build/1_checkout.txt": "2022-02-24T11:10:24.8627684Z env:\n2022-02-24T11:10:24.8628366Z   version: 1.2.3\n2022-02-24T11:10:24.8629861Z ##[endgroup]\n",
+	})
+
+	params, err := srv.Client().FetchWorkflowRunParams(context.Background(), &github.WorkflowFilter{Owner: "foo", Repo: "bar"}, 10)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := []github.JobRunParams{{"version": "1.2.3"}}
+	if !reflect.DeepEqual(params.Params, want) {
+		t.Errorf("got %v, wanted %v", params.Params, want)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	srv.FailRequests(`/actions/workflows$`, http.StatusBadGateway, 1)
+
+	client := srv.Client()
+	filter := &github.WorkflowFilter{Owner: "foo", Repo: "bar"}
+
+	if _, err := client.FetchWorkflowRuns(context.Background(), filter); err == nil {
+		t.Errorf("expected the injected error")
+	}
+
+	if _, err := client.FetchWorkflowRuns(context.Background(), filter); err != nil {
+		t.Errorf("expected the error to be injected only once, got: %s", err)
+	}
+}
+
+func TestInjectedDelays(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddWorkflow("foo", "bar", githubtest.Workflow{ID: 1, Name: "build"})
+	srv.DelayRequests(`/actions/workflows$`, time.Minute)
+
+	client := srv.Client()
+	filter := &github.WorkflowFilter{Owner: "foo", Repo: "bar"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.FetchWorkflowRuns(ctx, filter); err == nil {
+		t.Errorf("expected the delayed request to time out")
+	}
+
+	srv.ClearErrors()
+	if _, err := client.FetchWorkflowRuns(context.Background(), filter); err != nil {
+		t.Errorf("expected the delay to be cleared, got: %s", err)
+	}
+}
+
+func workflowName(i int) string {
+	return fmt.Sprintf("workflow-%d", i)
+}
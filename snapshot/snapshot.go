@@ -0,0 +1,103 @@
+// Package snapshot writes and reads the state of the dashboard as a versioned, gzip compressed json file so that it can be
+// shared, imported into a server or rendered offline.
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// Version of the snapshot format, snapshots of other versions can't be read
+const Version = 1
+
+type Snapshot struct {
+	Version      int           `json:"version"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	Repositories []*Repository `json:"repositories"`
+}
+
+// The state of a single repository
+type Repository struct {
+	Owner       string                       `json:"owner"`
+	Name        string                       `json:"name"`
+	Runs        []*github.WorkflowRun        `json:"runs"`
+	Definitions []*github.WorkflowDefinition `json:"definitions,omitempty"`
+	// Time the runs were fetched
+	Uts time.Time `json:"uts"`
+}
+
+func New(createdAt time.Time) *Snapshot {
+	return &Snapshot{
+		Version:      Version,
+		CreatedAt:    createdAt,
+		Repositories: make([]*Repository, 0),
+	}
+}
+
+// The runs of all repositories
+func (s *Snapshot) Runs() []*github.WorkflowRun {
+	result := make([]*github.WorkflowRun, 0)
+	for _, repo := range s.Repositories {
+		result = append(result, repo.Runs...)
+	}
+	return result
+}
+
+func Write(w io.Writer, snapshot *Snapshot) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(snapshot); err != nil {
+		return fmt.Errorf("writing snapshot failed, err: %s", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("writing snapshot failed, err: %s", err)
+	}
+	return nil
+}
+
+// Reads a snapshot written by Write, snapshots of another version are rejected
+func Read(r io.Reader) (*Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot failed, err: %s", err)
+	}
+	defer zr.Close()
+
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(zr).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("reading snapshot failed, err: %s", err)
+	}
+
+	if snapshot.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d, supported version: %d", snapshot.Version, Version)
+	}
+	return snapshot, nil
+}
+
+func WriteFile(path string, snapshot *Snapshot) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating snapshot file '%s' failed, err: %s", path, err)
+	}
+
+	if err := Write(file, snapshot); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func ReadFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening snapshot file '%s' failed, err: %s", path, err)
+	}
+	defer file.Close()
+
+	return Read(file)
+}
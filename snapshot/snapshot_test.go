@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func TestWriteAndReadFile(t *testing.T) {
+	now := time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)
+	written := New(now)
+	written.Repositories = append(written.Repositories, &Repository{
+		Owner: "foo",
+		Name:  "bar",
+		Runs: []*github.WorkflowRun{
+			{WorkflowName: "build", JobRunID: 1, JobRunTime: now, WorkflowParams: &github.WorkflowRunParams{Params: []github.JobRunParams{{"env": "prod"}}}},
+		},
+		Uts: now,
+	})
+
+	path := filepath.Join(t.TempDir(), "state.json.gz")
+	if err := WriteFile(path, written); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	runs := read.Runs()
+	if !read.CreatedAt.Equal(now) || len(read.Repositories) != 1 || len(runs) != 1 || runs[0].WorkflowParams.Params[0]["env"] != "prod" {
+		t.Errorf("got %+v, wanted the written snapshot", read)
+	}
+}
+
+func TestReadRejectsOtherVersions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Write(buf, &Snapshot{Version: Version + 1}); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if _, err := Read(buf); err == nil {
+		t.Errorf("got no error for version %d", Version+1)
+	}
+
+	zipped := &bytes.Buffer{}
+	zw := gzip.NewWriter(zipped)
+	_, _ = zw.Write([]byte("not json"))
+	_ = zw.Close()
+	if _, err := Read(zipped); err == nil {
+		t.Errorf("got no error for an invalid snapshot")
+	}
+}
@@ -0,0 +1,212 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// Selects the runs of a workflow that are deployments, e.g. "Azure/k8s-deploy/Deploy@main#environment=production"
+type DeploymentSelector struct {
+	Owner    string
+	Repo     string
+	Workflow string
+	// Optional, only runs of the branch are deployments
+	Branch string
+	// Optional, only runs having the param with the value are deployments, requires the run params to be parsed
+	ParamName  string
+	ParamValue string
+}
+
+// Parses a selector of the format <owner>/<repo>/<workflow>[@<branch>][#<param>=<value>]
+func ParseDeploymentSelector(value string) (*DeploymentSelector, error) {
+	parts := strings.SplitN(value, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("deployment '%s' must have the format <owner>/<repo>/<workflow>[@<branch>][#<param>=<value>]", value)
+	}
+
+	selector := &DeploymentSelector{Owner: parts[0], Repo: parts[1]}
+	workflow := parts[2]
+
+	if i := strings.LastIndex(workflow, "#"); i != -1 {
+		param := strings.SplitN(workflow[i+1:], "=", 2)
+		if len(param) != 2 || param[0] == "" {
+			return nil, fmt.Errorf("deployment '%s' has an invalid param, it must have the format #<param>=<value>", value)
+		}
+		selector.ParamName, selector.ParamValue = param[0], param[1]
+		workflow = workflow[:i]
+	}
+
+	if i := strings.LastIndex(workflow, "@"); i != -1 {
+		selector.Branch = workflow[i+1:]
+		workflow = workflow[:i]
+	}
+
+	if workflow == "" {
+		return nil, fmt.Errorf("deployment '%s' has no workflow", value)
+	}
+	selector.Workflow = workflow
+
+	return selector, nil
+}
+
+func (d *DeploymentSelector) String() string {
+	result := fmt.Sprintf("%s/%s/%s", d.Owner, d.Repo, d.Workflow)
+	if d.Branch != "" {
+		result += "@" + d.Branch
+	}
+	if d.ParamName != "" {
+		result += fmt.Sprintf("#%s=%s", d.ParamName, d.ParamValue)
+	}
+	return result
+}
+
+func (d *DeploymentSelector) NeedsParams() bool {
+	return d.ParamName != ""
+}
+
+func (d *DeploymentSelector) Matches(run *github.WorkflowRun) bool {
+	if run.WorkflowOwner != d.Owner || run.WorkflowRepo != d.Repo || run.WorkflowName != d.Workflow {
+		return false
+	}
+
+	if d.Branch != "" && run.JobBranch != d.Branch {
+		return false
+	}
+
+	if d.ParamName == "" {
+		return true
+	}
+
+	if run.WorkflowParams == nil {
+		return false
+	}
+	for _, params := range run.WorkflowParams.Params {
+		if value, ok := params[d.ParamName]; ok && value == d.ParamValue {
+			return true
+		}
+	}
+	return false
+}
+
+// DORA style delivery metrics of the deployments of a selector within a time window
+type DeliveryMetrics struct {
+	Deployment string    `json:"deployment"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+
+	Deployments           int `json:"deployments"`
+	SuccessfulDeployments int `json:"successfulDeployments"`
+	FailedDeployments     int `json:"failedDeployments"`
+	// Successful deployments per day
+	DeploymentFrequency float64 `json:"deploymentFrequency"`
+	// Time from the head commit to the completion of successful deployments
+	LeadTime DurationStats `json:"leadTime"`
+	// Share of failed deployments among the successful and failed ones
+	ChangeFailureRate float64 `json:"changeFailureRate"`
+	// Time from the first failed deployment to the next successful one, failures not restored yet are excluded
+	TimeToRestore DurationStats `json:"timeToRestore"`
+}
+
+// Computes the delivery metrics of each deployment selector from the runs, the runs are split into windows the same way
+// as Compute does. The result is sorted by selector and window.
+func ComputeDeliveryMetrics(runs []*github.WorkflowRun, selectors []*DeploymentSelector, opts Options) []*DeliveryMetrics {
+	result := make([]*DeliveryMetrics, 0)
+
+	for _, selector := range selectors {
+		windows := map[time.Time][]*github.WorkflowRun{}
+		keys := make([]time.Time, 0)
+
+		for _, run := range runs {
+			if !selector.Matches(run) || run.JobRunTime.Before(opts.Since) || (!IsSuccess(run) && !IsFailure(run)) {
+				continue
+			}
+
+			key := time.Time{}
+			if opts.Window > 0 {
+				key = run.JobRunTime.UTC().Truncate(opts.Window)
+			}
+			if _, ok := windows[key]; !ok {
+				keys = append(keys, key)
+			}
+			windows[key] = append(windows[key], run)
+		}
+
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Before(keys[j]) })
+
+		for _, key := range keys {
+			metrics := aggregateDeployments(windows[key])
+			metrics.Deployment = selector.String()
+
+			if opts.Window > 0 {
+				metrics.From, metrics.To = key, key.Add(opts.Window)
+			} else if !opts.Since.IsZero() {
+				metrics.From = opts.Since
+			}
+
+			if days := metrics.To.Sub(metrics.From).Hours() / 24; days >= 1 {
+				metrics.DeploymentFrequency = float64(metrics.SuccessfulDeployments) / days
+			} else {
+				metrics.DeploymentFrequency = float64(metrics.SuccessfulDeployments)
+			}
+
+			result = append(result, metrics)
+		}
+	}
+
+	return result
+}
+
+// Aggregates the completed deployments, the window is set to the creation times of the oldest and the newest one
+func aggregateDeployments(deployments []*github.WorkflowRun) *DeliveryMetrics {
+	sort.SliceStable(deployments, func(i, j int) bool {
+		return deployments[i].JobRunTime.Before(deployments[j].JobRunTime)
+	})
+
+	result := &DeliveryMetrics{
+		From:        deployments[0].JobRunTime,
+		To:          deployments[len(deployments)-1].JobRunTime,
+		Deployments: len(deployments),
+	}
+
+	leadTimes := make([]time.Duration, 0)
+	restoreTimes := make([]time.Duration, 0)
+	var failingSince *github.WorkflowRun
+
+	for _, deployment := range deployments {
+		if IsFailure(deployment) {
+			result.FailedDeployments++
+			if failingSince == nil {
+				failingSince = deployment
+			}
+			continue
+		}
+
+		result.SuccessfulDeployments++
+		if !deployment.JobCommitTime.IsZero() && completedAt(deployment).After(deployment.JobCommitTime) {
+			leadTimes = append(leadTimes, completedAt(deployment).Sub(deployment.JobCommitTime))
+		}
+
+		if failingSince != nil {
+			restoreTimes = append(restoreTimes, completedAt(deployment).Sub(completedAt(failingSince)))
+			failingSince = nil
+		}
+	}
+
+	result.ChangeFailureRate = float64(result.FailedDeployments) / float64(result.Deployments)
+	result.LeadTime = NewDurationStats(leadTimes)
+	result.TimeToRestore = NewDurationStats(restoreTimes)
+
+	return result
+}
+
+// The completion time of a completed run, its creation time if unknown
+func completedAt(run *github.WorkflowRun) time.Time {
+	if run.JobUpdateTime.IsZero() {
+		return run.JobRunTime
+	}
+	return run.JobUpdateTime
+}
@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func TestParseDeploymentSelector(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    DeploymentSelector
+		isValid bool
+	}{
+		{"foo/bar/Deploy", DeploymentSelector{Owner: "foo", Repo: "bar", Workflow: "Deploy"}, true},
+		{"foo/bar/Deploy to prod@main", DeploymentSelector{Owner: "foo", Repo: "bar", Workflow: "Deploy to prod", Branch: "main"}, true},
+		{"foo/bar/Deploy@main#env=production", DeploymentSelector{Owner: "foo", Repo: "bar", Workflow: "Deploy", Branch: "main", ParamName: "env", ParamValue: "production"}, true},
+		{"foo/bar/Deploy#env=", DeploymentSelector{Owner: "foo", Repo: "bar", Workflow: "Deploy", ParamName: "env"}, true},
+		{"foo/bar", DeploymentSelector{}, false},
+		{"foo//Deploy", DeploymentSelector{}, false},
+		{"foo/bar/@main", DeploymentSelector{}, false},
+		{"foo/bar/Deploy#env", DeploymentSelector{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseDeploymentSelector(test.value)
+			if !test.isValid {
+				if err == nil {
+					t.Errorf("got %+v, wanted an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %s", err)
+			}
+			if *got != test.want {
+				t.Errorf("got %+v, wanted %+v", got, test.want)
+			}
+			if got.String() != test.value {
+				t.Errorf("got string %s, wanted %s", got.String(), test.value)
+			}
+		})
+	}
+}
+
+func TestDeploymentSelectorMatchesParams(t *testing.T) {
+	selector := &DeploymentSelector{Owner: "foo", Repo: "bar", Workflow: "Deploy", ParamName: "env", ParamValue: "production"}
+	run := &github.WorkflowRun{WorkflowOwner: "foo", WorkflowRepo: "bar", WorkflowName: "Deploy"}
+
+	if selector.Matches(run) {
+		t.Errorf("matched a run without params")
+	}
+
+	run.WorkflowParams = &github.WorkflowRunParams{Params: []github.JobRunParams{{"env": "staging"}}}
+	if selector.Matches(run) {
+		t.Errorf("matched a run with env=staging")
+	}
+
+	run.WorkflowParams.Params = append(run.WorkflowParams.Params, github.JobRunParams{"env": "production"})
+	if !selector.Matches(run) {
+		t.Errorf("didn't match a run with env=production")
+	}
+}
+
+func TestComputeDeliveryMetrics(t *testing.T) {
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	run := func(branch, conclusion string, created time.Time, commitAge time.Duration) *github.WorkflowRun {
+		return &github.WorkflowRun{
+			WorkflowOwner: "foo",
+			WorkflowRepo:  "bar",
+			WorkflowName:  "Deploy",
+			JobStatus:     github.StatusCompleted,
+			JobConclusion: conclusion,
+			JobBranch:     branch,
+			JobRunTime:    created,
+			JobUpdateTime: created.Add(10 * time.Minute),
+			JobCommitTime: created.Add(-commitAge),
+		}
+	}
+
+	runs := []*github.WorkflowRun{
+		run("main", ConclusionSuccess, start, time.Hour),
+		run("main", ConclusionFailure, start.Add(24*time.Hour), time.Hour),
+		run("main", ConclusionCancelled, start.Add(25*time.Hour), time.Hour),
+		run("main", ConclusionTimedOut, start.Add(26*time.Hour), time.Hour),
+		run("main", ConclusionSuccess, start.Add(28*time.Hour), 3*time.Hour),
+		run("dev", ConclusionFailure, start.Add(29*time.Hour), time.Hour),
+		run("main", ConclusionSuccess, start.Add(4*24*time.Hour), 5*time.Hour),
+	}
+	selectors := []*DeploymentSelector{{Owner: "foo", Repo: "bar", Workflow: "Deploy", Branch: "main"}}
+
+	metrics := ComputeDeliveryMetrics(runs, selectors, Options{})
+	if len(metrics) != 1 {
+		t.Fatalf("got %d metrics, wanted 1", len(metrics))
+	}
+
+	m := metrics[0]
+	if m.Deployment != "foo/bar/Deploy@main" {
+		t.Errorf("got deployment %s", m.Deployment)
+	}
+	if m.Deployments != 5 || m.SuccessfulDeployments != 3 || m.FailedDeployments != 2 {
+		t.Errorf("got deployments=%d successful=%d failed=%d, wanted 5, 3 and 2", m.Deployments, m.SuccessfulDeployments, m.FailedDeployments)
+	}
+	if m.DeploymentFrequency != 0.75 {
+		t.Errorf("got deployment frequency %f, wanted 0.75 per day", m.DeploymentFrequency)
+	}
+	if m.ChangeFailureRate != 0.4 {
+		t.Errorf("got change failure rate %f, wanted 0.4", m.ChangeFailureRate)
+	}
+	if m.LeadTime.Count != 3 || m.LeadTime.Median != 3*time.Hour+10*time.Minute {
+		t.Errorf("got lead time %+v, wanted a median of 3h10m over 3 deployments", m.LeadTime)
+	}
+	if m.TimeToRestore.Count != 1 || m.TimeToRestore.Mean != 4*time.Hour {
+		t.Errorf("got time to restore %+v, wanted 4h", m.TimeToRestore)
+	}
+
+	windowed := ComputeDeliveryMetrics(runs, selectors, Options{Window: 2 * 24 * time.Hour})
+	if len(windowed) != 2 {
+		t.Fatalf("got %d windows, wanted 2", len(windowed))
+	}
+	if windowed[0].Deployments != 4 || windowed[1].Deployments != 1 || windowed[1].DeploymentFrequency != 0.5 {
+		t.Errorf("got windows with %d and %d deployments, wanted 4 and 1", windowed[0].Deployments, windowed[1].Deployments)
+	}
+}
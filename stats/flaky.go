@@ -0,0 +1,301 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+const (
+	// A run succeeded only after being re-run
+	ReasonRerunPassed = "passed on re-run"
+	// Runs of the same commit on the same branch failed and passed
+	ReasonSameCommitFlip = "same commit failed and passed"
+	// A job failed in one attempt and passed in another attempt of the same run
+	ReasonJobRetryPassed = "job failed and passed in the same run"
+	// Runs of the same branch kept alternating between failing and passing
+	ReasonAlternating = "branch alternated between failing and passing"
+)
+
+// Consecutive runs of a branch that have to alternate between failing and passing for their failures to count as flaky,
+// fewer would also match a failure that was fixed by the next commit
+const alternatingRuns = 4
+
+// Evidence of a flaky failure, the URLs point to the failing and the passing run or job
+type FlakyEvidence struct {
+	Reason    string    `json:"reason"`
+	Branch    string    `json:"branch"`
+	CommitSha string    `json:"commitSha"`
+	FailedURL string    `json:"failedUrl,omitempty"`
+	PassedURL string    `json:"passedUrl"`
+	Time      time.Time `json:"time"`
+}
+
+// How flaky a workflow (or one of its jobs if JobName is set) is
+type FlakinessReport struct {
+	WorkflowOwner string `json:"workflowOwner"`
+	WorkflowRepo  string `json:"workflowRepo"`
+	WorkflowName  string `json:"workflowName"`
+	JobName       string `json:"jobName,omitempty"`
+	// Completed runs (of the job) that succeeded or failed, the failed attempt of a run that passed on re-run counts as
+	// a failed run as well
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+	// Failures that passed without any change to the code
+	FlakyFailures int `json:"flakyFailures"`
+	// Share of flaky failures among all runs
+	Score    float64          `json:"score"`
+	Evidence []*FlakyEvidence `json:"evidence"`
+}
+
+// Detects flaky failures from the run history and the jobs of the runs (by run ID, may be incomplete). Workflows and
+// jobs without flaky failures are omitted, the result is ranked by score followed by the number of flaky failures.
+func DetectFlakiness(runs []*github.WorkflowRun, jobs map[int][]*github.RunJob) []*FlakinessReport {
+	workflows := map[string]*FlakinessReport{}
+	workflowJobs := map[string]*FlakinessReport{}
+	// failed runs of each workflow that passed without a change, their failing jobs are flaky as well
+	flakyRuns := map[int]*FlakyEvidence{}
+
+	for _, branchRuns := range groupByWorkflowAndBranch(runs) {
+		var previous *github.WorkflowRun
+		completed := make([]*github.WorkflowRun, 0, len(branchRuns))
+		for _, run := range branchRuns {
+			if !IsSuccess(run) && !IsFailure(run) {
+				continue
+			}
+			completed = append(completed, run)
+
+			report := reportOf(workflows, run, "")
+			report.Runs++
+			if IsFailure(run) {
+				report.Failures++
+			}
+
+			if IsSuccess(run) && run.JobRunAttempt > 1 {
+				// the failed attempt is hidden by the passing one
+				report.Runs++
+				report.Failures++
+				evidence := newEvidence(ReasonRerunPassed, nil, run)
+				report.add(evidence)
+			}
+
+			if previous != nil && previous.JobCommitSha == run.JobCommitSha && IsFailure(previous) != IsFailure(run) {
+				failed, passed := previous, run
+				if IsFailure(run) {
+					failed, passed = run, previous
+				}
+				evidence := newEvidence(ReasonSameCommitFlip, failed, passed)
+				report.add(evidence)
+				flakyRuns[failed.JobRunID] = evidence
+			}
+
+			previous = run
+		}
+
+		for _, flip := range findAlternatingFailures(completed) {
+			if _, ok := flakyRuns[flip.failed.JobRunID]; ok {
+				continue
+			}
+			evidence := newEvidence(ReasonAlternating, flip.failed, flip.passed)
+			reportOf(workflows, flip.failed, "").add(evidence)
+			flakyRuns[flip.failed.JobRunID] = evidence
+		}
+	}
+
+	for _, run := range runs {
+		runJobs, ok := jobs[run.JobRunID]
+		if !ok || (!IsSuccess(run) && !IsFailure(run)) {
+			continue
+		}
+
+		for name, attempts := range groupJobsByName(runJobs) {
+			report := reportOf(workflowJobs, run, name)
+
+			failed, passed := findFailedAndPassed(attempts)
+			if failed == nil {
+				continue
+			}
+			report.Failures++
+
+			if passed != nil {
+				report.add(&FlakyEvidence{
+					Reason:    ReasonJobRetryPassed,
+					Branch:    run.JobBranch,
+					CommitSha: run.JobCommitSha,
+					FailedURL: failed.HTMLURL,
+					PassedURL: passed.HTMLURL,
+					Time:      run.JobRunTime,
+				})
+			} else if evidence, ok := flakyRuns[run.JobRunID]; ok {
+				report.add(&FlakyEvidence{
+					Reason:    evidence.Reason,
+					Branch:    evidence.Branch,
+					CommitSha: evidence.CommitSha,
+					FailedURL: failed.HTMLURL,
+					PassedURL: evidence.PassedURL,
+					Time:      evidence.Time,
+				})
+			}
+		}
+	}
+
+	// jobs are usually fetched only for failed and re-run runs, jobs are assumed to have run in every run of the workflow
+	for _, report := range workflowJobs {
+		report.Runs = reportOf(workflows, &github.WorkflowRun{
+			WorkflowOwner: report.WorkflowOwner,
+			WorkflowRepo:  report.WorkflowRepo,
+			WorkflowName:  report.WorkflowName,
+		}, "").Runs
+	}
+
+	result := make([]*FlakinessReport, 0)
+	for _, reports := range []map[string]*FlakinessReport{workflows, workflowJobs} {
+		for _, report := range reports {
+			if report.FlakyFailures == 0 || report.Runs == 0 {
+				continue
+			}
+			report.Score = float64(report.FlakyFailures) / float64(report.Runs)
+			result = append(result, report)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.FlakyFailures != b.FlakyFailures {
+			return a.FlakyFailures > b.FlakyFailures
+		}
+		return a.key() < b.key()
+	})
+
+	return result
+}
+
+// Whether the jobs of the run are needed to detect flaky jobs, only failed and re-run runs can reveal them
+func NeedsJobs(run *github.WorkflowRun) bool {
+	return IsFailure(run) || (IsSuccess(run) && run.JobRunAttempt > 1)
+}
+
+func (r *FlakinessReport) add(evidence *FlakyEvidence) {
+	r.FlakyFailures++
+	r.Evidence = append(r.Evidence, evidence)
+}
+
+func (r *FlakinessReport) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", r.WorkflowOwner, r.WorkflowRepo, r.WorkflowName, r.JobName)
+}
+
+func reportOf(reports map[string]*FlakinessReport, run *github.WorkflowRun, jobName string) *FlakinessReport {
+	report := &FlakinessReport{
+		WorkflowOwner: run.WorkflowOwner,
+		WorkflowRepo:  run.WorkflowRepo,
+		WorkflowName:  run.WorkflowName,
+		JobName:       jobName,
+		Evidence:      make([]*FlakyEvidence, 0),
+	}
+
+	if existing, ok := reports[report.key()]; ok {
+		return existing
+	}
+	reports[report.key()] = report
+	return report
+}
+
+func newEvidence(reason string, failed, passed *github.WorkflowRun) *FlakyEvidence {
+	evidence := &FlakyEvidence{
+		Reason:    reason,
+		Branch:    passed.JobBranch,
+		CommitSha: passed.JobCommitSha,
+		PassedURL: passed.JobHTMLURL,
+		Time:      passed.JobRunTime,
+	}
+	if failed != nil {
+		evidence.FailedURL = failed.JobHTMLURL
+	}
+	return evidence
+}
+
+// Runs of each workflow and branch sorted from the oldest to the newest
+func groupByWorkflowAndBranch(runs []*github.WorkflowRun) [][]*github.WorkflowRun {
+	groups := map[string][]*github.WorkflowRun{}
+	keys := make([]string, 0)
+	for _, run := range runs {
+		key := fmt.Sprintf("%s/%s/%s@%s", run.WorkflowOwner, run.WorkflowRepo, run.WorkflowName, run.JobBranch)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], run)
+	}
+	sort.Strings(keys)
+
+	result := make([][]*github.WorkflowRun, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].JobRunTime.Before(group[j].JobRunTime)
+		})
+		result = append(result, group)
+	}
+	return result
+}
+
+// A failed run and a passing run next to it
+type runFlip struct {
+	failed *github.WorkflowRun
+	passed *github.WorkflowRun
+}
+
+// The failures among at least alternatingRuns consecutive runs of a branch that alternated between failing and passing,
+// each paired with the passing run after it or, for the last run, before it
+func findAlternatingFailures(branchRuns []*github.WorkflowRun) []*runFlip {
+	result := make([]*runFlip, 0)
+	start := 0
+	for end := 1; end <= len(branchRuns); end++ {
+		if end < len(branchRuns) && IsFailure(branchRuns[end]) != IsFailure(branchRuns[end-1]) {
+			continue
+		}
+
+		if end-start >= alternatingRuns {
+			for i := start; i < end; i++ {
+				if !IsFailure(branchRuns[i]) {
+					continue
+				}
+				passed := i + 1
+				if passed == end {
+					passed = i - 1
+				}
+				result = append(result, &runFlip{failed: branchRuns[i], passed: branchRuns[passed]})
+			}
+		}
+		start = end
+	}
+	return result
+}
+
+func groupJobsByName(jobs []*github.RunJob) map[string][]*github.RunJob {
+	result := map[string][]*github.RunJob{}
+	for _, job := range jobs {
+		result[job.JobName] = append(result[job.JobName], job)
+	}
+	return result
+}
+
+// The first failed and the last passed attempt of a job, nil if there is none
+func findFailedAndPassed(attempts []*github.RunJob) (*github.RunJob, *github.RunJob) {
+	var failed, passed *github.RunJob
+	for _, job := range attempts {
+		switch job.Conclusion {
+		case ConclusionFailure, ConclusionTimedOut:
+			if failed == nil {
+				failed = job
+			}
+		case ConclusionSuccess:
+			passed = job
+		}
+	}
+	return failed, passed
+}
@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+var flakyNow = time.Date(2022, 3, 9, 12, 0, 0, 0, time.UTC)
+
+// A completed run of the workflow on main, created hours before now
+func newFlakyRun(id int, workflow, sha, conclusion string, attempt int, hours int) *github.WorkflowRun {
+	return &github.WorkflowRun{
+		WorkflowOwner: "foo",
+		WorkflowRepo:  "bar",
+		WorkflowName:  workflow,
+		JobRunID:      id,
+		JobHTMLURL:    "https://github.com/foo/bar/actions/runs/" + sha,
+		JobStatus:     github.StatusCompleted,
+		JobConclusion: conclusion,
+		JobRunAttempt: attempt,
+		JobBranch:     "main",
+		JobCommitSha:  sha,
+		JobRunTime:    flakyNow.Add(-time.Duration(hours) * time.Hour),
+	}
+}
+
+func TestDetectFlakiness(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newFlakyRun(1, "build", "aaa", ConclusionSuccess, 1, 5),
+		newFlakyRun(2, "build", "bbb", ConclusionFailure, 1, 4),
+		newFlakyRun(3, "build", "bbb", ConclusionSuccess, 1, 3),
+		newFlakyRun(4, "build", "ccc", ConclusionSuccess, 2, 2),
+		// a real failure fixed by the next commit
+		newFlakyRun(5, "build", "ddd", ConclusionFailure, 1, 1),
+		newFlakyRun(6, "build", "eee", ConclusionSuccess, 1, 0),
+		newFlakyRun(7, "lint", "aaa", ConclusionSuccess, 1, 0),
+	}
+
+	jobs := map[int][]*github.RunJob{
+		2: {
+			{RunID: 2, JobName: "test", Conclusion: ConclusionFailure, HTMLURL: "job-2-test"},
+			{RunID: 2, JobName: "compile", Conclusion: ConclusionSuccess, HTMLURL: "job-2-compile"},
+		},
+		4: {
+			{RunID: 4, JobName: "test", Conclusion: ConclusionFailure, HTMLURL: "job-4-test-1"},
+			{RunID: 4, JobName: "test", Conclusion: ConclusionSuccess, HTMLURL: "job-4-test-2"},
+		},
+		5: {
+			{RunID: 5, JobName: "compile", Conclusion: ConclusionFailure, HTMLURL: "job-5-compile"},
+		},
+	}
+
+	reports := DetectFlakiness(runs, jobs)
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, wanted 2", len(reports))
+	}
+
+	for _, report := range reports {
+		if report.WorkflowName != "build" || report.FlakyFailures != 2 || report.Runs != 7 {
+			t.Errorf("got %s/%s with %d flaky failures in %d runs, wanted build with 2 flaky failures in 7 runs",
+				report.WorkflowName, report.JobName, report.FlakyFailures, report.Runs)
+		}
+	}
+
+	// the failed attempt of the run that passed on re-run is a failure as well
+	if workflow := reports[0]; workflow.JobName == "" && workflow.Failures != 3 {
+		t.Errorf("got %d failures of build, wanted 3", workflow.Failures)
+	}
+
+	job := reports[1]
+	if reports[0].JobName == "test" {
+		job = reports[0]
+	}
+	if job.JobName != "test" || job.Evidence[0].FailedURL != "job-2-test" || job.Evidence[1].PassedURL != "job-4-test-2" {
+		t.Errorf("got evidence %+v %+v for job %s", job.Evidence[0], job.Evidence[1], job.JobName)
+	}
+}
+
+func TestDetectFlakinessOfAlternatingBranch(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		newFlakyRun(1, "build", "aaa", ConclusionFailure, 1, 5),
+		newFlakyRun(2, "build", "bbb", ConclusionSuccess, 1, 4),
+		newFlakyRun(3, "build", "ccc", ConclusionFailure, 1, 3),
+		newFlakyRun(4, "build", "ddd", ConclusionSuccess, 1, 2),
+		newFlakyRun(5, "build", "eee", ConclusionSuccess, 1, 1),
+		newFlakyRun(6, "build", "fff", ConclusionFailure, 1, 0),
+		// a real failure fixed by the next commit doesn't alternate long enough
+		newFlakyRun(7, "lint", "aaa", ConclusionSuccess, 1, 2),
+		newFlakyRun(8, "lint", "bbb", ConclusionFailure, 1, 1),
+		newFlakyRun(9, "lint", "ccc", ConclusionSuccess, 1, 0),
+	}
+
+	reports := DetectFlakiness(runs, map[int][]*github.RunJob{})
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, wanted 1", len(reports))
+	}
+
+	report := reports[0]
+	if report.WorkflowName != "build" || report.FlakyFailures != 2 || report.Failures != 3 || report.Runs != 6 {
+		t.Errorf("got %s with %d flaky failures of %d in %d runs, wanted build with 2 flaky failures of 3 in 6 runs",
+			report.WorkflowName, report.FlakyFailures, report.Failures, report.Runs)
+	}
+	for i, shas := range [][2]string{{"aaa", "bbb"}, {"ccc", "ddd"}} {
+		evidence := report.Evidence[i]
+		if evidence.Reason != ReasonAlternating || !strings.HasSuffix(evidence.FailedURL, shas[0]) || evidence.CommitSha != shas[1] {
+			t.Errorf("got evidence %+v, wanted the failure of %s passing in %s", evidence, shas[0], shas[1])
+		}
+	}
+}
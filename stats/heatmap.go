@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// Weekdays in the order rows of a heatmap are shown
+var HeatmapWeekdays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// Runs created within an hour of a weekday
+type HeatmapCell struct {
+	Weekday   time.Weekday `json:"weekday"`
+	Hour      int          `json:"hour"`
+	Runs      int          `json:"runs"`
+	Completed int          `json:"completed"`
+	Failures  int          `json:"failures"`
+	// Relative to the completed runs that succeeded or failed
+	FailureRate float64       `json:"failureRate"`
+	QueueTime   DurationStats `json:"queueTime"`
+}
+
+// Run activity by hour of day and weekday
+type Heatmap struct {
+	// Location the hours and weekdays are computed in
+	Timezone string    `json:"timezone"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Runs     int       `json:"runs"`
+	// A cell per weekday and hour, ordered by weekday from sunday and hour
+	Cells []*HeatmapCell `json:"cells"`
+}
+
+func (h *Heatmap) Cell(weekday time.Weekday, hour int) *HeatmapCell {
+	return h.Cells[int(weekday)*24+hour]
+}
+
+// Aggregates the runs created since (zero means all runs) by their weekday and hour in the location
+func ComputeHeatmap(runs []*github.WorkflowRun, location *time.Location, since time.Time) *Heatmap {
+	result := &Heatmap{Timezone: location.String(), Cells: make([]*HeatmapCell, 7*24)}
+	for i := range result.Cells {
+		result.Cells[i] = &HeatmapCell{Weekday: time.Weekday(i / 24), Hour: i % 24}
+	}
+
+	queueTimes := make([][]time.Duration, len(result.Cells))
+	finished := make([]int, len(result.Cells))
+
+	for _, run := range runs {
+		if run.JobRunTime.Before(since) {
+			continue
+		}
+
+		if result.From.IsZero() || run.JobRunTime.Before(result.From) {
+			result.From = run.JobRunTime
+		}
+		if run.JobRunTime.After(result.To) {
+			result.To = run.JobRunTime
+		}
+		result.Runs++
+
+		created := run.JobRunTime.In(location)
+		index := int(created.Weekday())*24 + created.Hour()
+		cell := result.Cells[index]
+		cell.Runs++
+
+		if run.IsCompleted() {
+			cell.Completed++
+		}
+		if IsSuccess(run) || IsFailure(run) {
+			finished[index]++
+		}
+		if IsFailure(run) {
+			cell.Failures++
+		}
+		if queueTime, ok := QueueTime(run); ok {
+			queueTimes[index] = append(queueTimes[index], queueTime)
+		}
+	}
+
+	for i, cell := range result.Cells {
+		if finished[i] > 0 {
+			cell.FailureRate = float64(cell.Failures) / float64(finished[i])
+		}
+		cell.QueueTime = NewDurationStats(queueTimes[i])
+	}
+
+	return result
+}
@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func TestComputeHeatmap(t *testing.T) {
+	// monday 23:30 UTC is tuesday 00:30 in Berlin
+	monday := time.Date(2022, 3, 7, 23, 30, 0, 0, time.UTC)
+	run := func(created time.Time, conclusion string, queued time.Duration) *github.WorkflowRun {
+		return &github.WorkflowRun{
+			JobStatus:     github.StatusCompleted,
+			JobConclusion: conclusion,
+			JobRunTime:    created,
+			JobStartTime:  created.Add(queued),
+			JobRunAttempt: 1,
+		}
+	}
+
+	runs := []*github.WorkflowRun{
+		run(monday, ConclusionSuccess, time.Minute),
+		run(monday.Add(10*time.Minute), ConclusionFailure, 3*time.Minute),
+		run(monday.Add(20*time.Minute), ConclusionCancelled, 0),
+		run(monday.Add(-7*24*time.Hour), ConclusionFailure, 0),
+	}
+
+	heatmap := ComputeHeatmap(runs, time.UTC, monday.Add(-time.Hour))
+	if heatmap.Runs != 3 || !heatmap.From.Equal(monday) {
+		t.Fatalf("got %d runs from %s, wanted 3 runs since %s", heatmap.Runs, heatmap.From, monday)
+	}
+
+	cell := heatmap.Cell(time.Monday, 23)
+	if cell.Runs != 3 || cell.Completed != 3 || cell.Failures != 1 || cell.FailureRate != 0.5 {
+		t.Errorf("got cell %+v, wanted 3 runs and a failure rate of 0.5", cell)
+	}
+	if cell.QueueTime.Count != 3 || cell.QueueTime.Median != time.Minute {
+		t.Errorf("got queue time %+v, wanted a median of 1m", cell.QueueTime)
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no timezone data: %s", err)
+	}
+	heatmap = ComputeHeatmap(runs, berlin, monday.Add(-time.Hour))
+	if heatmap.Cell(time.Tuesday, 0).Runs != 3 || heatmap.Cell(time.Tuesday, 0).Failures != 1 {
+		t.Errorf("got %d runs on tuesday 00:00 in Berlin, wanted 3", heatmap.Cell(time.Tuesday, 0).Runs)
+	}
+}
@@ -0,0 +1,250 @@
+package stats
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// Used to detect duration regressions when no options are passed
+const (
+	DefaultBaselineRuns        = 20
+	DefaultRecentRuns          = 5
+	DefaultRegressionThreshold = 1.5
+	DefaultOutlierFactor       = 3.0
+)
+
+type RegressionOptions struct {
+	// Max number of successful runs before the recent ones whose median duration is the baseline
+	BaselineRuns int
+	// Number of the latest successful runs whose median duration is compared with the baseline
+	RecentRuns int
+	// The recent median must be at least this many times the baseline median to be a regression
+	Threshold float64
+	// Recent runs taking at least this many times the baseline median are anomalies
+	OutlierFactor float64
+}
+
+func DefaultRegressionOptions() RegressionOptions {
+	return RegressionOptions{
+		BaselineRuns:  DefaultBaselineRuns,
+		RecentRuns:    DefaultRecentRuns,
+		Threshold:     DefaultRegressionThreshold,
+		OutlierFactor: DefaultOutlierFactor,
+	}
+}
+
+// The durations of the recent runs of a workflow (or one of its jobs if JobName is set) on a branch compared with
+// the baseline durations
+type DurationRegression struct {
+	WorkflowOwner string `json:"workflowOwner"`
+	WorkflowRepo  string `json:"workflowRepo"`
+	WorkflowName  string `json:"workflowName"`
+	JobName       string `json:"jobName,omitempty"`
+	Branch        string `json:"branch"`
+
+	Baseline DurationStats `json:"baseline"`
+	Recent   DurationStats `json:"recent"`
+	// Recent median divided by the baseline median
+	Ratio float64 `json:"ratio"`
+	// Whether the ratio reached the threshold
+	Regressed bool `json:"regressed"`
+	// The last run before and the first run after the durations shifted, only set for regressions
+	LastFast   *github.WorkflowRun `json:"lastFast,omitempty"`
+	FirstSlow  *github.WorkflowRun `json:"firstSlow,omitempty"`
+	CompareURL string              `json:"compareUrl,omitempty"`
+	// Recent runs exceeding the baseline median by the outlier factor
+	Anomalies []*DurationAnomaly `json:"anomalies"`
+}
+
+type DurationAnomaly struct {
+	Run *github.WorkflowRun
+	// The run or the job
+	URL      string
+	Duration time.Duration
+	// Duration divided by the baseline median
+	Factor float64
+}
+
+// Durations are serialized in seconds
+type durationAnomalyJson struct {
+	Run      *github.WorkflowRun `json:"run"`
+	URL      string              `json:"url"`
+	Duration float64             `json:"duration"`
+	Factor   float64             `json:"factor"`
+}
+
+func (a DurationAnomaly) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&durationAnomalyJson{Run: a.Run, URL: a.URL, Duration: a.Duration.Seconds(), Factor: a.Factor})
+}
+
+func (a *DurationAnomaly) UnmarshalJSON(data []byte) error {
+	value := &durationAnomalyJson{}
+	if err := json.Unmarshal(data, value); err != nil {
+		return err
+	}
+
+	*a = DurationAnomaly{Run: value.Run, URL: value.URL, Duration: seconds(value.Duration), Factor: value.Factor}
+	return nil
+}
+
+// The duration of a successful run or job
+type durationSample struct {
+	run      *github.WorkflowRun
+	url      string
+	duration time.Duration
+}
+
+// Compares the recent durations of successful runs of each workflow and branch, and of their jobs (by run ID, may be
+// incomplete), with the preceding ones. Only regressions and series with anomalies are returned, ranked by ratio.
+func DetectDurationRegressions(runs []*github.WorkflowRun, jobs map[int][]*github.RunJob, opts RegressionOptions) []*DurationRegression {
+	result := make([]*DurationRegression, 0)
+
+	for _, branchRuns := range groupByWorkflowAndBranch(runs) {
+		samples := make([]*durationSample, 0)
+		jobSamples := map[string][]*durationSample{}
+		jobNames := make([]string, 0)
+
+		for _, run := range branchRuns {
+			if !IsSuccess(run) {
+				continue
+			}
+			if duration, ok := Duration(run); ok {
+				samples = append(samples, &durationSample{run: run, url: run.JobHTMLURL, duration: duration})
+			}
+
+			for name, attempts := range groupJobsByName(jobs[run.JobRunID]) {
+				job := latestAttempt(attempts)
+				if job.Conclusion != ConclusionSuccess || job.StartedAt.IsZero() || job.CompletedAt.Before(job.StartedAt) {
+					continue
+				}
+				if _, ok := jobSamples[name]; !ok {
+					jobNames = append(jobNames, name)
+				}
+				jobSamples[name] = append(jobSamples[name], &durationSample{run: run, url: job.HTMLURL, duration: job.CompletedAt.Sub(job.StartedAt)})
+			}
+		}
+
+		if regression := detectRegression(samples, opts); regression != nil {
+			result = append(result, regression)
+		}
+
+		sort.Strings(jobNames)
+		for _, name := range jobNames {
+			if regression := detectRegression(jobSamples[name], opts); regression != nil {
+				regression.JobName = name
+				result = append(result, regression)
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Ratio > result[j].Ratio })
+	return result
+}
+
+// Compares the recent samples with the baseline, nil if there are too few samples or neither a regression nor anomalies
+func detectRegression(samples []*durationSample, opts RegressionOptions) *DurationRegression {
+	if opts.RecentRuns < 1 || len(samples) < 2*opts.RecentRuns {
+		return nil
+	}
+
+	recent := samples[len(samples)-opts.RecentRuns:]
+	baselineStart := len(samples) - opts.RecentRuns - opts.BaselineRuns
+	if baselineStart < 0 {
+		baselineStart = 0
+	}
+	baseline := samples[baselineStart : len(samples)-opts.RecentRuns]
+
+	run := samples[0].run
+	result := &DurationRegression{
+		WorkflowOwner: run.WorkflowOwner,
+		WorkflowRepo:  run.WorkflowRepo,
+		WorkflowName:  run.WorkflowName,
+		Branch:        run.JobBranch,
+		Baseline:      NewDurationStats(durationsOf(baseline)),
+		Recent:        NewDurationStats(durationsOf(recent)),
+		Anomalies:     make([]*DurationAnomaly, 0),
+	}
+	if result.Baseline.Median <= 0 {
+		return nil
+	}
+
+	result.Ratio = float64(result.Recent.Median) / float64(result.Baseline.Median)
+	result.Regressed = result.Ratio >= opts.Threshold
+
+	if result.Regressed {
+		window := samples[baselineStart:]
+		shift := findShift(durationsOf(window))
+		result.LastFast, result.FirstSlow = window[shift-1].run, window[shift].run
+		result.CompareURL = compareURL(result.LastFast, result.FirstSlow)
+	}
+
+	for _, sample := range recent {
+		factor := float64(sample.duration) / float64(result.Baseline.Median)
+		if opts.OutlierFactor > 0 && factor >= opts.OutlierFactor {
+			result.Anomalies = append(result.Anomalies, &DurationAnomaly{
+				Run:      sample.run,
+				URL:      sample.url,
+				Duration: sample.duration,
+				Factor:   factor,
+			})
+		}
+	}
+
+	if !result.Regressed && len(result.Anomalies) == 0 {
+		return nil
+	}
+	return result
+}
+
+// The index of the first duration after the shift, the split with the least absolute deviation
+// from the medians of both sides. Requires at least 2 durations.
+func findShift(durations []time.Duration) int {
+	best, bestCost := 1, time.Duration(-1)
+	for i := 1; i < len(durations); i++ {
+		cost := deviation(durations[:i]) + deviation(durations[i:])
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = i, cost
+		}
+	}
+	return best
+}
+
+// The sum of absolute deviations from the median
+func deviation(durations []time.Duration) time.Duration {
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	m := median(sorted)
+
+	var result time.Duration
+	for _, d := range durations {
+		if d > m {
+			result += d - m
+		} else {
+			result += m - d
+		}
+	}
+	return result
+}
+
+func durationsOf(samples []*durationSample) []time.Duration {
+	result := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		result[i] = sample.duration
+	}
+	return result
+}
+
+// The attempt of a job that started last
+func latestAttempt(attempts []*github.RunJob) *github.RunJob {
+	result := attempts[0]
+	for _, job := range attempts[1:] {
+		if job.StartedAt.After(result.StartedAt) {
+			result = job
+		}
+	}
+	return result
+}
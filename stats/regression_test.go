@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func TestDetectDurationRegressions(t *testing.T) {
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	run := func(number int, conclusion string, duration time.Duration) *github.WorkflowRun {
+		created := start.Add(time.Duration(number) * time.Hour)
+		return &github.WorkflowRun{
+			WorkflowOwner: "foo",
+			WorkflowRepo:  "bar",
+			WorkflowName:  "build",
+			JobRunID:      number,
+			JobRunNumber:  number,
+			JobHTMLURL:    fmt.Sprintf("https://github.com/foo/bar/actions/runs/%d", number),
+			JobStatus:     github.StatusCompleted,
+			JobConclusion: conclusion,
+			JobBranch:     "main",
+			JobCommitSha:  fmt.Sprintf("sha%d", number),
+			JobRunTime:    created,
+			JobStartTime:  created,
+			JobUpdateTime: created.Add(duration),
+		}
+	}
+
+	// 8 minute builds that start taking 20 minutes from #7 on, the failed #9 is ignored
+	runs := make([]*github.WorkflowRun, 0)
+	for i := 1; i <= 6; i++ {
+		runs = append(runs, run(i, ConclusionSuccess, 8*time.Minute))
+	}
+	runs = append(runs,
+		run(7, ConclusionSuccess, 20*time.Minute),
+		run(8, ConclusionSuccess, 19*time.Minute),
+		run(9, ConclusionFailure, time.Minute),
+		run(10, ConclusionSuccess, 21*time.Minute),
+	)
+
+	// the test job got slower by a single outlier only
+	jobs := map[int][]*github.RunJob{}
+	for _, r := range runs {
+		duration := 2 * time.Minute
+		if r.JobRunNumber == 10 {
+			duration = 7 * time.Minute
+		}
+		jobs[r.JobRunID] = []*github.RunJob{{
+			RunID:       r.JobRunID,
+			JobName:     "test",
+			Conclusion:  ConclusionSuccess,
+			HTMLURL:     fmt.Sprintf("%s/jobs/%d", r.JobHTMLURL, r.JobRunID),
+			StartedAt:   r.JobStartTime,
+			CompletedAt: r.JobStartTime.Add(duration),
+		}}
+	}
+
+	opts := RegressionOptions{BaselineRuns: 10, RecentRuns: 3, Threshold: 1.5, OutlierFactor: 3}
+	regressions := DetectDurationRegressions(runs, jobs, opts)
+	if len(regressions) != 2 {
+		t.Fatalf("got %d regressions, wanted 2", len(regressions))
+	}
+
+	workflow, job := regressions[0], regressions[1]
+	if !workflow.Regressed || workflow.JobName != "" || workflow.Baseline.Median != 8*time.Minute || workflow.Recent.Median != 20*time.Minute {
+		t.Errorf("got workflow regression %+v, wanted a shift from 8m to 20m", workflow)
+	}
+	if workflow.LastFast.JobRunNumber != 6 || workflow.FirstSlow.JobRunNumber != 7 {
+		t.Errorf("got shift between #%d and #%d, wanted #6 and #7", workflow.LastFast.JobRunNumber, workflow.FirstSlow.JobRunNumber)
+	}
+	if want := "https://github.com/foo/bar/compare/sha6...sha7"; workflow.CompareURL != want {
+		t.Errorf("got compare url %s, wanted %s", workflow.CompareURL, want)
+	}
+	if len(workflow.Anomalies) != 0 {
+		t.Errorf("got %d anomalies, wanted none below 3x the baseline", len(workflow.Anomalies))
+	}
+
+	if job.Regressed || job.JobName != "test" || job.LastFast != nil {
+		t.Errorf("got job regression %+v, wanted only anomalies", job)
+	}
+	if len(job.Anomalies) != 1 || job.Anomalies[0].Run.JobRunNumber != 10 || job.Anomalies[0].Factor != 3.5 {
+		t.Errorf("got anomalies %+v, wanted #10 taking 3.5x the baseline", job.Anomalies)
+	}
+}
+
+func TestDetectDurationRegressionsNeedsEnoughRuns(t *testing.T) {
+	runs := []*github.WorkflowRun{}
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		created := start.Add(time.Duration(i) * time.Hour)
+		runs = append(runs, &github.WorkflowRun{
+			WorkflowName:  "build",
+			JobStatus:     github.StatusCompleted,
+			JobConclusion: ConclusionSuccess,
+			JobRunTime:    created,
+			JobStartTime:  created,
+			JobUpdateTime: created.Add(time.Duration(i+1) * 10 * time.Minute),
+		})
+	}
+
+	if regressions := DetectDurationRegressions(runs, nil, DefaultRegressionOptions()); len(regressions) != 0 {
+		t.Errorf("got %d regressions, wanted none with less than 2x the recent runs", len(regressions))
+	}
+}
@@ -0,0 +1,304 @@
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+const (
+	ConclusionSuccess        = "success"
+	ConclusionFailure        = "failure"
+	ConclusionTimedOut       = "timed_out"
+	ConclusionStartupFailure = "startup_failure"
+	ConclusionCancelled      = "cancelled"
+)
+
+// Used to narrow down which runs are aggregated and how they are grouped
+type Options struct {
+	// Aggregate the runs of each branch separately
+	GroupByBranch bool
+	// Ignore runs created before, zero means all runs
+	Since time.Time
+	// Split the runs into consecutive windows of this size (e.g. a day), zero means a single window
+	Window time.Duration
+}
+
+// Aggregated stats of the runs of a workflow (and branch) within a time window
+type WorkflowStats struct {
+	WorkflowOwner string `json:"workflowOwner"`
+	WorkflowRepo  string `json:"workflowRepo"`
+	WorkflowName  string `json:"workflowName"`
+	// Empty unless grouped by branch
+	Branch string `json:"branch,omitempty"`
+	// The window of the runs, the creation times of the oldest and newest run when not split into windows
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	Runs      int `json:"runs"`
+	Completed int `json:"completed"`
+	Successes int `json:"successes"`
+	Failures  int `json:"failures"`
+	Cancelled int `json:"cancelled"`
+	// Rates are relative to the completed runs
+	SuccessRate   float64 `json:"successRate"`
+	FailureRate   float64 `json:"failureRate"`
+	CancelledRate float64 `json:"cancelledRate"`
+
+	// Time from the start to the completion of completed runs
+	Duration DurationStats `json:"duration"`
+	// Time runs were queued for before they started
+	QueueTime DurationStats `json:"queueTime"`
+}
+
+type DurationStats struct {
+	Count  int
+	Mean   time.Duration
+	Median time.Duration
+	P90    time.Duration
+	P95    time.Duration
+}
+
+// Durations are serialized in seconds
+type durationStatsJson struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+}
+
+func (d DurationStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&durationStatsJson{
+		Count:  d.Count,
+		Mean:   d.Mean.Seconds(),
+		Median: d.Median.Seconds(),
+		P90:    d.P90.Seconds(),
+		P95:    d.P95.Seconds(),
+	})
+}
+
+func (d *DurationStats) UnmarshalJSON(data []byte) error {
+	value := &durationStatsJson{}
+	if err := json.Unmarshal(data, value); err != nil {
+		return err
+	}
+
+	*d = DurationStats{
+		Count:  value.Count,
+		Mean:   seconds(value.Mean),
+		Median: seconds(value.Median),
+		P90:    seconds(value.P90),
+		P95:    seconds(value.P95),
+	}
+	return nil
+}
+
+// The key of the group a run is aggregated in
+type groupKey struct {
+	owner    string
+	repo     string
+	workflow string
+	branch   string
+	window   time.Time
+}
+
+// Aggregates the runs per workflow, branch and window, the result is sorted by repository, workflow, branch and window
+func Compute(runs []*github.WorkflowRun, opts Options) []*WorkflowStats {
+	groups := map[groupKey][]*github.WorkflowRun{}
+	keys := make([]groupKey, 0)
+
+	for _, run := range runs {
+		if run.JobRunTime.Before(opts.Since) {
+			continue
+		}
+
+		key := groupKey{owner: run.WorkflowOwner, repo: run.WorkflowRepo, workflow: run.WorkflowName}
+		if opts.GroupByBranch {
+			key.branch = run.JobBranch
+		}
+		if opts.Window > 0 {
+			key.window = run.JobRunTime.UTC().Truncate(opts.Window)
+		}
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], run)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.owner != b.owner {
+			return a.owner < b.owner
+		}
+		if a.repo != b.repo {
+			return a.repo < b.repo
+		}
+		if a.workflow != b.workflow {
+			return a.workflow < b.workflow
+		}
+		if a.branch != b.branch {
+			return a.branch < b.branch
+		}
+		return a.window.Before(b.window)
+	})
+
+	result := make([]*WorkflowStats, 0, len(keys))
+	for _, key := range keys {
+		stats := aggregate(groups[key])
+		stats.Branch = key.branch
+		if opts.Window > 0 {
+			stats.From = key.window
+			stats.To = key.window.Add(opts.Window)
+		}
+		result = append(result, stats)
+	}
+
+	return result
+}
+
+// Aggregates all runs into a single result, the runs are expected to be of the same workflow
+func aggregate(runs []*github.WorkflowRun) *WorkflowStats {
+	result := &WorkflowStats{
+		WorkflowOwner: runs[0].WorkflowOwner,
+		WorkflowRepo:  runs[0].WorkflowRepo,
+		WorkflowName:  runs[0].WorkflowName,
+		From:          runs[0].JobRunTime,
+		To:            runs[0].JobRunTime,
+		Runs:          len(runs),
+	}
+
+	durations := make([]time.Duration, 0)
+	queueTimes := make([]time.Duration, 0)
+
+	for _, run := range runs {
+		if run.JobRunTime.Before(result.From) {
+			result.From = run.JobRunTime
+		}
+		if run.JobRunTime.After(result.To) {
+			result.To = run.JobRunTime
+		}
+
+		if queueTime, ok := QueueTime(run); ok {
+			queueTimes = append(queueTimes, queueTime)
+		}
+
+		if !run.IsCompleted() {
+			continue
+		}
+
+		result.Completed++
+		switch {
+		case IsSuccess(run):
+			result.Successes++
+		case IsFailure(run):
+			result.Failures++
+		case run.JobConclusion == ConclusionCancelled:
+			result.Cancelled++
+		}
+
+		if duration, ok := Duration(run); ok {
+			durations = append(durations, duration)
+		}
+	}
+
+	if result.Completed > 0 {
+		result.SuccessRate = float64(result.Successes) / float64(result.Completed)
+		result.FailureRate = float64(result.Failures) / float64(result.Completed)
+		result.CancelledRate = float64(result.Cancelled) / float64(result.Completed)
+	}
+
+	result.Duration = NewDurationStats(durations)
+	result.QueueTime = NewDurationStats(queueTimes)
+
+	return result
+}
+
+func IsSuccess(run *github.WorkflowRun) bool {
+	return run.IsCompleted() && run.JobConclusion == ConclusionSuccess
+}
+
+// Whether the run completed with a failure, timing out and failing to start count as failures as well
+func IsFailure(run *github.WorkflowRun) bool {
+	if !run.IsCompleted() {
+		return false
+	}
+
+	switch run.JobConclusion {
+	case ConclusionFailure, ConclusionTimedOut, ConclusionStartupFailure:
+		return true
+	default:
+		return false
+	}
+}
+
+// The time from the start to the completion of the latest attempt of a completed run
+func Duration(run *github.WorkflowRun) (time.Duration, bool) {
+	if !run.IsCompleted() || run.JobStartTime.IsZero() || run.JobUpdateTime.Before(run.JobStartTime) {
+		return 0, false
+	}
+
+	return run.JobUpdateTime.Sub(run.JobStartTime), true
+}
+
+// The time a run was queued for before it started, re-run attempts are skipped since
+// their start time is compared to the creation of the first attempt
+func QueueTime(run *github.WorkflowRun) (time.Duration, bool) {
+	if run.JobRunAttempt > 1 || run.JobStartTime.IsZero() || run.JobStartTime.Before(run.JobRunTime) {
+		return 0, false
+	}
+
+	return run.JobStartTime.Sub(run.JobRunTime), true
+}
+
+func NewDurationStats(durations []time.Duration) DurationStats {
+	if len(durations) == 0 {
+		return DurationStats{}
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	return DurationStats{
+		Count:  len(sorted),
+		Mean:   total / time.Duration(len(sorted)),
+		Median: median(sorted),
+		P90:    Percentile(sorted, 90),
+		P95:    Percentile(sorted, 95),
+	}
+}
+
+// The nearest-rank percentile of sorted durations
+func Percentile(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func median(sorted []time.Duration) time.Duration {
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func newRun(workflow, branch, conclusion string, created time.Time, queued, duration time.Duration) *github.WorkflowRun {
+	return &github.WorkflowRun{
+		WorkflowOwner: "foo",
+		WorkflowRepo:  "bar",
+		WorkflowName:  workflow,
+		JobStatus:     github.StatusCompleted,
+		JobConclusion: conclusion,
+		JobBranch:     branch,
+		JobRunAttempt: 1,
+		JobRunTime:    created,
+		JobStartTime:  created.Add(queued),
+		JobUpdateTime: created.Add(queued + duration),
+	}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2022, 3, 9, 12, 0, 0, 0, time.UTC)
+	runs := []*github.WorkflowRun{
+		newRun("build", "main", ConclusionSuccess, now.Add(-4*time.Hour), time.Minute, 10*time.Minute),
+		newRun("build", "main", ConclusionFailure, now.Add(-3*time.Hour), 2*time.Minute, 20*time.Minute),
+		newRun("build", "dev", ConclusionCancelled, now.Add(-2*time.Hour), 3*time.Minute, 30*time.Minute),
+		newRun("build", "dev", ConclusionSuccess, now.Add(-time.Hour), 4*time.Minute, 40*time.Minute),
+		{WorkflowOwner: "foo", WorkflowRepo: "bar", WorkflowName: "build", JobStatus: github.StatusQueued, JobRunTime: now},
+		newRun("release", "main", ConclusionTimedOut, now.Add(-time.Hour), time.Minute, time.Hour),
+	}
+
+	result := Compute(runs, Options{})
+	if len(result) != 2 {
+		t.Fatalf("got %d stats, wanted 2", len(result))
+	}
+
+	build := result[0]
+	if build.WorkflowName != "build" || build.Runs != 5 || build.Completed != 4 {
+		t.Errorf("got %s with %d runs, %d completed, wanted build with 5 runs, 4 completed", build.WorkflowName, build.Runs, build.Completed)
+	}
+	if build.SuccessRate != 0.5 || build.FailureRate != 0.25 || build.CancelledRate != 0.25 {
+		t.Errorf("got rates %f/%f/%f", build.SuccessRate, build.FailureRate, build.CancelledRate)
+	}
+	if build.Duration.Mean != 25*time.Minute || build.Duration.Median != 25*time.Minute || build.Duration.P90 != 40*time.Minute {
+		t.Errorf("got durations %+v", build.Duration)
+	}
+	if build.QueueTime.Count != 4 || build.QueueTime.Median != 150*time.Second {
+		t.Errorf("got queue times %+v", build.QueueTime)
+	}
+
+	if release := result[1]; release.FailureRate != 1 {
+		t.Errorf("got failure rate %f for a timed out run, wanted 1", release.FailureRate)
+	}
+}
+
+func TestComputeByBranchAndWindow(t *testing.T) {
+	now := time.Date(2022, 3, 9, 12, 0, 0, 0, time.UTC)
+	runs := []*github.WorkflowRun{
+		newRun("build", "main", ConclusionSuccess, now.Add(-50*time.Hour), 0, time.Minute),
+		newRun("build", "main", ConclusionSuccess, now.Add(-26*time.Hour), 0, time.Minute),
+		newRun("build", "main", ConclusionFailure, now.Add(-25*time.Hour), 0, time.Minute),
+		newRun("build", "dev", ConclusionSuccess, now.Add(-time.Hour), 0, time.Minute),
+	}
+
+	result := Compute(runs, Options{GroupByBranch: true, Window: 24 * time.Hour, Since: now.Add(-48 * time.Hour)})
+
+	want := []struct {
+		branch string
+		from   time.Time
+		runs   int
+	}{
+		{"dev", time.Date(2022, 3, 9, 0, 0, 0, 0, time.UTC), 1},
+		{"main", time.Date(2022, 3, 8, 0, 0, 0, 0, time.UTC), 2},
+	}
+
+	if len(result) != len(want) {
+		t.Fatalf("got %d stats, wanted %d", len(result), len(want))
+	}
+
+	for i, w := range want {
+		if result[i].Branch != w.branch || !result[i].From.Equal(w.from) || result[i].Runs != w.runs {
+			t.Errorf("got %s from %s with %d runs, wanted %s from %s with %d runs",
+				result[i].Branch, result[i].From, result[i].Runs, w.branch, w.from, w.runs)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	durations := make([]time.Duration, 0)
+	for i := 1; i <= 20; i++ {
+		durations = append(durations, time.Duration(i)*time.Second)
+	}
+
+	if p := Percentile(durations, 90); p != 18*time.Second {
+		t.Errorf("got p90 %s, wanted 18s", p)
+	}
+	if p := Percentile(durations, 95); p != 19*time.Second {
+		t.Errorf("got p95 %s, wanted 19s", p)
+	}
+	if p := Percentile(durations[:1], 95); p != time.Second {
+		t.Errorf("got p95 %s of a single duration, wanted 1s", p)
+	}
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// The current streak of consecutive failed or successful runs of a workflow on a branch
+type Streak struct {
+	WorkflowOwner string `json:"workflowOwner"`
+	WorkflowRepo  string `json:"workflowRepo"`
+	WorkflowName  string `json:"workflowName"`
+	Branch        string `json:"branch"`
+	Failing       bool   `json:"failing"`
+	// Number of consecutive runs with the same outcome, cancelled and incomplete runs are skipped
+	Length int `json:"length"`
+	// The first and the latest run of the streak
+	First  *github.WorkflowRun `json:"first"`
+	Latest *github.WorkflowRun `json:"latest"`
+	// The latest successful run before a failing streak, nil if the fetched runs don't contain one
+	LastSuccess *github.WorkflowRun `json:"lastSuccess,omitempty"`
+	// Compares the commit of the last successful run with the commit of the first failed run
+	CompareURL string `json:"compareUrl,omitempty"`
+}
+
+// How long the workflow has been failing, zero for a successful streak
+func (s *Streak) RedFor(now time.Time) time.Duration {
+	if !s.Failing {
+		return 0
+	}
+	return now.Sub(s.First.JobRunTime)
+}
+
+// Computes the current streak of each workflow and branch, sorted by repository, workflow and branch
+func ComputeStreaks(runs []*github.WorkflowRun) []*Streak {
+	result := make([]*Streak, 0)
+
+	for _, branchRuns := range groupByWorkflowAndBranch(runs) {
+		var streak *Streak
+		// newest runs first
+		for i := len(branchRuns) - 1; i >= 0; i-- {
+			run := branchRuns[i]
+			if !IsSuccess(run) && !IsFailure(run) {
+				continue
+			}
+
+			if streak == nil {
+				streak = &Streak{
+					WorkflowOwner: run.WorkflowOwner,
+					WorkflowRepo:  run.WorkflowRepo,
+					WorkflowName:  run.WorkflowName,
+					Branch:        run.JobBranch,
+					Failing:       IsFailure(run),
+					Latest:        run,
+				}
+			}
+
+			if IsFailure(run) != streak.Failing {
+				if streak.Failing {
+					streak.LastSuccess = run
+					streak.CompareURL = compareURL(run, streak.First)
+				}
+				break
+			}
+
+			streak.Length++
+			streak.First = run
+		}
+
+		if streak != nil {
+			result = append(result, streak)
+		}
+	}
+
+	return result
+}
+
+// The github compare URL of the commits of two runs, empty if the commits are unknown
+func compareURL(from, to *github.WorkflowRun) string {
+	if from.JobCommitSha == "" || to.JobCommitSha == "" || from.JobCommitSha == to.JobCommitSha {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/compare/%s...%s", repositoryURL(to), from.JobCommitSha, to.JobCommitSha)
+}
+
+// The web URL of the repository of the run, derived from the run URL to work with github enterprise as well
+func repositoryURL(run *github.WorkflowRun) string {
+	if i := strings.Index(run.JobHTMLURL, "/actions/runs/"); i != -1 {
+		return run.JobHTMLURL[:i]
+	}
+	return fmt.Sprintf("https://github.com/%s/%s", run.WorkflowOwner, run.WorkflowRepo)
+}
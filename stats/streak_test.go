@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func TestComputeStreaks(t *testing.T) {
+	now := time.Date(2022, 3, 9, 12, 0, 0, 0, time.UTC)
+	run := func(number int, branch, sha, conclusion string, created time.Time) *github.WorkflowRun {
+		return &github.WorkflowRun{
+			WorkflowOwner: "foo",
+			WorkflowRepo:  "bar",
+			WorkflowName:  "build",
+			JobRunNumber:  number,
+			JobHTMLURL:    fmt.Sprintf("https://github.com/foo/bar/actions/runs/%d", number),
+			JobStatus:     github.StatusCompleted,
+			JobConclusion: conclusion,
+			JobBranch:     branch,
+			JobCommitSha:  sha,
+			JobRunTime:    created,
+		}
+	}
+
+	runs := []*github.WorkflowRun{
+		run(1, "main", "aaa", ConclusionFailure, now.Add(-6*time.Hour)),
+		run(2, "main", "bbb", ConclusionSuccess, now.Add(-5*time.Hour)),
+		run(3, "main", "ccc", ConclusionFailure, now.Add(-4*time.Hour)),
+		run(4, "main", "ddd", ConclusionCancelled, now.Add(-3*time.Hour)),
+		run(5, "main", "eee", ConclusionFailure, now.Add(-2*time.Hour)),
+		run(6, "dev", "fff", ConclusionSuccess, now.Add(-2*time.Hour)),
+		run(7, "dev", "ggg", ConclusionSuccess, now.Add(-time.Hour)),
+	}
+
+	streaks := ComputeStreaks(runs)
+	if len(streaks) != 2 {
+		t.Fatalf("got %d streaks, wanted 2", len(streaks))
+	}
+
+	dev, main := streaks[0], streaks[1]
+	if dev.Failing || dev.Length != 2 || dev.RedFor(now) != 0 {
+		t.Errorf("got dev streak failing=%t length=%d, wanted a successful streak of 2", dev.Failing, dev.Length)
+	}
+
+	if !main.Failing || main.Length != 2 || main.First.JobRunNumber != 3 || main.Latest.JobRunNumber != 5 {
+		t.Errorf("got main streak failing=%t length=%d, wanted a failing streak of 2 from #3 to #5", main.Failing, main.Length)
+	}
+	if main.LastSuccess == nil || main.LastSuccess.JobRunNumber != 2 {
+		t.Errorf("got last success %+v, wanted #2", main.LastSuccess)
+	}
+	if main.RedFor(now) != 4*time.Hour {
+		t.Errorf("got red for %s, wanted 4h", main.RedFor(now))
+	}
+	if want := "https://github.com/foo/bar/compare/bbb...ccc"; main.CompareURL != want {
+		t.Errorf("got compare url %s, wanted %s", main.CompareURL, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Package store persists workflow runs in a single file so that the history survives restarts and only new runs
+// have to be fetched from github.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// Contains a bucket of runs by run ID per repository
+	runsBucket = []byte("runs")
+	// The time of the latest sync by repository
+	syncsBucket = []byte("syncs")
+)
+
+type Store struct {
+	db *bolt.DB
+}
+
+// Opens the store file, it's created if missing. The file is locked until the store is closed.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening store '%s' failed, err: %s", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{runsBucket, syncsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initializing store '%s' failed, err: %s", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Inserts the runs of the repository or replaces the stored ones with the same ID, and records the time of the sync
+func (s *Store) SaveRuns(repo *github.RepoId, runs []*github.WorkflowRun, syncTime time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(runsBucket).CreateBucketIfNotExists([]byte(repo.String()))
+		if err != nil {
+			return err
+		}
+
+		for _, run := range runs {
+			// the active run is derived from the other runs whenever the latest runs are selected
+			stored := *run
+			stored.ActiveRun = nil
+
+			value, err := json.Marshal(&stored)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(runKey(run.JobRunID), value); err != nil {
+				return err
+			}
+		}
+
+		syncValue, err := syncTime.MarshalText()
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(syncsBucket).Put([]byte(repo.String()), syncValue)
+	})
+}
+
+// All stored runs of the repository sorted by workflow and from the newest to the oldest run, and the time of the latest
+// sync which is zero if the repository was never synced
+func (s *Store) LoadRuns(repo *github.RepoId) ([]*github.WorkflowRun, time.Time, error) {
+	runs := make([]*github.WorkflowRun, 0)
+	syncTime := time.Time{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(syncsBucket).Get([]byte(repo.String())); value != nil {
+			if err := syncTime.UnmarshalText(value); err != nil {
+				return err
+			}
+		}
+
+		bucket := tx.Bucket(runsBucket).Bucket([]byte(repo.String()))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, value []byte) error {
+			run := &github.WorkflowRun{}
+			if err := json.Unmarshal(value, run); err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("loading runs of %s failed, err: %s", repo, err)
+	}
+
+	return github.SortWorkflowRuns(runs), syncTime, nil
+}
+
+// The repositories having stored runs
+func (s *Store) Repositories() ([]*github.RepoId, error) {
+	result := make([]*github.RepoId, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).ForEach(func(key, _ []byte) error {
+			parts := strings.SplitN(string(key), "/", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid repository key '%s'", key)
+			}
+			result = append(result, &github.RepoId{Owner: parts[0], Name: parts[1]})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing stored repositories failed, err: %s", err)
+	}
+
+	return result, nil
+}
+
+// Deletes the runs created before the time of all repositories and returns the number of deleted runs
+func (s *Store) Prune(before time.Time) (int, error) {
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).ForEach(func(repoKey, _ []byte) error {
+			bucket := tx.Bucket(runsBucket).Bucket(repoKey)
+
+			expired := make([][]byte, 0)
+			err := bucket.ForEach(func(key, value []byte) error {
+				run := &github.WorkflowRun{}
+				if err := json.Unmarshal(value, run); err != nil {
+					return err
+				}
+				if run.JobRunTime.Before(before) {
+					expired = append(expired, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// keys can't be deleted while iterating
+			for _, key := range expired {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			deleted += len(expired)
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("pruning runs created before %s failed, err: %s", before, err)
+	}
+
+	return deleted, nil
+}
+
+// Big endian so that runs are iterated in the order of their IDs
+func runKey(runId int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(runId))
+	return key
+}
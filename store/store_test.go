@@ -0,0 +1,106 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+func openTestStore(t *testing.T) *Store {
+	s, err := Open(filepath.Join(t.TempDir(), "runs.db"))
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSaveAndLoadRuns(t *testing.T) {
+	s := openTestStore(t)
+	repo := &github.RepoId{Owner: "foo", Name: "bar"}
+	now := time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)
+
+	runs := []*github.WorkflowRun{
+		{WorkflowName: "build", JobRunID: 1, JobStatus: github.StatusCompleted, JobRunTime: now.Add(-48 * time.Hour)},
+		{WorkflowName: "build", JobRunID: 2, JobStatus: "in_progress", JobRunTime: now.Add(-time.Hour)},
+	}
+	runs[1].ActiveRun = runs[0]
+	if err := s.SaveRuns(repo, runs, now); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	updated := *runs[1]
+	updated.JobStatus = github.StatusCompleted
+	if err := s.SaveRuns(repo, []*github.WorkflowRun{&updated}, now.Add(time.Minute)); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	loaded, syncTime, err := s.LoadRuns(repo)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(loaded) != 2 || loaded[0].JobRunID != 2 || loaded[0].JobStatus != github.StatusCompleted || loaded[0].ActiveRun != nil {
+		t.Fatalf("got %+v, wanted the updated run 2 without active run first", loaded)
+	}
+	if !syncTime.Equal(now.Add(time.Minute)) {
+		t.Errorf("got sync time %s, wanted %s", syncTime, now.Add(time.Minute))
+	}
+
+	repos, err := s.Repositories()
+	if err != nil || len(repos) != 1 || repos[0].String() != "foo/bar" {
+		t.Errorf("got repositories %v, err: %v, wanted foo/bar", repos, err)
+	}
+
+	deleted, err := s.Prune(now.Add(-24 * time.Hour))
+	if err != nil || deleted != 1 {
+		t.Fatalf("got %d deleted runs, err: %v, wanted 1", deleted, err)
+	}
+	if loaded, _, _ := s.LoadRuns(repo); len(loaded) != 1 || loaded[0].JobRunID != 2 {
+		t.Errorf("got %+v after pruning, wanted run 2", loaded)
+	}
+
+	if loaded, syncTime, err := s.LoadRuns(&github.RepoId{Owner: "foo", Name: "baz"}); err != nil || len(loaded) != 0 || !syncTime.IsZero() {
+		t.Errorf("got %d runs synced at %s, err: %v, wanted none for an unknown repository", len(loaded), syncTime, err)
+	}
+}
+
+func TestCreatedSince(t *testing.T) {
+	now := time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)
+	stored := []*github.WorkflowRun{
+		{WorkflowName: "build", JobRunID: 3, JobStatus: github.StatusCompleted, JobConclusion: "success", JobRunTime: now},
+		{WorkflowName: "build", JobRunID: 2, JobStatus: "queued", JobRunTime: now.Add(-time.Hour)},
+		{WorkflowName: "build", JobRunID: 1, JobStatus: "in_progress", JobRunTime: now.Add(-2 * time.Hour)},
+		{WorkflowName: "release", JobRunID: 5, JobStatus: github.StatusCompleted, JobConclusion: "success", JobRunTime: now.Add(-time.Hour)},
+		{WorkflowName: "release", JobRunID: 4, JobStatus: github.StatusCompleted, JobConclusion: "success", JobRunTime: now.Add(-2 * time.Hour)},
+		{WorkflowName: "deploy", JobRunID: 8, JobStatus: github.StatusCompleted, JobConclusion: "success", JobRunTime: now},
+		{WorkflowName: "deploy", JobRunID: 7, JobStatus: github.StatusCompleted, JobConclusion: "failure", JobRunTime: now.Add(-time.Hour)},
+		{WorkflowName: "deploy", JobRunID: 6, JobStatus: github.StatusCompleted, JobConclusion: "failure", JobRunTime: now.Add(-48 * time.Hour)},
+	}
+
+	since := CreatedSince(stored, now.Add(-24*time.Hour))
+	if !since["build"].Equal(now.Add(-2*time.Hour)) || !since["release"].Equal(now.Add(-time.Hour)) || len(since) != 3 {
+		t.Errorf("got %v, wanted the oldest incomplete build and the newest release", since)
+	}
+	if !since["deploy"].Equal(now.Add(-time.Hour)) {
+		t.Errorf("got %s, wanted the deploy that failed within the rerun window", since["deploy"])
+	}
+}
+
+func TestMergeRuns(t *testing.T) {
+	now := time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)
+	stored := []*github.WorkflowRun{
+		{WorkflowName: "build", JobRunID: 1, JobStatus: "in_progress", JobRunTime: now.Add(-time.Hour)},
+		{WorkflowName: "build", JobRunID: 0, JobStatus: github.StatusCompleted, JobRunTime: now.Add(-2 * time.Hour)},
+	}
+	fetched := []*github.WorkflowRun{
+		{WorkflowName: "build", JobRunID: 2, JobStatus: "queued", JobRunTime: now},
+		{WorkflowName: "build", JobRunID: 1, JobStatus: github.StatusCompleted, JobRunTime: now.Add(-time.Hour)},
+	}
+
+	merged := MergeRuns(stored, fetched)
+	if len(merged) != 3 || merged[0].JobRunID != 2 || merged[1].JobRunID != 1 || merged[1].JobStatus != github.StatusCompleted {
+		t.Errorf("got %+v, wanted runs 2, the completed 1 and 0", merged)
+	}
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"time"
+
+	"github.com/newestuser/github-workflow-dashboard/github"
+)
+
+// The creation time from which on runs of each workflow have to be fetched to sync the stored runs: the oldest run that
+// wasn't completed yet or didn't succeed after rerunSince since it may have changed, otherwise the newest run. A re-run
+// keeps the creation time of its first attempt, so it's only fetched if its run is refetched. Runs created at the same
+// time are refetched, which is harmless since stored runs are replaced by ID.
+func CreatedSince(stored []*github.WorkflowRun, rerunSince time.Time) map[string]time.Time {
+	newest := map[string]time.Time{}
+	oldestChangeable := map[string]time.Time{}
+
+	for _, run := range stored {
+		if run.JobRunTime.After(newest[run.WorkflowName]) {
+			newest[run.WorkflowName] = run.JobRunTime
+		}
+
+		if run.IsCompleted() && (run.JobConclusion == "success" || run.JobRunTime.Before(rerunSince)) {
+			continue
+		}
+		if since, ok := oldestChangeable[run.WorkflowName]; !ok || run.JobRunTime.Before(since) {
+			oldestChangeable[run.WorkflowName] = run.JobRunTime
+		}
+	}
+
+	for workflow, since := range oldestChangeable {
+		newest[workflow] = since
+	}
+	return newest
+}
+
+// The stored runs updated with the fetched ones, sorted by workflow and from the newest to the oldest run
+func MergeRuns(stored, fetched []*github.WorkflowRun) []*github.WorkflowRun {
+	byId := map[int]*github.WorkflowRun{}
+	for _, runs := range [][]*github.WorkflowRun{stored, fetched} {
+		for _, run := range runs {
+			byId[run.JobRunID] = run
+		}
+	}
+
+	result := make([]*github.WorkflowRun, 0, len(byId))
+	for _, run := range byId {
+		result = append(result, run)
+	}
+	return github.SortWorkflowRuns(result)
+}